@@ -72,6 +72,8 @@ var describe = &cobra.Command{
 	Use:   "describe",
 	Short: "Display documentation for an action",
 	Run: func(_ *cobra.Command, args []string) {
+		loadPlugins()
+
 		if len(args) == 0 {
 			fmt.Printf(" - %s\n", strings.Join(actions.ListActions(), "\n - "))
 			return
@@ -141,6 +143,10 @@ var describe = &cobra.Command{
 			fmt.Println("Any options allowed")
 		}
 
+		if args[0] == "task" {
+			printConditionHelp()
+		}
+
 		if plg.Example != "" {
 			fmt.Printf("\n%s\n", header("Example"))
 			fmt.Printf("\n%s\n", codeBlock(plg.Example))
@@ -156,6 +162,44 @@ var describe = &cobra.Command{
 	},
 }
 
+// printConditionHelp prints the additional help sections,
+// examples and contact information of all registered conditions
+// that provide them.
+func printConditionHelp() {
+	for _, cond := range deploy.Conditions() {
+		if len(cond.Help) == 0 && cond.Example == "" && cond.Author == "" && cond.Website == "" {
+			continue
+		}
+
+		fmt.Printf("\n%s\n", header("Condition"+cond.Name+"="))
+
+		if cond.Description != "" {
+			fmt.Printf("\n%s\n", wrap(cond.Description, ""))
+		}
+
+		for _, section := range cond.Help {
+			if section.Title != "" {
+				fmt.Printf("\n%s\n", bold(section.Title))
+			}
+
+			if section.Description != "" {
+				fmt.Printf("\n%s\n", wrap(section.Description, ""))
+			}
+		}
+
+		if cond.Example != "" {
+			fmt.Printf("\n%s\n", codeBlock(cond.Example))
+		}
+
+		if cond.Author != "" || cond.Website != "" {
+			fmt.Printf("\n%s  ", underlineOrItalic(cond.Author))
+			fmt.Printf("\n%s  ", cond.Website)
+
+			fmt.Println()
+		}
+	}
+}
+
 // wrap ensures that text is no longer thatn 80 characters per line.
 // It automatically breaks text into multiple lines that fit into a
 // 80 character (including indention) limit.
@@ -167,4 +211,7 @@ func wrap(text string, indent string) string {
 const taskDescription = "The `[Task]` section must be available in each deploy unit file and contains metadata for the task like a human-readable " +
 	"description or the tasks state (Disabled or Masked). Users may also defined condition and assertions in the tasks meta section that may disable or " +
 	"fail the task based on environmental conditions. All properties starting with `Condition` will disable the task if not matched, all properties starting " +
-	"with `Assert` will cause *system-deploy* to throw an error and exit."
+	"with `Assert` will cause *system-deploy* to throw an error and exit. If multiple values are specified all of them must match. Values prefixed " +
+	"with a pipe symbol (`|`) are triggering conditions: if at least one triggering condition is defined, at least one of them must match. Prefix a " +
+	"value with an exclamation mark (`!`), after the pipe symbol if any, to negate it. Conditions and assertions may also be placed " +
+	"inside action sections in which case only the action is skipped if a condition fails."
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/ppacher/system-deploy/pkg/facts"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var flagFactsEnv bool
+
+func init() {
+	factsCommand.Flags().BoolVar(&flagFactsEnv, "env", false, "Print facts as FACT_* environment variables instead of JSON.")
+}
+
+var factsCommand = &cobra.Command{
+	Use:   "facts [NAME...]",
+	Short: "Print facts about the local host",
+	Run: func(_ *cobra.Command, args []string) {
+		// failing custom fact executables are reported but
+		// the remaining facts are still printed.
+		all, err := facts.Gather(context.Background())
+		if err != nil {
+			logrus.Warnf("Failed to gather facts: %s", err)
+		}
+
+		result := all
+		if len(args) > 0 {
+			result = make(facts.Facts, len(args))
+			for _, name := range args {
+				value, ok := all[name]
+				if !ok {
+					log.Fatalf("unknown fact %q", name)
+				}
+				result[name] = value
+			}
+		}
+
+		if flagFactsEnv {
+			for _, e := range result.Environ() {
+				os.Stdout.WriteString(e + "\n")
+			}
+			return
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
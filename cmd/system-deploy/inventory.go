@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/ppacher/system-deploy/pkg/facts"
+	"github.com/ppacher/system-deploy/pkg/inventory"
+	"github.com/spf13/cobra"
+)
+
+// inventoryPath is the path of the inventory file. If empty,
+// inventory.DefaultPaths are searched.
+var inventoryPath string
+
+var flagInventoryHosts []string
+
+func init() {
+	inventoryShowCommand.Flags().StringSliceVar(&flagInventoryHosts, "host", nil, "Show the inventory for the given hostnames instead of the local host.")
+
+	inventoryCommand.AddCommand(inventoryShowCommand)
+}
+
+var inventoryCommand = &cobra.Command{
+	Use:   "inventory",
+	Short: "Inspect the host inventory",
+}
+
+var inventoryShowCommand = &cobra.Command{
+	Use:   "show",
+	Short: "Show the groups and variables that apply to the current host",
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		names := flagInventoryHosts
+		if len(names) == 0 {
+			names = localHostnames()
+		}
+
+		inv, match, err := loadInventory(names)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if inv == nil {
+			log.Fatal(inventory.ErrNotFound)
+		}
+
+		fmt.Printf("%s %s\n", bold("Inventory:"), inv.Path)
+		fmt.Printf("%s %s\n", bold("Host:     "), strings.Join(names, ", "))
+		fmt.Printf("%s %s\n", bold("Hosts:    "), strings.Join(match.Hosts, ", "))
+		fmt.Printf("%s %s\n", bold("Groups:   "), strings.Join(match.Groups, ", "))
+
+		fmt.Printf("\n%s\n", bold("Environment files:"))
+		for _, file := range match.EnvironmentFiles {
+			fmt.Printf("  %s\n", file)
+		}
+
+		tsk := &deploy.Task{
+			EnvironmentFiles: match.EnvironmentFiles,
+		}
+		if err := deploy.LoadEnv(tsk); err != nil {
+			log.Fatal(err)
+		}
+
+		env := tsk.RedactedEnvironment()
+		sort.Strings(env)
+
+		fmt.Printf("\n%s\n", bold("Variables:"))
+		for _, e := range env {
+			fmt.Printf("  %s\n", e)
+		}
+	},
+}
+
+// localHostnames returns the hostname and the fully qualified
+// domain name of the local host.
+func localHostnames() []string {
+	hostFacts, _ := facts.Load(context.Background())
+
+	var names []string
+	for _, name := range []string{hostFacts["hostname"], hostFacts["fqdn"]} {
+		if name != "" && (len(names) == 0 || names[0] != name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// loadInventory loads the inventory from inventoryPath or the
+// default locations and matches it against names. It returns
+// nil if no inventory path is configured and none exists at the
+// default locations.
+func loadInventory(names []string) (*inventory.Inventory, *inventory.Match, error) {
+	path := inventoryPath
+	if path == "" {
+		var err error
+		path, err = inventory.Find(inventory.DefaultPaths)
+		if errors.Is(err, inventory.ErrNotFound) {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	inv, err := inventory.LoadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return inv, inv.Match(names...), nil
+}
@@ -1,8 +1,18 @@
 package main
 
-import "log"
+import (
+	"log"
+	"os"
+
+	"github.com/ppacher/system-deploy/pkg/secrets"
+)
 
 func main() {
+	// errors, like the output of failed commands, may contain
+	// sensitive values and are not passed through the logrus
+	// RedactHook.
+	log.SetOutput(secrets.RedactWriter{W: os.Stderr})
+
 	if err := getRootCmd().Execute(); err != nil {
 		log.Fatal(err)
 	}
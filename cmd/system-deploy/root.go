@@ -10,8 +10,12 @@ import (
 
 	"github.com/ppacher/system-conf/conf"
 	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/actions/external"
 	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/ppacher/system-deploy/pkg/facts"
 	"github.com/ppacher/system-deploy/pkg/runner"
+	"github.com/ppacher/system-deploy/pkg/secrets"
+	"github.com/ppacher/system-deploy/pkg/version"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
@@ -28,12 +32,29 @@ func init() {
 func getRootCmd() *cobra.Command {
 	var dropInSearchPaths []string
 	var additionalEnv []string
+	var keepGoing bool
 
 	var root = &cobra.Command{
-		Use:   "system-deploy",
-		Short: "Deploy and manage system configuration",
-		Args:  cobra.MinimumNArgs(1),
+		Use:     "system-deploy",
+		Short:   "Deploy and manage system configuration",
+		Version: version.Version,
+		Args:    cobra.MinimumNArgs(1),
+		// errors are logged by main so sensitive values are
+		// redacted.
+		SilenceErrors: true,
 		Run: func(cmd *cobra.Command, args []string) {
+			loadPlugins()
+
+			_, match, err := loadInventory(localHostnames())
+			if err != nil {
+				log.Fatalf("Failed to load inventory: %s", err)
+			}
+
+			var inventoryFiles []string
+			if match != nil {
+				logrus.Debugf("inventory: hosts=%v groups=%v", match.Hosts, match.Groups)
+				inventoryFiles = match.EnvironmentFiles
+			}
 
 			var targets []deploy.Task
 			for _, dir := range args {
@@ -61,7 +82,7 @@ func getRootCmd() *cobra.Command {
 					}
 
 					path := filepath.Join(dir, fi.Name())
-					file := parseFile(path, dropInSearchPaths, additionalEnv)
+					file := parseFile(path, dropInSearchPaths, inventoryFiles, additionalEnv)
 					targets = append(targets, file)
 				}
 			}
@@ -74,6 +95,7 @@ func getRootCmd() *cobra.Command {
 			if err != nil {
 				log.Fatal(err)
 			}
+			run.KeepGoing = keepGoing
 
 			if err := run.Deploy(context.Background()); err != nil {
 				log.Fatal(err)
@@ -87,9 +109,16 @@ func getRootCmd() *cobra.Command {
 	}
 	root.Flags().StringSliceVarP(&dropInSearchPaths, "path", "p", defaultSearchPath, "Search paths for task drop-in files.")
 	root.Flags().StringSliceVarP(&additionalEnv, "env", "e", nil, "Additional environment variables for each task")
+	root.Flags().BoolVarP(&keepGoing, "keep-going", "k", false, "Continue with the remaining tasks if a task fails.")
 
 	var logLevel string
 	root.PersistentFlags().StringVarP(&logLevel, "log", "l", "info", "Log level")
+	root.PersistentFlags().StringSliceVar(&flagPluginDirs, "plugin-dir", external.DefaultDirs, "Directories to search for external action plugins.")
+	root.PersistentFlags().StringVar(&secrets.KeyDir, "key-dir", secrets.KeyDir, "Directory that holds the age keys for encrypted files.")
+	root.PersistentFlags().StringVarP(&inventoryPath, "inventory", "i", "", "Path to the inventory file. Defaults to inventory.conf in the working directory or /etc/system-deploy.")
+	root.PersistentFlags().StringVar(&facts.Dir, "facts-dir", facts.Dir, "Directory that holds executables providing custom facts.")
+	root.PersistentFlags().StringVar(&secrets.SecretDir, "secret-dir", secrets.SecretDir, "Directory used by the file secret provider.")
+	root.PersistentFlags().StringVar(&secrets.SecretCommand, "secret-command", "", "Command used by the cmd secret provider. The secret key is appended as the last argument.")
 	cobra.OnInitialize(func() {
 		lvl, err := logrus.ParseLevel(logLevel)
 		if err != nil {
@@ -97,15 +126,36 @@ func getRootCmd() *cobra.Command {
 		}
 
 		logrus.SetLevel(lvl)
+		logrus.AddHook(actions.RedactHook{})
 	})
 
 	root.AddCommand(describe)
 	root.AddCommand(runActionCommand)
+	root.AddCommand(secretsCommand)
+	root.AddCommand(factsCommand)
+	root.AddCommand(inventoryCommand)
 
 	return root
 }
 
-func parseFile(filePath string, searchPaths []string, extraEnv []string) deploy.Task {
+// flagPluginDirs holds the directories searched for external
+// action plugins.
+var flagPluginDirs []string
+
+// loadPlugins registers all external action plugins. It must
+// only be called by commands that need actions as each plugin
+// is executed to describe itself.
+func loadPlugins() {
+	if err := external.Load(flagPluginDirs); err != nil {
+		log.Fatalf("Failed to load action plugins: %s", err)
+	}
+}
+
+// parseFile loads the task at filePath and applies drop-ins and
+// environment substitution. The task environment is built in the
+// following order where later values overwrite earlier ones: host
+// facts, the tasks environment files, inventoryFiles and extraEnv.
+func parseFile(filePath string, searchPaths []string, inventoryFiles []string, extraEnv []string) deploy.Task {
 	f, err := os.Open(filePath)
 	if err != nil {
 		log.Fatal(err)
@@ -131,16 +181,28 @@ func parseFile(filePath string, searchPaths []string, extraEnv []string) deploy.
 		log.Fatalf("Failed to apply dropins to %s: %s", target.FileName, err)
 	}
 
+	// host facts are available to all tasks and may be
+	// overwritten by environment files.
+	hostFacts, err := facts.Load(context.Background())
+	if err != nil {
+		logrus.Warnf("Failed to gather facts: %s", err)
+	}
+	target.Environment = hostFacts.Environ()
+	target.EnvironmentFiles = append(target.EnvironmentFiles, inventoryFiles...)
+
 	if err := deploy.LoadEnv(target); err != nil {
 		log.Fatalf("Failed to load environment for task %s: %s", target.FileName, err)
 	}
 
-	target.Environment = append(target.Environment, extraEnv...)
+	deploy.MergeEnv(target, extraEnv...)
 
 	if err = deploy.ApplyEnvironment(target); err != nil {
 		log.Fatalf("Failed to apply environment to task %s: %s", target.FileName, err)
 	}
-	dump(target.FileName, *target)
+
+	// never dump secret values. They must be redacted before
+	// encoding because JSON escapes some characters.
+	dump(target.FileName, target.Redacted())
 
 	return *target
 }
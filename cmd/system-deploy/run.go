@@ -24,6 +24,8 @@ var runActionCommand = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
 
+		loadPlugins()
+
 		_, ok := actions.GetPlugin(name)
 		if !ok {
 			log.Fatalf("unknown plugin: %s", name)
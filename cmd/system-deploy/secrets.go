@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ppacher/system-deploy/pkg/secrets"
+	"github.com/ppacher/system-deploy/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// Flags for the secrets commands.
+var (
+	flagSecretsOutput     string
+	flagSecretsRecipients []string
+	flagSecretsArmor      bool
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{secretsEncryptCommand, secretsEditCommand} {
+		cmd.Flags().StringSliceVarP(&flagSecretsRecipients, "recipient", "r", nil, "Additional age recipients to encrypt for.")
+		cmd.Flags().BoolVarP(&flagSecretsArmor, "armor", "a", false, "Write ASCII armored output.")
+	}
+
+	secretsEncryptCommand.Flags().StringVarP(&flagSecretsOutput, "output", "o", "", "Output file. Defaults to the input file with an .age extension. Use - for stdout.")
+	secretsDecryptCommand.Flags().StringVarP(&flagSecretsOutput, "output", "o", "-", "Output file. Use - for stdout.")
+
+	secretsCommand.AddCommand(secretsEncryptCommand)
+	secretsCommand.AddCommand(secretsDecryptCommand)
+	secretsCommand.AddCommand(secretsEditCommand)
+}
+
+var secretsCommand = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage encrypted environment files",
+}
+
+var secretsEncryptCommand = &cobra.Command{
+	Use:   "encrypt FILE",
+	Short: "Encrypt a file for all keys in the key directory",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		plain, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		output := flagSecretsOutput
+		if output == "" {
+			output = args[0] + secrets.Extension
+		}
+
+		if err := writeEncrypted(output, plain); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var secretsDecryptCommand = &cobra.Command{
+	Use:   "decrypt FILE",
+	Short: "Decrypt a file using the keys in the key directory",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		plain, err := secrets.DecryptFile(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if flagSecretsOutput == "-" {
+			if _, err := os.Stdout.Write(plain); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		if err := utils.CreateAtomic(flagSecretsOutput, 0600, bytes.NewReader(plain)); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var secretsEditCommand = &cobra.Command{
+	Use:   "edit FILE",
+	Short: "Edit an encrypted file using $EDITOR",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		file := args[0]
+
+		var plain []byte
+		if _, err := os.Stat(file); err == nil {
+			plain, err = secrets.DecryptFile(file)
+			if err != nil {
+				log.Fatal(err)
+			}
+		} else if !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+
+		// prefer a tmpfs so the plain text never hits the disk.
+		tmpDir := ""
+		if stat, err := os.Stat("/dev/shm"); err == nil && stat.IsDir() {
+			tmpDir = "/dev/shm"
+		}
+
+		dir, err := ioutil.TempDir(tmpDir, "system-deploy-secrets-")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		tmpFile := filepath.Join(dir, strings.TrimSuffix(filepath.Base(file), secrets.Extension))
+		if err := ioutil.WriteFile(tmpFile, plain, 0600); err != nil {
+			log.Fatal(err)
+		}
+
+		editor := os.Getenv("EDITOR")
+		if editor == "" {
+			editor = "vi"
+		}
+
+		cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", tmpFile)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			os.RemoveAll(dir)
+			log.Fatalf("editor failed: %s", err)
+		}
+
+		edited, err := ioutil.ReadFile(tmpFile)
+		if err != nil {
+			os.RemoveAll(dir)
+			log.Fatal(err)
+		}
+
+		if bytes.Equal(edited, plain) {
+			log.Printf("%s unchanged", file)
+			return
+		}
+
+		if err := writeEncrypted(file, edited); err != nil {
+			os.RemoveAll(dir)
+			log.Fatal(err)
+		}
+	},
+}
+
+// writeEncrypted encrypts plain for all recipients and writes
+// the result to output. Use - to write to stdout.
+func writeEncrypted(output string, plain []byte) error {
+	recipients, err := secrets.Recipients(flagSecretsRecipients...)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := secrets.Encrypt(&buf, plain, recipients, flagSecretsArmor); err != nil {
+		return err
+	}
+
+	if output == "-" {
+		_, err := io.Copy(os.Stdout, &buf)
+		return err
+	}
+
+	return utils.CreateAtomic(output, 0600, &buf)
+}
@@ -3,9 +3,12 @@ module github.com/ppacher/system-deploy
 go 1.14
 
 require (
+	filippo.io/age v1.0.0
 	github.com/a8m/envsubst v1.1.0
+	github.com/coreos/go-systemd/v22 v22.5.0
 	github.com/fatih/color v1.9.0
 	github.com/flynn/go-shlex v0.0.0-20150515145356-3f9db97f8568
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	github.com/google/renameio v0.1.0
 	github.com/kr/text v0.2.0 // indirect
 	github.com/mattn/go-colorable v0.1.6 // indirect
@@ -20,7 +23,7 @@ require (
 	github.com/stretchr/testify v1.5.1
 	github.com/tevino/abool v0.0.0-20170917061928-9b9efcf221b5
 	github.com/twmb/murmur3 v1.1.3
-	golang.org/x/sys v0.0.0-20200519105757-fe76b779f299 // indirect
+	go.starlark.net v0.0.0-20201006213952-227f4aabceb5
 	gopkg.in/check.v1 v1.0.0-20200227125254-8fa46927fb4f // indirect
 	gopkg.in/yaml.v2 v2.3.0 // indirect
 )
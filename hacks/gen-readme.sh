@@ -23,6 +23,10 @@ gendoc Copy
 gendoc Exec
 gendoc OnChange
 gendoc EditFile
+gendoc Hosts
+gendoc PackageRepository
+gendoc LanguagePackages
+gendoc Script
 
 cat > ./docs/docs/concepts/task-props.md <<EOT
 ---
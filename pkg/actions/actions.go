@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/condition"
 	"github.com/ppacher/system-deploy/pkg/deploy"
 )
 
@@ -85,7 +86,14 @@ func TaskSpec(t *deploy.Task) (map[string]map[string]conf.OptionSpec, error) {
 			return nil, errors.New("unknown action")
 		}
 
-		result[key] = plg.OptionSpecs()
+		specs := plg.OptionSpecs()
+		if !conf.IsAllowAny(plg.Options) {
+			for _, opt := range deploy.ConditionOptions() {
+				specs[strings.ToLower(opt.Name)] = opt
+			}
+		}
+
+		result[key] = specs
 	}
 
 	return result, nil
@@ -124,6 +132,27 @@ type Executor interface {
 	Execute(ctx context.Context) (bool, error)
 }
 
+// Conditional describes the interface that actions must
+// implement to support ConditionXxx= and AssertXxx= options
+// inside their section. It's implemented by Base.
+type Conditional interface {
+	// SetActionConditions configures the conditions that
+	// must pass for the action to be executed.
+	SetActionConditions([]condition.Instance)
+
+	// ActionConditions returns the conditions of the action.
+	ActionConditions() []condition.Instance
+}
+
+// SectionIndexer may be implemented by actions that need to
+// know the position of their section inside the task file,
+// like actions that store state per section.
+type SectionIndexer interface {
+	// SetSectionIndex is called by SetupSection with the
+	// index of the section the action has been created for.
+	SetSectionIndex(idx int)
+}
+
 var (
 	actionsLock sync.RWMutex
 	actions     map[string]*Plugin
@@ -1,12 +1,17 @@
 package actions
 
-import "github.com/ppacher/system-deploy/pkg/deploy"
+import (
+	"github.com/ppacher/system-deploy/pkg/condition"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+)
 
 // Base provides a base action and is meant to be embedded into
 // real action implementations.
 type Base struct {
 	Logger
 	deploy.Task
+
+	conditions []condition.Instance
 }
 
 // SetLogger configures the logger to use and implements
@@ -20,3 +25,15 @@ func (b *Base) SetLogger(l Logger) {
 func (b *Base) SetTask(t deploy.Task) {
 	b.Task = t
 }
+
+// SetActionConditions configures the conditions of the action
+// and implements SetActionConditions from actions.Conditional.
+func (b *Base) SetActionConditions(instances []condition.Instance) {
+	b.conditions = instances
+}
+
+// ActionConditions returns the conditions of the action and
+// implements ActionConditions from actions.Conditional.
+func (b *Base) ActionConditions() []condition.Instance {
+	return b.conditions
+}
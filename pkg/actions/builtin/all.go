@@ -5,7 +5,11 @@ import (
 	_ "github.com/ppacher/system-deploy/pkg/actions/builtin/copy"
 	_ "github.com/ppacher/system-deploy/pkg/actions/builtin/editfile"
 	_ "github.com/ppacher/system-deploy/pkg/actions/builtin/exec"
+	_ "github.com/ppacher/system-deploy/pkg/actions/builtin/hosts"
+	_ "github.com/ppacher/system-deploy/pkg/actions/builtin/langpkg"
 	_ "github.com/ppacher/system-deploy/pkg/actions/builtin/onchange"
 	_ "github.com/ppacher/system-deploy/pkg/actions/builtin/platform"
+	_ "github.com/ppacher/system-deploy/pkg/actions/builtin/repository"
+	_ "github.com/ppacher/system-deploy/pkg/actions/builtin/script"
 	_ "github.com/ppacher/system-deploy/pkg/actions/builtin/systemd"
 )
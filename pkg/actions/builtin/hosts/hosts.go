@@ -0,0 +1,178 @@
+package hosts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/ppacher/system-deploy/pkg/utils"
+	"github.com/ppacher/system-deploy/pkg/utils/hostsfile"
+)
+
+func init() {
+	actions.MustRegister(actions.Plugin{
+		Name:        "Hosts",
+		Description: "Manage entries in the hosts file",
+		Setup:       setupAction,
+		Example:     example,
+		Author:      "Patrick Pacher <patrick.pacher@gmail.com>",
+		Website:     "https://github.com/ppacher/system-deploy",
+		Help: []actions.HelpSection{
+			{
+				Title: "Unmanaged Entries",
+				Description: "" +
+					"The `Hosts` action only touches entries that are configured using Entry=. All other lines, including comments " +
+					"and empty lines, are kept untouched. If the hosts file already contains an entry for an address, missing " +
+					"host names are appended to the existing line instead of adding a new one. Host names that are configured for a " +
+					"different address of the same family are removed from the old entry so they only resolve to the new address.",
+			},
+		},
+		Options: []conf.OptionSpec{
+			{
+				Name:        "Entry",
+				Type:        conf.StringSliceType,
+				Required:    true,
+				Description: "A hosts entry in the format \"<address> <hostname> [<alias>...]\". May be specified multiple times. Entries for the same address are merged.",
+			},
+			{
+				Name:        "State",
+				Type:        conf.StringType,
+				Description: "Whether the entries should be \"present\" or \"absent\". If set to absent and an entry does not specify any host names, all entries for that address are removed.",
+				Default:     "present",
+			},
+			{
+				Name:        "File",
+				Type:        conf.StringType,
+				Description: "Path to the hosts file.",
+				Default:     "/etc/hosts",
+			},
+		},
+	})
+}
+
+type entry struct {
+	ip    string
+	names []string
+}
+
+func setupAction(task deploy.Task, sec conf.Section) (actions.Action, error) {
+	state, err := sec.GetString("State")
+	if err != nil {
+		if !conf.IsNotSet(err) {
+			return nil, err
+		}
+		state = "present"
+	}
+
+	state = strings.ToLower(state)
+	if state != "present" && state != "absent" {
+		return nil, fmt.Errorf("invalid value for State: %q", state)
+	}
+
+	file, err := sec.GetString("File")
+	if err != nil {
+		if !conf.IsNotSet(err) {
+			return nil, err
+		}
+		file = "/etc/hosts"
+	}
+
+	var entries []entry
+	for _, value := range sec.GetStringSlice("Entry") {
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid value for Entry: %q", value)
+		}
+
+		if net.ParseIP(fields[0]) == nil {
+			return nil, fmt.Errorf("invalid address for Entry: %q", fields[0])
+		}
+
+		if len(fields) < 2 && state == "present" {
+			return nil, fmt.Errorf("missing host name for Entry: %q", value)
+		}
+
+		entries = append(entries, entry{
+			ip:    fields[0],
+			names: fields[1:],
+		})
+	}
+
+	return &action{
+		file:    file,
+		absent:  state == "absent",
+		entries: entries,
+	}, nil
+}
+
+type action struct {
+	actions.Base
+
+	file    string
+	absent  bool
+	entries []entry
+}
+
+func (a *action) Name() string {
+	return "Hosts " + a.file
+}
+
+func (a *action) Execute(_ context.Context) (bool, error) {
+	content, err := ioutil.ReadFile(a.file)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	hosts, err := hostsfile.Parse(bytes.NewReader(content))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", a.file, err)
+	}
+
+	var changed bool
+	for _, e := range a.entries {
+		var c bool
+		if a.absent {
+			c = hosts.Remove(e.ip, e.names...)
+		} else {
+			c = hosts.Add(e.ip, e.names...)
+		}
+
+		if c {
+			a.Debugf("%s: updated entry for %s", a.file, e.ip)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	mode, err := utils.FileMode(a.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return false, err
+		}
+		mode = 0644
+	}
+
+	if err := utils.CreateAtomic(a.file, mode, bytes.NewReader(hosts.Bytes())); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+const example = `[Task]
+Description= Add hosts entries for the database server
+
+[Hosts]
+Entry= 10.0.0.5 db db.internal
+Entry= 10.0.0.6 cache
+`
@@ -0,0 +1,152 @@
+package hosts
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const initial = `# managed by hand
+127.0.0.1	localhost
+10.0.0.5	db db.internal
+`
+
+func setup(t *testing.T, file, state string, entries ...string) actions.Executor {
+	opts := conf.Options{
+		{Name: "File", Value: file},
+		{Name: "State", Value: state},
+	}
+	for _, e := range entries {
+		opts = append(opts, conf.Option{Name: "Entry", Value: e})
+	}
+
+	act, err := actions.Setup("Hosts", actions.NewLogger(), deploy.Task{}, conf.Section{
+		Name:    "Hosts",
+		Options: opts,
+	})
+	require.NoError(t, err)
+
+	return act.(actions.Executor)
+}
+
+func run(t *testing.T, act actions.Executor) bool {
+	changed, err := act.Execute(context.Background())
+	require.NoError(t, err)
+	return changed
+}
+
+func hostsFile(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "hosts-")
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "hosts")
+	require.NoError(t, ioutil.WriteFile(file, []byte(initial), 0600))
+
+	return file, func() { os.RemoveAll(dir) }
+}
+
+func assertContent(t *testing.T, file, expected string) {
+	content, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(content))
+}
+
+func TestHostsAdd(t *testing.T) {
+	file, cleanup := hostsFile(t)
+	defer cleanup()
+
+	act := setup(t, file, "present", "10.0.0.5 db db.local", "10.0.0.6 cache")
+	assert.True(t, run(t, act))
+	assert.False(t, run(t, act))
+
+	assertContent(t, file, `# managed by hand
+127.0.0.1	localhost
+10.0.0.5	db db.internal db.local
+10.0.0.6	cache
+`)
+
+	stat, err := os.Stat(file)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+}
+
+func TestHostsMove(t *testing.T) {
+	file, cleanup := hostsFile(t)
+	defer cleanup()
+
+	act := setup(t, file, "present", "10.0.0.7 db")
+	assert.True(t, run(t, act))
+	assert.False(t, run(t, act))
+
+	assertContent(t, file, `# managed by hand
+127.0.0.1	localhost
+10.0.0.5	db.internal
+10.0.0.7	db
+`)
+
+	act = setup(t, file, "present", "10.0.0.8 db.internal")
+	assert.True(t, run(t, act))
+
+	assertContent(t, file, `# managed by hand
+127.0.0.1	localhost
+10.0.0.7	db
+10.0.0.8	db.internal
+`)
+}
+
+func TestHostsRemove(t *testing.T) {
+	file, cleanup := hostsFile(t)
+	defer cleanup()
+
+	act := setup(t, file, "absent", "10.0.0.5 db.internal")
+	assert.True(t, run(t, act))
+	assert.False(t, run(t, act))
+
+	assertContent(t, file, `# managed by hand
+127.0.0.1	localhost
+10.0.0.5	db
+`)
+
+	act = setup(t, file, "absent", "10.0.0.5")
+	assert.True(t, run(t, act))
+
+	assertContent(t, file, `# managed by hand
+127.0.0.1	localhost
+`)
+}
+
+func TestHostsMissingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hosts-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "hosts")
+	act := setup(t, file, "present", "10.0.0.5 db")
+	assert.True(t, run(t, act))
+
+	assertContent(t, file, "10.0.0.5\tdb\n")
+}
+
+func TestHostsSetupErrors(t *testing.T) {
+	cases := []conf.Options{
+		{{Name: "Entry", Value: "not-an-ip db"}},
+		{{Name: "Entry", Value: "10.0.0.5"}},
+		{{Name: "Entry", Value: "10.0.0.5 db"}, {Name: "State", Value: "unknown"}},
+	}
+
+	for _, opts := range cases {
+		_, err := actions.Setup("Hosts", actions.NewLogger(), deploy.Task{}, conf.Section{
+			Name:    "Hosts",
+			Options: opts,
+		})
+		assert.Error(t, err, "%v", opts)
+	}
+}
@@ -0,0 +1,68 @@
+package langpkg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
+)
+
+type cargo struct{}
+
+func (*cargo) Name() string { return "cargo" }
+
+func (*cargo) parse(value string) (pkgmgr.Spec, error) {
+	return splitVersion(value, "@"), nil
+}
+
+func (*cargo) installed(ctx context.Context, _ []string) (pkgmgr.Packages, error) {
+	output, err := run(ctx, "cargo", "install", "--list")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCargoList(output), nil
+}
+
+func (*cargo) install(ctx context.Context, specs []pkgmgr.Spec) error {
+	// cargo install only accepts --version if a single
+	// crate is installed.
+	for _, spec := range specs {
+		args := []string{"install", "--quiet", spec.Name}
+		if spec.IsPinned() {
+			args = append(args, "--version", spec.Version)
+		}
+
+		if _, err := run(ctx, "cargo", args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseCargoList parses the output of "cargo install --list".
+// Each crate is listed as "name v1.2.3:" followed by indented
+// lines for the binaries it provides.
+func parseCargoList(output []byte) pkgmgr.Packages {
+	pkgs := make(pkgmgr.Packages)
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+
+		fields := strings.Fields(strings.TrimSuffix(line, ":"))
+		if len(fields) < 2 {
+			continue
+		}
+
+		pkgs[fields[0]] = strings.TrimPrefix(fields[1], "v")
+	}
+
+	return pkgs
+}
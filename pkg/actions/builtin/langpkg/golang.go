@@ -0,0 +1,137 @@
+package langpkg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
+)
+
+// majorVersionRegex matches major version suffixes of
+// module paths.
+var majorVersionRegex = regexp.MustCompile(`^v[0-9]+$`)
+
+type golang struct{}
+
+func (*golang) Name() string { return "go" }
+
+func (*golang) parse(value string) (pkgmgr.Spec, error) {
+	spec := splitVersion(value, "@")
+	if spec.Version == "latest" {
+		spec.Version = ""
+	}
+
+	if !strings.Contains(spec.Name, "/") {
+		return spec, fmt.Errorf("invalid package path %q", spec.Name)
+	}
+
+	return spec, nil
+}
+
+func (g *golang) installed(ctx context.Context, pkgs []string) (pkgmgr.Packages, error) {
+	bin, err := gobin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(pkgmgr.Packages)
+	for _, pkg := range pkgs {
+		binary := filepath.Join(bin, binaryName(pkg))
+		if _, err := os.Stat(binary); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		output, err := run(ctx, "go", "version", "-m", binary)
+		if err != nil {
+			return nil, err
+		}
+
+		if p, version := parseBuildInfo(output); p == pkg {
+			result[pkg] = version
+		}
+	}
+
+	return result, nil
+}
+
+func (*golang) install(ctx context.Context, specs []pkgmgr.Spec) error {
+	for _, spec := range specs {
+		version := spec.Version
+		if version == "" {
+			version = "latest"
+		}
+
+		if _, err := run(ctx, "go", "install", spec.Name+"@"+version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// gobin returns the directory go install places binaries in.
+func gobin(ctx context.Context) (string, error) {
+	output, err := run(ctx, "go", "env", "GOBIN", "GOPATH")
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(string(output), "\n")
+	if len(lines) >= 1 && strings.TrimSpace(lines[0]) != "" {
+		return strings.TrimSpace(lines[0]), nil
+	}
+
+	if len(lines) >= 2 {
+		gopath := filepath.SplitList(strings.TrimSpace(lines[1]))
+		if len(gopath) > 0 && gopath[0] != "" {
+			return filepath.Join(gopath[0], "bin"), nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to determine GOBIN")
+}
+
+// binaryName returns the name of the binary built for the
+// package pkg.
+func binaryName(pkg string) string {
+	name := path.Base(pkg)
+	if majorVersionRegex.MatchString(name) {
+		name = path.Base(path.Dir(pkg))
+	}
+	return name
+}
+
+// parseBuildInfo parses the output of "go version -m" and
+// returns the package path and the module version.
+func parseBuildInfo(output []byte) (string, string) {
+	var pkg, version string
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "path":
+			pkg = fields[1]
+		case "mod":
+			if len(fields) >= 3 {
+				version = fields[2]
+			}
+		}
+	}
+
+	return pkg, version
+}
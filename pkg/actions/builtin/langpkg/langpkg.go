@@ -0,0 +1,252 @@
+package langpkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
+)
+
+func init() {
+	actions.MustRegister(actions.Plugin{
+		Name:        "LanguagePackages",
+		Description: "Install tools and libraries using language package managers like pip, npm, cargo and go.",
+		Setup:       setupAction,
+		Example:     example,
+		Author:      "Patrick Pacher <patrick.pacher@gmail.com>",
+		Website:     "https://github.com/ppacher/system-deploy",
+		Help: []actions.HelpSection{
+			{
+				Title: "Version Pins",
+				Description: "" +
+					"Packages are specified using the native syntax of each package manager: `name==1.2.3` for pip, " +
+					"`name@1.2.3` for npm and cargo and `path@v1.2.3` for go. Only exact versions are supported. A package " +
+					"is only installed if it's missing or the installed version does not match the pinned one.",
+			},
+		},
+		Options: []conf.OptionSpec{
+			{
+				Name:        "PipPkgs",
+				Type:        conf.StringSliceType,
+				Description: "Python packages to install using pip.",
+			},
+			{
+				Name:        "Virtualenv",
+				Type:        conf.StringType,
+				Description: "Install pip packages into the virtual environment at the given path. The environment is created if it does not exist.",
+			},
+			{
+				Name:        "Python",
+				Type:        conf.StringType,
+				Description: "The python interpreter used to run pip and to create virtual environments.",
+				Default:     "python3",
+			},
+			{
+				Name:        "NpmPkgs",
+				Type:        conf.StringSliceType,
+				Description: "Node.js packages to install globally using npm.",
+			},
+			{
+				Name:        "CargoPkgs",
+				Type:        conf.StringSliceType,
+				Description: "Rust crates to install using cargo install.",
+			},
+			{
+				Name:        "GoPkgs",
+				Type:        conf.StringSliceType,
+				Description: "Go packages to install using go install. Packages without a version are installed at @latest.",
+			},
+		},
+	})
+}
+
+// tool is a language specific package manager.
+type tool interface {
+	// Name returns the name of the tool.
+	Name() string
+
+	// parse parses a package specification.
+	parse(value string) (pkgmgr.Spec, error)
+
+	// installed returns the installed versions of pkgs.
+	// Tools may return other packages as well.
+	installed(ctx context.Context, pkgs []string) (pkgmgr.Packages, error)
+
+	// install installs all packages in specs.
+	install(ctx context.Context, specs []pkgmgr.Spec) error
+}
+
+// toolSpecs holds a tool and the packages it should install.
+type toolSpecs struct {
+	tool  tool
+	specs []pkgmgr.Spec
+}
+
+type action struct {
+	actions.Base
+
+	tools []toolSpecs
+}
+
+func setupAction(task deploy.Task, sec conf.Section) (actions.Action, error) {
+	python, err := sec.GetString("Python")
+	if err != nil {
+		if !conf.IsNotSet(err) {
+			return nil, err
+		}
+		python = "python3"
+	}
+
+	venv, err := sec.GetString("Virtualenv")
+	if err != nil && !conf.IsNotSet(err) {
+		return nil, err
+	}
+
+	tools := []struct {
+		option string
+		tool   tool
+	}{
+		{"PipPkgs", &pip{python: python, venv: venv}},
+		{"NpmPkgs", &npm{}},
+		{"CargoPkgs", &cargo{}},
+		{"GoPkgs", &golang{}},
+	}
+
+	a := new(action)
+	for _, t := range tools {
+		var specs []pkgmgr.Spec
+		for _, value := range sec.GetStringSlice(t.option) {
+			for _, field := range strings.Fields(value) {
+				spec, err := t.tool.parse(field)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value for %s: %w", t.option, err)
+				}
+				specs = append(specs, spec)
+			}
+		}
+
+		if len(specs) > 0 {
+			a.tools = append(a.tools, toolSpecs{t.tool, specs})
+		}
+	}
+
+	if len(a.tools) == 0 {
+		return nil, fmt.Errorf("no packages to install")
+	}
+
+	return a, nil
+}
+
+func (a *action) Name() string {
+	return "Installing language packages"
+}
+
+func (a *action) Execute(ctx context.Context) (bool, error) {
+	var changed bool
+	for _, t := range a.tools {
+		c, err := a.apply(ctx, t.tool, t.specs)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", t.tool.Name(), err)
+		}
+
+		changed = changed || c
+	}
+
+	return changed, nil
+}
+
+// apply installs all specs that are missing or don't match
+// the installed version.
+func (a *action) apply(ctx context.Context, t tool, specs []pkgmgr.Spec) (bool, error) {
+	names := make([]string, len(specs))
+	for idx, spec := range specs {
+		names[idx] = spec.Name
+	}
+
+	before, err := t.installed(ctx, names)
+	if err != nil {
+		return false, err
+	}
+
+	var missing []pkgmgr.Spec
+	for _, spec := range specs {
+		version, ok := before[spec.Name]
+		if ok && (!spec.IsPinned() || version == spec.Version) {
+			continue
+		}
+		missing = append(missing, spec)
+	}
+
+	if len(missing) == 0 {
+		return false, nil
+	}
+
+	if err := t.install(ctx, missing); err != nil {
+		return false, err
+	}
+
+	after, err := t.installed(ctx, names)
+	if err != nil {
+		return false, err
+	}
+
+	changes := pkgmgr.Diff(before, after)
+	for _, c := range changes {
+		a.Infof("%s: %s %s", t.Name(), c.Action(), c)
+	}
+	actions.RecordChange(ctx, "packages", pkgmgr.Names(changes)...)
+
+	return len(changes) > 0, nil
+}
+
+// run executes the command name with args and returns the
+// standard output. Any error returned contains the complete
+// output of the command.
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+
+	var stdout, combined bytes.Buffer
+	cmd.Stdout = io.MultiWriter(&stdout, &combined)
+	cmd.Stderr = &combined
+
+	if err := cmd.Run(); err != nil {
+		return stdout.Bytes(), fmt.Errorf("%s %s: %w\n%s", name, strings.Join(args, " "), err, combined.String())
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// splitVersion splits value at the last occurrence of sep.
+// If sep is found at index 0 (like in scoped npm packages)
+// value is returned as the name.
+func splitVersion(value, sep string) pkgmgr.Spec {
+	idx := strings.LastIndex(value, sep)
+	if idx <= 0 {
+		return pkgmgr.Spec{Name: value}
+	}
+
+	return pkgmgr.Spec{
+		Name:    value[:idx],
+		Version: value[idx+len(sep):],
+	}
+}
+
+const example = `[Task]
+Description= Install development tools
+
+[LanguagePackages]
+PipPkgs= ansible-lint==6.8.6 yamllint
+Virtualenv= /opt/tools
+NpmPkgs= typescript@4.9.4 @angular/cli
+CargoPkgs= ripgrep@13.0.0
+GoPkgs= golang.org/x/tools/cmd/goimports@v0.4.0
+`
@@ -0,0 +1,61 @@
+package langpkg
+
+import (
+	"testing"
+
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpecs(t *testing.T) {
+	cases := []struct {
+		tool     tool
+		value    string
+		expected pkgmgr.Spec
+		err      bool
+	}{
+		{&pip{}, "Ansible_Lint==6.8.6", pkgmgr.Spec{Name: "ansible-lint", Version: "6.8.6"}, false},
+		{&pip{}, "yamllint", pkgmgr.Spec{Name: "yamllint"}, false},
+		{&pip{}, "requests>=2.0", pkgmgr.Spec{}, true},
+		{&npm{}, "typescript@4.9.4", pkgmgr.Spec{Name: "typescript", Version: "4.9.4"}, false},
+		{&npm{}, "@angular/cli", pkgmgr.Spec{Name: "@angular/cli"}, false},
+		{&npm{}, "@angular/cli@15.0.0", pkgmgr.Spec{Name: "@angular/cli", Version: "15.0.0"}, false},
+		{&cargo{}, "ripgrep@13.0.0", pkgmgr.Spec{Name: "ripgrep", Version: "13.0.0"}, false},
+		{&golang{}, "golang.org/x/tools/cmd/goimports@v0.4.0", pkgmgr.Spec{Name: "golang.org/x/tools/cmd/goimports", Version: "v0.4.0"}, false},
+		{&golang{}, "golang.org/x/tools/cmd/goimports@latest", pkgmgr.Spec{Name: "golang.org/x/tools/cmd/goimports"}, false},
+		{&golang{}, "goimports", pkgmgr.Spec{}, true},
+	}
+
+	for _, c := range cases {
+		spec, err := c.tool.parse(c.value)
+		if c.err {
+			assert.Error(t, err, c.value)
+			continue
+		}
+
+		if assert.NoError(t, err, c.value) {
+			assert.Equal(t, c.expected, spec, c.value)
+		}
+	}
+}
+
+func TestParseInstalled(t *testing.T) {
+	pipPkgs, err := parsePipList([]byte(`[{"name": "PyYAML", "version": "6.0"}, {"name": "yamllint", "version": "1.28.0"}]`))
+	require.NoError(t, err)
+	assert.Equal(t, pkgmgr.Packages{"pyyaml": "6.0", "yamllint": "1.28.0"}, pipPkgs)
+
+	npmPkgs, err := parseNpmList([]byte(`{"dependencies": {"typescript": {"version": "4.9.4"}, "@angular/cli": {"version": "15.0.0"}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, pkgmgr.Packages{"typescript": "4.9.4", "@angular/cli": "15.0.0"}, npmPkgs)
+
+	cargoPkgs := parseCargoList([]byte("ripgrep v13.0.0:\n    rg\nfd-find v8.5.3:\n    fd\n"))
+	assert.Equal(t, pkgmgr.Packages{"ripgrep": "13.0.0", "fd-find": "8.5.3"}, cargoPkgs)
+
+	pkg, version := parseBuildInfo([]byte("/root/go/bin/goimports: go1.19.4\n\tpath\tgolang.org/x/tools/cmd/goimports\n\tmod\tgolang.org/x/tools\tv0.4.0\th1:abc=\n"))
+	assert.Equal(t, "golang.org/x/tools/cmd/goimports", pkg)
+	assert.Equal(t, "v0.4.0", version)
+
+	assert.Equal(t, "goimports", binaryName("golang.org/x/tools/cmd/goimports"))
+	assert.Equal(t, "mockgen", binaryName("github.com/golang/mock/mockgen/v2"))
+}
@@ -0,0 +1,62 @@
+package langpkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
+)
+
+type npm struct{}
+
+func (*npm) Name() string { return "npm" }
+
+func (*npm) parse(value string) (pkgmgr.Spec, error) {
+	return splitVersion(value, "@"), nil
+}
+
+func (*npm) installed(ctx context.Context, _ []string) (pkgmgr.Packages, error) {
+	// npm ls exits with a non-zero code if the dependency
+	// tree has problems but still prints the list.
+	output, err := run(ctx, "npm", "ls", "--global", "--depth=0", "--json")
+	if err != nil && len(output) == 0 {
+		return nil, err
+	}
+
+	return parseNpmList(output)
+}
+
+func (*npm) install(ctx context.Context, specs []pkgmgr.Spec) error {
+	args := []string{"install", "--global"}
+	for _, spec := range specs {
+		if spec.IsPinned() {
+			args = append(args, spec.Name+"@"+spec.Version)
+		} else {
+			args = append(args, spec.Name)
+		}
+	}
+
+	_, err := run(ctx, "npm", args...)
+	return err
+}
+
+// parseNpmList parses the output of "npm ls --json".
+func parseNpmList(output []byte) (pkgmgr.Packages, error) {
+	var list struct {
+		Dependencies map[string]struct {
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	}
+
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse npm output: %w", err)
+	}
+
+	pkgs := make(pkgmgr.Packages, len(list.Dependencies))
+	for name, dep := range list.Dependencies {
+		pkgs[name] = dep.Version
+	}
+
+	return pkgs, nil
+}
@@ -0,0 +1,103 @@
+package langpkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
+)
+
+// pipNameRegex matches runs of characters that are
+// equivalent in python package names (see PEP 503).
+var pipNameRegex = regexp.MustCompile(`[-_.]+`)
+
+type pip struct {
+	python string
+	venv   string
+}
+
+func (*pip) Name() string { return "pip" }
+
+// interpreter returns the python interpreter to use.
+func (p *pip) interpreter() string {
+	if p.venv != "" {
+		return filepath.Join(p.venv, "bin", "python")
+	}
+	return p.python
+}
+
+func (*pip) parse(value string) (pkgmgr.Spec, error) {
+	spec := splitVersion(value, "==")
+	if strings.ContainsAny(spec.Name, "<>=!~") {
+		return spec, fmt.Errorf("unsupported version constraint %q", value)
+	}
+
+	spec.Name = normalizePipName(spec.Name)
+	return spec, nil
+}
+
+func (p *pip) installed(ctx context.Context, _ []string) (pkgmgr.Packages, error) {
+	if p.venv != "" {
+		if _, err := os.Stat(p.interpreter()); os.IsNotExist(err) {
+			return pkgmgr.Packages{}, nil
+		}
+	}
+
+	output, err := run(ctx, p.interpreter(), "-m", "pip", "list", "--format=json", "--disable-pip-version-check")
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePipList(output)
+}
+
+func (p *pip) install(ctx context.Context, specs []pkgmgr.Spec) error {
+	if p.venv != "" {
+		if _, err := os.Stat(p.interpreter()); os.IsNotExist(err) {
+			if _, err := run(ctx, p.python, "-m", "venv", p.venv); err != nil {
+				return fmt.Errorf("failed to create virtualenv: %w", err)
+			}
+		}
+	}
+
+	args := []string{"-m", "pip", "install", "--disable-pip-version-check"}
+	for _, spec := range specs {
+		if spec.IsPinned() {
+			args = append(args, spec.Name+"=="+spec.Version)
+		} else {
+			args = append(args, spec.Name)
+		}
+	}
+
+	_, err := run(ctx, p.interpreter(), args...)
+	return err
+}
+
+// normalizePipName normalizes a python package name.
+func normalizePipName(name string) string {
+	return strings.ToLower(pipNameRegex.ReplaceAllString(name, "-"))
+}
+
+// parsePipList parses the output of "pip list --format=json".
+func parsePipList(output []byte) (pkgmgr.Packages, error) {
+	var list []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pip output: %w", err)
+	}
+
+	pkgs := make(pkgmgr.Packages, len(list))
+	for _, p := range list {
+		pkgs[normalizePipName(p.Name)] = p.Version
+	}
+
+	return pkgs, nil
+}
@@ -17,11 +17,20 @@ func init() {
 		Setup:       setupAction,
 		Author:      "Patrick Pacher <patrick.pacher@gmail.com>",
 		Website:     "https://github.com/ppacher/system-deploy",
+		Help: []actions.HelpSection{
+			{
+				Title: "Change Details",
+				Description: "" +
+					"Commands executed by Run= receive details about the changes performed by the task as environment " +
+					"variables. Each variable holds a space separated list of items. `CHANGED_PACKAGES` contains the " +
+					"names of all packages that have been installed, upgraded or removed by `InstallPackages` and `LanguagePackages`.",
+			},
+		},
 		Options: []conf.OptionSpec{
 			{
 				Name:        "Run",
 				Type:        conf.StringSliceType,
-				Description: "Run a command. May be specified multiple times. Note that errors are only logged and don't abort subsequent tasks. Use Unmask for more control",
+				Description: "Run a command. May be specified multiple times. Note that errors are only logged and don't abort subsequent tasks. Use Unmask for more control. Details about changes performed by the task are passed as environment variables (see below).",
 			},
 			{
 				Name:        "Unmask",
@@ -53,7 +62,12 @@ func (a *action) Name() string {
 func (a *action) Prepare(graph actions.ExecGraph) error {
 	err := a.forEachStringValue("Run", func(value string) error {
 		return a.runOnChange(graph, func(ctx context.Context) {
-			utils.ExecCommand(ctx, a.task.Directory, value, nil)
+			opts := &utils.ExecOptions{
+				Env: actions.ChangesFromContext(ctx).Env(),
+			}
+			if err := utils.ExecCommand(ctx, a.task.Directory, value, opts); err != nil {
+				a.Warnf("failed to run %q: %s", value, err)
+			}
 		})
 	})
 	if err != nil {
@@ -3,73 +3,190 @@ package platform
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/exec"
-	"regexp"
 	"strings"
+	"time"
 
 	"github.com/ppacher/system-conf/conf"
 	"github.com/ppacher/system-deploy/pkg/actions"
 	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
 )
 
-var (
-	pacmanNothingToDoRegex = regexp.MustCompile("\n[ \t]{1}there is nothing to do\n")
-	aptChangedRegex        = regexp.MustCompile("[1-9]+[0-9]* (upgraded|newly|to remove)")
+// Supported values for State=.
+const (
+	statePresent = "present"
+	stateAbsent  = "absent"
+	stateLatest  = "latest"
 )
 
+// detectManagers returns the package managers available on
+// the system. It's a variable so it can be replaced in tests.
+var detectManagers = pkgmgr.Detect
+
+// packageOptions maps the name of a package manager to the
+// option holding the packages to install.
+var packageOptions = []struct {
+	manager string
+	option  string
+	label   string
+}{
+	{pkgmgr.APT, "AptPkgs", "APT"},
+	{pkgmgr.Pacman, "PacmanPkgs", "Pacman"},
+	{pkgmgr.Dnf, "DnfPkgs", "DNF"},
+	{pkgmgr.Zypper, "ZypperPkgs", "Zypper"},
+	{pkgmgr.Apk, "ApkPkgs", "APK"},
+	{pkgmgr.Snap, "SnapPkgs", "Snap"},
+	{pkgmgr.Flatpak, "FlatpakPkgs", "Flatpak"},
+	{pkgmgr.Brew, "BrewPkgs", "Homebrew"},
+}
+
 func init() {
+	var options []conf.OptionSpec
+	for _, p := range packageOptions {
+		options = append(options, conf.OptionSpec{
+			Name:        p.option,
+			Description: "Packages to install if " + p.label + " is available",
+			Type:        conf.StringSliceType,
+		})
+	}
+
+	options = append(options,
+		conf.OptionSpec{
+			Name:        "State",
+			Description: "The desired state of the packages. Either \"present\", \"absent\" or \"latest\". If set to \"latest\", installed packages are upgraded to the latest available version.",
+			Type:        conf.StringType,
+			Default:     statePresent,
+		},
+		conf.OptionSpec{
+			Name:        "Hold",
+			Description: "Hold the packages back from being upgraded. Supported for APT (apt-mark hold) and Pacman (IgnorePkg).",
+			Type:        conf.BoolType,
+			Default:     "no",
+		},
+		conf.OptionSpec{
+			Name:        "RefreshCache",
+			Description: "Update the package cache before installing packages. Pacman does not support partial upgrades so all installed packages are upgraded as well.",
+			Type:        conf.BoolType,
+			Default:     "no",
+		},
+		conf.OptionSpec{
+			Name:        "CacheValidSec",
+			Description: "If RefreshCache= is set, only update the package cache if it's older than the given number of seconds.",
+			Type:        conf.IntType,
+		},
+	)
+
+	// TODO(ppacher): add support for arch-linux AUR (maybe using yay?)
 	actions.MustRegister(actions.Plugin{
 		Name:        "InstallPackages",
 		Author:      "Patrick Pacher <patrick.pacher@gmail.com>",
 		Website:     "https://github.com/ppacher/system-deploy",
 		Description: "Install software packages using various package managers. For more control on the installation behavior use the Exec section instead.",
-		Options: []conf.OptionSpec{
-			{
-				Name:        "AptPkgs",
-				Description: "Packages to install if APT is available",
-				Type:        conf.StringSliceType,
-			},
+		Example:     installExample,
+		Help: []actions.HelpSection{
 			{
-				Name:        "PacmanPkgs",
-				Description: "Packages to install if Pacman is available",
-				Type:        conf.StringSliceType,
+				Title: "Version Pins",
+				Description: "" +
+					"Packages may be pinned to a version using `name=version` where version may be a glob pattern like " +
+					"`nginx=1.24.*`. A pinned package is only installed if the installed version does not match the pattern. " +
+					"Version pins are supported by APT, DNF, Zypper and APK and can only be used with State=present.",
 			},
-			// TODO(ppacher): add support for DNF
-			// TODO(ppacher): add support for snap
-			// TODO(ppacher): add support for arch-linux AUR (maybe using yay?)
-			/*
-				{
-					Name:        "DnfPkgs",
-					Description: "Packages to install if DNF is available",
-					Type:        deploy.StringSliceType,
-				},
-				{
-					Name:        "SnapPkgs",
-					Description: "Packages to install if Snap is available",
-					Type:        deploy.StringSliceType,
-				},
-			*/
 		},
-		Setup: setupInstallAction,
+		Options: options,
+		Setup:   setupInstallAction,
 	})
 }
 
+const installExample = `[Task]
+Description= Install nginx and keep it at 1.24
+
+[InstallPackages]
+AptPkgs= nginx=1.24.* curl
+Hold= yes
+RefreshCache= yes
+CacheValidSec= 3600
+`
+
 func setupInstallAction(task deploy.Task, sec conf.Section) (actions.Action, error) {
-	aptPkgs := getPackages("AptPkgs", sec)
-	pacmanPkgs := getPackages("PacmanPkgs", sec)
-	dnfPkgs := getPackages("DnfPkgs", sec)
-	snapPkgs := getPackages("SnapPkgs", sec)
+	pkgs := make(map[string][]string)
+
+	for _, p := range packageOptions {
+		if list := getPackages(p.option, sec); len(list) > 0 {
+			pkgs[p.manager] = list
+		}
+	}
 
-	if len(aptPkgs) == 0 && len(pacmanPkgs) == 0 && len(dnfPkgs) == 0 && len(snapPkgs) == 0 {
+	if len(pkgs) == 0 {
 		return nil, fmt.Errorf("no packages to install")
 	}
 
+	state, err := sec.GetString("State")
+	if err != nil && !conf.IsNotSet(err) {
+		return nil, err
+	}
+	state = strings.ToLower(state)
+	if state == "" {
+		state = statePresent
+	}
+
+	switch state {
+	case statePresent, stateAbsent, stateLatest:
+	default:
+		return nil, fmt.Errorf("invalid value for State=: %q", state)
+	}
+
+	if state != statePresent {
+		for _, list := range pkgs {
+			for _, spec := range pkgmgr.Specs(list...) {
+				if spec.IsPinned() {
+					return nil, fmt.Errorf("version pin %s cannot be used with State=%s", spec, state)
+				}
+			}
+		}
+	}
+
+	hold, err := sec.GetBool("Hold")
+	if err != nil && !conf.IsNotSet(err) {
+		return nil, err
+	}
+
+	if hold && state == stateAbsent {
+		return nil, fmt.Errorf("option Hold= cannot be used with State=%s", state)
+	}
+
+	if hold {
+		for name := range pkgs {
+			m, ok := pkgmgr.Get(name)
+			if !ok {
+				continue
+			}
+
+			if _, ok := m.(pkgmgr.Holder); !ok {
+				return nil, fmt.Errorf("option Hold= is not supported by %s", name)
+			}
+		}
+	}
+
+	refresh, err := sec.GetBool("RefreshCache")
+	if err != nil && !conf.IsNotSet(err) {
+		return nil, err
+	}
+
+	validSec, err := sec.GetInt("CacheValidSec")
+	if err != nil && !conf.IsNotSet(err) {
+		return nil, err
+	}
+
+	if validSec < 0 {
+		return nil, fmt.Errorf("invalid value for CacheValidSec=: %d", validSec)
+	}
+
 	return &installAction{
-		aptPkgs:    aptPkgs,
-		pacmanPkgs: pacmanPkgs,
-		dnfPkgs:    dnfPkgs,
-		snapPkgs:   snapPkgs,
+		pkgs:         pkgs,
+		state:        state,
+		hold:         hold,
+		refreshCache: refresh,
+		cacheValid:   time.Duration(validSec) * time.Second,
 	}, nil
 }
 
@@ -87,10 +204,14 @@ func getPackages(configKey string, sec conf.Section) []string {
 type installAction struct {
 	actions.Base
 
-	aptPkgs    []string
-	pacmanPkgs []string
-	dnfPkgs    []string
-	snapPkgs   []string
+	// pkgs holds the packages to install keyed by the
+	// name of the package manager.
+	pkgs map[string][]string
+
+	state        string
+	hold         bool
+	refreshCache bool
+	cacheValid   time.Duration
 }
 
 func (ia *installAction) Name() string {
@@ -102,78 +223,89 @@ func (ia *installAction) Prepare(graph actions.ExecGraph) error {
 }
 
 func (ia *installAction) Execute(ctx context.Context) (bool, error) {
-	managers := getPackageManagers()
-
 	var changed bool
-	for _, m := range managers {
-		var err error
-
-		switch m {
-		case Pacman:
-			if len(ia.pacmanPkgs) == 0 {
-				continue
-			}
-			changed, err = installPacman(ctx, ia.pacmanPkgs...)
-
-		case APT:
-			if len(ia.aptPkgs) == 0 {
-				continue
-			}
-			changed, err = installApt(ctx, ia.aptPkgs...)
-
-		default:
+	for _, m := range detectManagers() {
+		pkgs := ia.pkgs[m.Name()]
+		if len(pkgs) == 0 {
 			continue
 		}
 
+		c, err := ia.apply(ctx, m, pkgs)
 		if err != nil {
-			return false, err
+			return false, fmt.Errorf("%s: %w", m.Name(), err)
 		}
+
+		changed = changed || c
 	}
 
 	return changed, nil
 }
 
-func installPacman(ctx context.Context, pkgs ...string) (bool, error) {
-	args := []string{
-		"-S",
-		"--needed",
-		"--quiet",
-		"--noconfirm",
+// apply ensures the configured state for pkgs using the
+// package manager m.
+func (ia *installAction) apply(ctx context.Context, m pkgmgr.Manager, pkgs []string) (bool, error) {
+	if ia.refreshCache {
+		if err := ia.updateCache(ctx, m); err != nil {
+			return false, fmt.Errorf("failed to update package cache: %w", err)
+		}
 	}
 
-	args = append(args, pkgs...)
-	cmd := exec.CommandContext(ctx, "pacman", args...)
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, "LC_ALL=C")
+	specs := pkgmgr.Specs(pkgs...)
+	names := make([]string, len(specs))
+	for idx, spec := range specs {
+		names[idx] = spec.Name
+	}
 
-	output, err := cmd.CombinedOutput()
+	var (
+		changes []pkgmgr.Change
+		err     error
+	)
+	switch ia.state {
+	case statePresent:
+		changes, err = m.Install(ctx, specs...)
+	case stateLatest:
+		changes, err = m.Upgrade(ctx, names...)
+	case stateAbsent:
+		changes, err = m.Remove(ctx, names...)
+	}
 	if err != nil {
-		return false, fmt.Errorf("failed to install packages: %w\n%s", err, string(output))
+		return false, err
 	}
 
-	nothingToDo := pacmanNothingToDoRegex.Match(output)
-	return !nothingToDo, nil
-}
-
-func installApt(ctx context.Context, pkg ...string) (bool, error) {
-	args := []string{
-		"install",
-		"-y",
+	for _, c := range changes {
+		ia.Infof("%s: %s %s", m.Name(), c.Action(), c)
 	}
-	args = append(args, pkg...)
+	actions.RecordChange(ctx, "packages", pkgmgr.Names(changes)...)
 
-	cmd := exec.CommandContext(ctx, "apt", args...)
-	cmd.Env = os.Environ()
+	changed := len(changes) > 0
 
-	cmd.Env = append(cmd.Env, "DEBCONF_FRONTEND='noninteractive'")
-	cmd.Env = append(cmd.Env, "LC_ALL=C")
+	if ia.hold {
+		holder, ok := m.(pkgmgr.Holder)
+		if !ok {
+			return false, fmt.Errorf("option Hold= is not supported")
+		}
 
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return false, fmt.Errorf("failed to install packages: %w\n%s", err, string(output))
+		held, err := holder.Hold(ctx, names...)
+		if err != nil {
+			return false, fmt.Errorf("failed to hold packages: %w", err)
+		}
+
+		changed = changed || held
 	}
 
-	hasChanged := aptChangedRegex.Match(output)
+	return changed, nil
+}
+
+// updateCache updates the package cache of m unless it's
+// younger than CacheValidSec=.
+func (ia *installAction) updateCache(ctx context.Context, m pkgmgr.Manager) error {
+	if ia.cacheValid > 0 {
+		if age, ok := pkgmgr.CacheAge(m); ok && age < ia.cacheValid {
+			ia.Debugf("%s: package cache is still valid (%s old)", m.Name(), age.Round(time.Second))
+			return nil
+		}
+	}
 
-	return hasChanged, nil
+	ia.Debugf("%s: updating package cache", m.Name())
+	return m.Update(ctx)
 }
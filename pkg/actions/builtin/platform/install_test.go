@@ -0,0 +1,192 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeManager is a pkgmgr.Manager that records all calls
+// and reports each requested package as changed.
+type fakeManager struct {
+	name  string
+	calls []string
+	err   error
+}
+
+func (m *fakeManager) Name() string { return m.name }
+
+func (m *fakeManager) Detect() bool { return true }
+
+func (m *fakeManager) Installed(ctx context.Context) (pkgmgr.Packages, error) {
+	return pkgmgr.Packages{}, nil
+}
+
+func (m *fakeManager) record(op string, pkgs []string) ([]pkgmgr.Change, error) {
+	m.calls = append(m.calls, op)
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var changes []pkgmgr.Change
+	for _, pkg := range pkgs {
+		m.calls = append(m.calls, op+" "+pkg)
+		changes = append(changes, pkgmgr.Change{Name: pkg, To: "1.0"})
+	}
+	return changes, nil
+}
+
+func (m *fakeManager) Install(ctx context.Context, specs ...pkgmgr.Spec) ([]pkgmgr.Change, error) {
+	pkgs := make([]string, len(specs))
+	for idx, spec := range specs {
+		pkgs[idx] = spec.String()
+	}
+	return m.record("install", pkgs)
+}
+
+func (m *fakeManager) Upgrade(ctx context.Context, pkgs ...string) ([]pkgmgr.Change, error) {
+	return m.record("upgrade", pkgs)
+}
+
+func (m *fakeManager) Remove(ctx context.Context, pkgs ...string) ([]pkgmgr.Change, error) {
+	return m.record("remove", pkgs)
+}
+
+func (m *fakeManager) Update(ctx context.Context) error {
+	m.calls = append(m.calls, "update")
+	return nil
+}
+
+// fakeHolder is a fakeManager that supports holding packages.
+type fakeHolder struct {
+	fakeManager
+}
+
+func (m *fakeHolder) Hold(ctx context.Context, pkgs ...string) (bool, error) {
+	_, err := m.record("hold", pkgs)
+	return err == nil, err
+}
+
+func withManagers(managers ...pkgmgr.Manager) func() {
+	old := detectManagers
+	detectManagers = func() []pkgmgr.Manager { return managers }
+	return func() { detectManagers = old }
+}
+
+func setupInstall(opts ...conf.Option) (actions.Action, error) {
+	act, err := setupInstallAction(deploy.Task{FileName: "test.task"}, conf.Section{
+		Name:    "InstallPackages",
+		Options: opts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	act.SetLogger(actions.NewLogger())
+	return act, nil
+}
+
+func TestInstallSetup(t *testing.T) {
+	cases := []struct {
+		options conf.Options
+		err     string
+	}{
+		{conf.Options{{Name: "AptPkgs", Value: "nginx"}}, ""},
+		{conf.Options{{Name: "State", Value: "present"}}, "no packages to install"},
+		{conf.Options{{Name: "AptPkgs", Value: "nginx"}, {Name: "State", Value: "purged"}}, `invalid value for State=: "purged"`},
+		{conf.Options{{Name: "AptPkgs", Value: "nginx=1.*"}, {Name: "State", Value: "latest"}}, "version pin nginx=1.* cannot be used with State=latest"},
+		{conf.Options{{Name: "AptPkgs", Value: "nginx"}, {Name: "State", Value: "absent"}, {Name: "Hold", Value: "yes"}}, "option Hold= cannot be used with State=absent"},
+		{conf.Options{{Name: "PacmanPkgs", Value: "nginx"}, {Name: "Hold", Value: "yes"}}, ""},
+		{conf.Options{{Name: "AptPkgs", Value: "nginx"}, {Name: "DnfPkgs", Value: "nginx"}, {Name: "Hold", Value: "yes"}}, "option Hold= is not supported by dnf"},
+		{conf.Options{{Name: "ZypperPkgs", Value: "nginx"}, {Name: "Hold", Value: "yes"}}, "option Hold= is not supported by zypper"},
+		{conf.Options{{Name: "AptPkgs", Value: "nginx"}, {Name: "CacheValidSec", Value: "-1"}}, "invalid value for CacheValidSec=: -1"},
+	}
+
+	for idx, c := range cases {
+		_, err := setupInstall(c.options...)
+		if c.err == "" {
+			assert.NoError(t, err, idx)
+		} else {
+			assert.EqualError(t, err, c.err, idx)
+		}
+	}
+}
+
+func TestInstallExecute(t *testing.T) {
+	apt := &fakeHolder{fakeManager{name: pkgmgr.APT}}
+	dnf := &fakeManager{name: pkgmgr.Dnf}
+	defer withManagers(apt, dnf)()
+
+	act, err := setupInstall(
+		conf.Option{Name: "AptPkgs", Value: "nginx=1.24.* curl"},
+		conf.Option{Name: "PacmanPkgs", Value: "nginx"},
+		conf.Option{Name: "Hold", Value: "yes"},
+		conf.Option{Name: "RefreshCache", Value: "yes"},
+	)
+	require.NoError(t, err)
+
+	ctx := actions.WithChanges(context.Background())
+	changed, err := act.(actions.Executor).Execute(ctx)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	assert.Equal(t, []string{
+		"update",
+		"install",
+		"install nginx=1.24.*",
+		"install curl",
+		"hold",
+		"hold nginx",
+		"hold curl",
+	}, apt.calls)
+	assert.Empty(t, dnf.calls, "dnf has no packages configured")
+	assert.Equal(t, []string{"nginx=1.24.*", "curl"}, actions.ChangesFromContext(ctx).Get("packages"))
+}
+
+func TestInstallExecuteState(t *testing.T) {
+	cases := []struct {
+		state string
+		calls []string
+	}{
+		{"present", []string{"install", "install nginx"}},
+		{"latest", []string{"upgrade", "upgrade nginx"}},
+		{"absent", []string{"remove", "remove nginx"}},
+	}
+
+	for _, c := range cases {
+		m := &fakeManager{name: pkgmgr.APT}
+		restore := withManagers(m)
+
+		act, err := setupInstall(
+			conf.Option{Name: "AptPkgs", Value: "nginx"},
+			conf.Option{Name: "State", Value: c.state},
+		)
+		require.NoError(t, err, c.state)
+
+		changed, err := act.(actions.Executor).Execute(context.Background())
+		require.NoError(t, err, c.state)
+		assert.True(t, changed, c.state)
+		assert.Equal(t, c.calls, m.calls, c.state)
+
+		restore()
+	}
+}
+
+func TestInstallExecuteError(t *testing.T) {
+	m := &fakeManager{name: pkgmgr.APT, err: errors.New("no space left on device")}
+	defer withManagers(m)()
+
+	act, err := setupInstall(conf.Option{Name: "AptPkgs", Value: "nginx"})
+	require.NoError(t, err)
+
+	changed, err := act.(actions.Executor).Execute(context.Background())
+	assert.EqualError(t, err, "apt: no space left on device")
+	assert.False(t, changed)
+}
@@ -0,0 +1,61 @@
+package platform
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	deploy.RegisterAllConditions()
+}
+
+// fakeGraph records the tasks disabled by the action.
+type fakeGraph struct {
+	actions.ExecGraph
+
+	disabled []string
+}
+
+func (g *fakeGraph) DisableTask(task string) error {
+	g.disabled = append(g.disabled, task)
+	return nil
+}
+
+func TestPlatformOperatingSystem(t *testing.T) {
+	cases := []struct {
+		value   string
+		disable bool
+	}{
+		{runtime.GOOS, false},
+		{"=" + runtime.GOOS, false},
+		{"!" + runtime.GOOS, true},
+		{"plan9-unknown", true},
+		{"=plan9-unknown", true},
+	}
+
+	for _, c := range cases {
+		act, err := setupPlatform(deploy.Task{FileName: "test.task"}, conf.Section{
+			Name: "Platform",
+			Options: conf.Options{
+				{Name: "OperatingSystem", Value: c.value},
+			},
+		})
+		require.NoError(t, err, c.value)
+		act.SetLogger(actions.NewLogger())
+
+		graph := &fakeGraph{}
+		require.NoError(t, act.(actions.Preparer).Prepare(graph), c.value)
+
+		if c.disable {
+			assert.Equal(t, []string{"test.task"}, graph.disabled, c.value)
+		} else {
+			assert.Empty(t, graph.disabled, c.value)
+		}
+	}
+}
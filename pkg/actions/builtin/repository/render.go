@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// header is added to all files created by PackageRepository.
+const header = "# Managed by system-deploy\n"
+
+// renderDeb822 renders repo as a deb822 sources file. If
+// signedBy is set, it's used as the path to the signing key.
+func renderDeb822(repo repository, signedBy string) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(header)
+	fmt.Fprintf(&buf, "Types: %s\n", strings.Join(repo.sourceTypes, " "))
+	fmt.Fprintf(&buf, "URIs: %s\n", repo.url)
+	fmt.Fprintf(&buf, "Suites: %s\n", strings.Join(repo.suites, " "))
+
+	if len(repo.components) > 0 {
+		fmt.Fprintf(&buf, "Components: %s\n", strings.Join(repo.components, " "))
+	}
+	if len(repo.architectures) > 0 {
+		fmt.Fprintf(&buf, "Architectures: %s\n", strings.Join(repo.architectures, " "))
+	}
+	if signedBy != "" {
+		fmt.Fprintf(&buf, "Signed-By: %s\n", signedBy)
+	}
+
+	return buf.Bytes()
+}
+
+// renderRepoFile renders repo as a DNF .repo file. If keyFile
+// is set, GPG checks are enabled.
+func renderRepoFile(repo repository, keyFile string) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(header)
+	fmt.Fprintf(&buf, "[%s]\n", repo.name)
+	fmt.Fprintf(&buf, "name=%s\n", repo.description)
+	fmt.Fprintf(&buf, "baseurl=%s\n", repo.url)
+	buf.WriteString("enabled=1\n")
+
+	if keyFile != "" {
+		buf.WriteString("gpgcheck=1\n")
+		fmt.Fprintf(&buf, "gpgkey=file://%s\n", keyFile)
+	} else {
+		buf.WriteString("gpgcheck=0\n")
+	}
+
+	return buf.Bytes()
+}
+
+// renderPacmanSection renders repo as a section for
+// pacman.conf.
+func renderPacmanSection(repo repository) string {
+	var buf strings.Builder
+
+	fmt.Fprintf(&buf, "[%s]\n", repo.name)
+	if repo.sigLevel != "" {
+		fmt.Fprintf(&buf, "SigLevel = %s\n", repo.sigLevel)
+	}
+	fmt.Fprintf(&buf, "Server = %s\n", repo.url)
+
+	return buf.String()
+}
+
+// setPacmanSection replaces the section name in content with
+// section. If section is empty, the section is removed. If
+// the section does not exist yet, it's appended. It returns
+// the updated content and true if something changed.
+func setPacmanSection(content []byte, name, section string) ([]byte, bool) {
+	var (
+		before   []string
+		existing []string
+		after    []string
+		found    bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "[") && trimmed == "["+name+"]" {
+			found = true
+		}
+
+		switch {
+		case !found:
+			before = append(before, line)
+		case after == nil && (len(existing) == 0 || !strings.HasPrefix(trimmed, "[")):
+			existing = append(existing, line)
+		default:
+			after = append(after, line)
+		}
+	}
+
+	// empty lines and comments at the end of the section
+	// belong to the next one.
+	for len(existing) > 0 {
+		last := strings.TrimSpace(existing[len(existing)-1])
+		if last != "" && !strings.HasPrefix(last, "#") {
+			break
+		}
+		after = append([]string{existing[len(existing)-1]}, after...)
+		existing = existing[:len(existing)-1]
+	}
+
+	if !found {
+		if section == "" {
+			return content, false
+		}
+
+		if len(before) > 0 && strings.TrimSpace(before[len(before)-1]) != "" {
+			before = append(before, "")
+		}
+	} else if strings.Join(existing, "\n")+"\n" == section {
+		return content, false
+	}
+
+	result := before
+	if section != "" {
+		result = append(result, strings.Split(strings.TrimSuffix(section, "\n"), "\n")...)
+	}
+	result = append(result, after...)
+
+	return []byte(strings.Join(result, "\n") + "\n"), true
+}
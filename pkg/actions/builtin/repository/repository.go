@@ -0,0 +1,580 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/change"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
+	"github.com/ppacher/system-deploy/pkg/utils"
+)
+
+// nameRegex matches valid repository names.
+var nameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+func init() {
+	actions.MustRegister(actions.Plugin{
+		Name:        "PackageRepository",
+		Description: "Manage third-party package repositories for APT, DNF and Pacman.",
+		Setup:       setupAction,
+		Example:     example,
+		Author:      "Patrick Pacher <patrick.pacher@gmail.com>",
+		Website:     "https://github.com/ppacher/system-deploy",
+		Help: []actions.HelpSection{
+			{
+				Title: "Repository Files",
+				Description: "" +
+					"For APT, the repository is written as a deb822 file to `/etc/apt/sources.list.d/<Name>.sources` and " +
+					"the signing key is stored in `/etc/apt/keyrings`. For DNF, a `/etc/yum.repos.d/<Name>.repo` file is " +
+					"created and the key is stored in `/etc/pki/rpm-gpg`. For Pacman, a `[<Name>]` section is managed in " +
+					"`/etc/pacman.conf`; signing keys need to be imported using `pacman-key` as Key= is not supported.",
+			},
+			{
+				Title: "Signing Keys",
+				Description: "" +
+					"Keys referenced by an HTTP(S) URL are only downloaded if the key has not been installed yet. " +
+					"Set KeySHA256= to verify the key and to replace an installed key that does not match the checksum, " +
+					"for example after the key has been rotated.",
+			},
+			{
+				Title: "Package Cache",
+				Description: "" +
+					"The package cache of the package manager is only refreshed if the repository definition or the signing " +
+					"key has been changed.",
+			},
+		},
+		Options: []conf.OptionSpec{
+			{
+				Name:        "Name",
+				Type:        conf.StringType,
+				Required:    true,
+				Description: "The name of the repository. Used as the file name of the repository definition.",
+			},
+			{
+				Name:        "Type",
+				Type:        conf.StringType,
+				Description: "The package manager of the repository. One of \"apt\", \"dnf\" or \"pacman\". Defaults to the first one available.",
+			},
+			{
+				Name:        "Description",
+				Type:        conf.StringType,
+				Description: "A human readable description of the repository. Only used for DNF.",
+			},
+			{
+				Name:        "URL",
+				Type:        conf.StringType,
+				Description: "The base URL of the repository. Required if State=present.",
+			},
+			{
+				Name:        "Suites",
+				Type:        conf.StringSliceType,
+				Description: "The suites of an APT repository (like the distribution codename).",
+			},
+			{
+				Name:        "Components",
+				Type:        conf.StringSliceType,
+				Description: "The components of an APT repository (like main).",
+			},
+			{
+				Name:        "Architectures",
+				Type:        conf.StringSliceType,
+				Description: "Restrict an APT repository to the given architectures.",
+			},
+			{
+				Name:        "SourceTypes",
+				Type:        conf.StringSliceType,
+				Description: "The types of an APT repository.",
+				Default:     "deb",
+			},
+			{
+				Name:        "SigLevel",
+				Type:        conf.StringType,
+				Description: "The SigLevel of a Pacman repository.",
+			},
+			{
+				Name:        "Key",
+				Type:        conf.StringType,
+				Description: "Path or HTTP(S) URL of the signing key of the repository. Relative paths are resolved against the task directory. ASCII-armored and binary keys are supported.",
+			},
+			{
+				Name:        "KeySHA256",
+				Type:        conf.StringType,
+				Description: "The SHA-256 checksum of the signing key in hex encoding. If set, the key is verified before it's installed.",
+			},
+			{
+				Name:        "State",
+				Type:        conf.StringType,
+				Description: "Whether the repository should be \"present\" or \"absent\".",
+				Default:     "present",
+			},
+		},
+	})
+}
+
+// Paths used to store repository definitions and keys.
+var (
+	aptSourcesDir = "/etc/apt/sources.list.d"
+	aptKeyringDir = "/etc/apt/keyrings"
+	dnfReposDir   = "/etc/yum.repos.d"
+	dnfKeyDir     = "/etc/pki/rpm-gpg"
+	pacmanConf    = "/etc/pacman.conf"
+)
+
+// httpClient is used to download signing keys.
+var httpClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+// supported holds all package managers for which repositories
+// can be managed.
+var supported = []string{pkgmgr.APT, pkgmgr.Dnf, pkgmgr.Pacman}
+
+// isAvailable is used to detect the package manager if Type=
+// is not set. It's a variable so it can be replaced in tests.
+var isAvailable = pkgmgr.IsAvailable
+
+type repository struct {
+	name          string
+	description   string
+	url           string
+	suites        []string
+	components    []string
+	architectures []string
+	sourceTypes   []string
+	sigLevel      string
+}
+
+type action struct {
+	actions.Base
+
+	task   deploy.Task
+	kind   string
+	key    string
+	keySum string
+	absent bool
+	repo   repository
+
+	// updateCache is called when the repository changed.
+	// It's nil in tests.
+	updateCache func(context.Context) error
+}
+
+func setupAction(task deploy.Task, sec conf.Section) (actions.Action, error) {
+	name, err := sec.GetString("Name")
+	if err != nil {
+		return nil, err
+	}
+
+	if !nameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid value for Name: %q", name)
+	}
+
+	state, err := sec.GetString("State")
+	if err != nil {
+		if !conf.IsNotSet(err) {
+			return nil, err
+		}
+		state = "present"
+	}
+
+	state = strings.ToLower(state)
+	if state != "present" && state != "absent" {
+		return nil, fmt.Errorf("invalid value for State: %q", state)
+	}
+
+	kind, err := sec.GetString("Type")
+	if err != nil && !conf.IsNotSet(err) {
+		return nil, err
+	}
+
+	kind = strings.ToLower(kind)
+	if kind != "" && !isSupported(kind) {
+		return nil, fmt.Errorf("invalid value for Type: %q", kind)
+	}
+
+	a := &action{
+		task:   task,
+		kind:   kind,
+		absent: state == "absent",
+		repo: repository{
+			name:          name,
+			suites:        sec.GetStringSlice("Suites"),
+			components:    sec.GetStringSlice("Components"),
+			architectures: sec.GetStringSlice("Architectures"),
+			sourceTypes:   sec.GetStringSlice("SourceTypes"),
+		},
+	}
+
+	for opt, target := range map[string]*string{
+		"Description": &a.repo.description,
+		"URL":         &a.repo.url,
+		"SigLevel":    &a.repo.sigLevel,
+		"Key":         &a.key,
+		"KeySHA256":   &a.keySum,
+	} {
+		*target, err = sec.GetString(opt)
+		if err != nil && !conf.IsNotSet(err) {
+			return nil, err
+		}
+	}
+
+	if a.repo.description == "" {
+		a.repo.description = name
+	}
+
+	if len(a.repo.sourceTypes) == 0 {
+		a.repo.sourceTypes = []string{"deb"}
+	}
+
+	a.keySum = strings.ToLower(a.keySum)
+	if a.keySum != "" {
+		if a.key == "" {
+			return nil, fmt.Errorf("option KeySHA256= requires Key=")
+		}
+
+		if sum, err := hex.DecodeString(a.keySum); err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("invalid value for KeySHA256: %q", a.keySum)
+		}
+	}
+
+	if a.key != "" && !isURL(a.key) && !filepath.IsAbs(a.key) {
+		a.key = filepath.Join(task.Directory, a.key)
+	}
+
+	if !a.absent && a.repo.url == "" {
+		return nil, fmt.Errorf("option URL= is required")
+	}
+
+	// if the package manager is detected automatically the
+	// options are validated in Prepare.
+	if err := a.validate(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// validate checks if the options of a are supported by the
+// package manager of a. It's a no-op if the package manager
+// is not yet known.
+func (a *action) validate() error {
+	if a.kind == pkgmgr.Pacman && a.key != "" {
+		return fmt.Errorf("option Key= is not supported for pacman")
+	}
+
+	if a.kind == pkgmgr.APT && !a.absent && len(a.repo.suites) == 0 {
+		return fmt.Errorf("option Suites= is required for apt")
+	}
+
+	return nil
+}
+
+func (a *action) Name() string {
+	return "PackageRepository " + a.repo.name
+}
+
+func (a *action) Prepare(graph actions.ExecGraph) error {
+	if a.kind == "" {
+		for _, name := range supported {
+			if isAvailable(name) {
+				a.kind = name
+				break
+			}
+		}
+
+		if a.kind == "" {
+			return fmt.Errorf("%s: no supported package manager found", a.repo.name)
+		}
+
+		if err := a.validate(); err != nil {
+			return fmt.Errorf("%s: %w", a.repo.name, err)
+		}
+	}
+
+	if m, ok := pkgmgr.Get(a.kind); ok {
+		a.updateCache = m.Update
+	}
+
+	return nil
+}
+
+func (a *action) Execute(ctx context.Context) (bool, error) {
+	var (
+		changed bool
+		err     error
+	)
+
+	switch a.kind {
+	case pkgmgr.APT:
+		changed, err = a.apt(ctx)
+	case pkgmgr.Dnf:
+		changed, err = a.dnf(ctx)
+	case pkgmgr.Pacman:
+		changed, err = a.pacman()
+	}
+	if err != nil || !changed {
+		return changed, err
+	}
+
+	if a.updateCache != nil {
+		a.Debugf("%s: repository changed, updating package cache", a.repo.name)
+		if err := a.updateCache(ctx); err != nil {
+			return true, fmt.Errorf("failed to update package cache: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+// apt manages a deb822 sources file and the signing key.
+func (a *action) apt(ctx context.Context) (bool, error) {
+	sourcesFile := filepath.Join(aptSourcesDir, a.repo.name+".sources")
+
+	if a.absent {
+		return removeFiles(
+			sourcesFile,
+			filepath.Join(aptKeyringDir, a.repo.name+".asc"),
+			filepath.Join(aptKeyringDir, a.repo.name+".gpg"),
+		)
+	}
+
+	var signedBy string
+	var changed bool
+	if a.key != "" {
+		var err error
+		signedBy, changed, err = a.installKey(ctx, aptKeyringDir, a.repo.name)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	c, err := writeFile(sourcesFile, renderDeb822(a.repo, signedBy))
+	return changed || c, err
+}
+
+// dnf manages a .repo file and the signing key.
+func (a *action) dnf(ctx context.Context) (bool, error) {
+	repoFile := filepath.Join(dnfReposDir, a.repo.name+".repo")
+	keyName := "RPM-GPG-KEY-" + a.repo.name
+
+	if a.absent {
+		return removeFiles(
+			repoFile,
+			filepath.Join(dnfKeyDir, keyName+".asc"),
+			filepath.Join(dnfKeyDir, keyName+".gpg"),
+		)
+	}
+
+	var keyFile string
+	var changed bool
+	if a.key != "" {
+		var err error
+		keyFile, changed, err = a.installKey(ctx, dnfKeyDir, keyName)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	c, err := writeFile(repoFile, renderRepoFile(a.repo, keyFile))
+	return changed || c, err
+}
+
+// pacman manages the repository section in pacman.conf.
+func (a *action) pacman() (bool, error) {
+	content, err := ioutil.ReadFile(pacmanConf)
+	if err != nil {
+		return false, err
+	}
+
+	var section string
+	if !a.absent {
+		section = renderPacmanSection(a.repo)
+	}
+
+	updated, changed := setPacmanSection(content, a.repo.name, section)
+	if !changed {
+		return false, nil
+	}
+
+	return writeFile(pacmanConf, updated)
+}
+
+// installKey fetches the signing key and stores it as name in
+// dir. The file extension depends on whether the key is
+// ASCII-armored or not. It returns the path of the key file.
+func (a *action) installKey(ctx context.Context, dir, name string) (string, bool, error) {
+	// avoid downloading the key on each run if it's already
+	// installed.
+	if isURL(a.key) {
+		if path, ok := a.installedKey(dir, name); ok {
+			a.Debugf("%s: using installed key %s", a.repo.name, path)
+			return path, false, nil
+		}
+	}
+
+	key, err := fetchKey(ctx, a.key)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to fetch key: %w", err)
+	}
+
+	if a.keySum != "" && checksum(key) != a.keySum {
+		return "", false, fmt.Errorf("checksum mismatch for key %s: expected %s but got %s", a.key, a.keySum, checksum(key))
+	}
+
+	ext, other := ".gpg", ".asc"
+	if bytes.Contains(key, []byte("-----BEGIN PGP PUBLIC KEY BLOCK-----")) {
+		ext, other = other, ext
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", false, err
+	}
+
+	path := filepath.Join(dir, name+ext)
+
+	changed, err := writeFile(path, key)
+	if err != nil {
+		return "", false, err
+	}
+
+	// remove a key stored with the other format.
+	removed, err := removeFiles(filepath.Join(dir, name+other))
+	if err != nil {
+		return "", false, err
+	}
+
+	return path, changed || removed, nil
+}
+
+// installedKey returns the path of the key stored as name in
+// dir. If KeySHA256= is set, the key must match the checksum.
+func (a *action) installedKey(dir, name string) (string, bool) {
+	for _, ext := range []string{".gpg", ".asc"} {
+		path := filepath.Join(dir, name+ext)
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		if a.keySum == "" || checksum(content) == a.keySum {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// checksum returns the hex encoded SHA-256 checksum of
+// content.
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// fetchKey reads the key from a local file or downloads it
+// if key is an HTTP(S) URL.
+func fetchKey(ctx context.Context, key string) ([]byte, error) {
+	if !isURL(key) {
+		return ioutil.ReadFile(key)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return ioutil.ReadAll(res.Body)
+}
+
+// writeFile writes content to path if it differs from the
+// current content of path.
+func writeFile(path string, content []byte) (bool, error) {
+	needed, err := change.ContentUpdateNeeded(content, path)
+	if err != nil || !needed {
+		return false, err
+	}
+
+	mode, err := utils.FileMode(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return false, err
+		}
+		mode = 0644
+	}
+
+	if err := utils.CreateAtomic(path, mode, bytes.NewReader(content)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// removeFiles removes all paths and returns true if at
+// least one of them existed.
+func removeFiles(paths ...string) (bool, error) {
+	var changed bool
+	for _, p := range paths {
+		err := os.Remove(p)
+		if err == nil {
+			changed = true
+			continue
+		}
+
+		if !os.IsNotExist(err) {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+func isSupported(kind string) bool {
+	for _, s := range supported {
+		if s == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+const example = `[Task]
+Description= Add the official nginx repository
+
+[PackageRepository]
+Name= nginx
+Type= apt
+URL= https://nginx.org/packages/ubuntu
+Suites= jammy
+Components= nginx
+Key= https://nginx.org/keys/nginx_signing.key
+
+[InstallPackages]
+AptPkgs= nginx
+`
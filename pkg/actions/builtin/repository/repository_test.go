@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderDeb822(t *testing.T) {
+	repo := repository{
+		name:        "nginx",
+		url:         "https://nginx.org/packages/ubuntu",
+		suites:      []string{"jammy"},
+		components:  []string{"nginx"},
+		sourceTypes: []string{"deb", "deb-src"},
+	}
+
+	assert.Equal(t, ""+
+		"# Managed by system-deploy\n"+
+		"Types: deb deb-src\n"+
+		"URIs: https://nginx.org/packages/ubuntu\n"+
+		"Suites: jammy\n"+
+		"Components: nginx\n"+
+		"Signed-By: /etc/apt/keyrings/nginx.asc\n",
+		string(renderDeb822(repo, "/etc/apt/keyrings/nginx.asc")))
+}
+
+func TestRenderRepoFile(t *testing.T) {
+	repo := repository{
+		name:        "nginx",
+		description: "nginx stable",
+		url:         "https://nginx.org/packages/centos/8/x86_64/",
+	}
+
+	assert.Equal(t, ""+
+		"# Managed by system-deploy\n"+
+		"[nginx]\n"+
+		"name=nginx stable\n"+
+		"baseurl=https://nginx.org/packages/centos/8/x86_64/\n"+
+		"enabled=1\n"+
+		"gpgcheck=1\n"+
+		"gpgkey=file:///etc/pki/rpm-gpg/RPM-GPG-KEY-nginx.asc\n",
+		string(renderRepoFile(repo, "/etc/pki/rpm-gpg/RPM-GPG-KEY-nginx.asc")))
+}
+
+func TestSetPacmanSection(t *testing.T) {
+	conf := "[options]\nArchitecture = auto\n\n[core]\nInclude = /etc/pacman.d/mirrorlist\n"
+	section := renderPacmanSection(repository{
+		name:     "custom",
+		url:      "https://example.com/$arch",
+		sigLevel: "Optional TrustAll",
+	})
+
+	// append a new section
+	updated, changed := setPacmanSection([]byte(conf), "custom", section)
+	assert.True(t, changed)
+	assert.Equal(t, conf+"\n[custom]\nSigLevel = Optional TrustAll\nServer = https://example.com/$arch\n", string(updated))
+
+	// no changes
+	_, changed = setPacmanSection(updated, "custom", section)
+	assert.False(t, changed)
+
+	// replace a section in the middle
+	withNext := string(updated) + "\n# extra\n[extra]\nInclude = /etc/pacman.d/mirrorlist\n"
+	replaced, changed := setPacmanSection([]byte(withNext), "custom", "[custom]\nServer = https://mirror.example.com/$arch\n")
+	assert.True(t, changed)
+	assert.Equal(t, conf+"\n[custom]\nServer = https://mirror.example.com/$arch\n\n# extra\n[extra]\nInclude = /etc/pacman.d/mirrorlist\n", string(replaced))
+
+	// remove the section
+	removed, changed := setPacmanSection(replaced, "custom", "")
+	assert.True(t, changed)
+	assert.Equal(t, conf+"\n\n# extra\n[extra]\nInclude = /etc/pacman.d/mirrorlist\n", string(removed))
+
+	_, changed = setPacmanSection([]byte(conf), "custom", "")
+	assert.False(t, changed)
+}
+
+func TestAptRepository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repository-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	aptSourcesDir = filepath.Join(dir, "sources.list.d")
+	aptKeyringDir = filepath.Join(dir, "keyrings")
+	require.NoError(t, os.MkdirAll(aptSourcesDir, 0755))
+
+	keyFile := filepath.Join(dir, "key.asc")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("-----BEGIN PGP PUBLIC KEY BLOCK-----\nabc\n"), 0644))
+
+	var updates int
+	a := &action{
+		kind: pkgmgr.APT,
+		key:  keyFile,
+		repo: repository{
+			name:        "test",
+			url:         "https://example.com/debian",
+			suites:      []string{"stable"},
+			components:  []string{"main"},
+			sourceTypes: []string{"deb"},
+		},
+		updateCache: func(context.Context) error {
+			updates++
+			return nil
+		},
+	}
+	a.SetLogger(actions.NewLogger())
+
+	changed, err := a.Execute(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, 1, updates)
+
+	content, err := ioutil.ReadFile(filepath.Join(aptSourcesDir, "test.sources"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "Signed-By: "+filepath.Join(aptKeyringDir, "test.asc")+"\n")
+	assert.FileExists(t, filepath.Join(aptKeyringDir, "test.asc"))
+
+	// running again must not change anything or refresh
+	// the package cache.
+	changed, err = a.Execute(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 1, updates)
+
+	a.absent = true
+	changed, err = a.Execute(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, 2, updates)
+	assert.NoFileExists(t, filepath.Join(aptSourcesDir, "test.sources"))
+	assert.NoFileExists(t, filepath.Join(aptKeyringDir, "test.asc"))
+}
+
+func TestKeyDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repository-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dnfReposDir = filepath.Join(dir, "yum.repos.d")
+	dnfKeyDir = filepath.Join(dir, "rpm-gpg")
+	require.NoError(t, os.MkdirAll(dnfReposDir, 0755))
+
+	key := "-----BEGIN PGP PUBLIC KEY BLOCK-----\nfirst\n"
+	var downloads int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Write([]byte(key))
+	}))
+	defer srv.Close()
+
+	a := &action{
+		kind: pkgmgr.Dnf,
+		key:  srv.URL + "/key.asc",
+		repo: repository{
+			name: "test",
+			url:  "https://example.com/fedora",
+		},
+	}
+	a.SetLogger(actions.NewLogger())
+
+	keyFile := filepath.Join(dnfKeyDir, "RPM-GPG-KEY-test.asc")
+
+	changed, err := a.Execute(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, 1, downloads)
+
+	// the installed key is used without downloading it
+	// again even if it changed on the server.
+	key = "-----BEGIN PGP PUBLIC KEY BLOCK-----\nrotated\n"
+	changed, err = a.Execute(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 1, downloads)
+
+	// a checksum that does not match the installed key
+	// causes the key to be downloaded and verified.
+	a.keySum = "0000000000000000000000000000000000000000000000000000000000000000"
+	_, err = a.Execute(context.Background())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "checksum mismatch")
+	}
+	assert.Equal(t, 2, downloads)
+
+	a.keySum = checksum([]byte(key))
+	changed, err = a.Execute(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, 3, downloads)
+
+	content, err := ioutil.ReadFile(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, key, string(content))
+
+	changed, err = a.Execute(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 3, downloads)
+}
+
+func TestPrepareValidatesDetectedType(t *testing.T) {
+	defer func(old func(string) bool) { isAvailable = old }(isAvailable)
+
+	setup := func(opts conf.Options) actions.Preparer {
+		act, err := actions.Setup("PackageRepository", actions.NewLogger(), deploy.Task{}, conf.Section{
+			Name: "PackageRepository",
+			Options: append(conf.Options{
+				{Name: "Name", Value: "test"},
+				{Name: "URL", Value: "https://example.com/repo"},
+			}, opts...),
+		})
+		require.NoError(t, err)
+		return act.(actions.Preparer)
+	}
+
+	isAvailable = func(name string) bool { return name == pkgmgr.Pacman }
+	err := setup(conf.Options{{Name: "Key", Value: "/tmp/key.asc"}}).Prepare(nil)
+	assert.EqualError(t, err, "test: option Key= is not supported for pacman")
+	assert.NoError(t, setup(nil).Prepare(nil))
+
+	isAvailable = func(name string) bool { return name == pkgmgr.APT }
+	err = setup(nil).Prepare(nil)
+	assert.EqualError(t, err, "test: option Suites= is required for apt")
+	assert.NoError(t, setup(conf.Options{{Name: "Suites", Value: "stable"}}).Prepare(nil))
+}
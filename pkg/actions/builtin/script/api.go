@@ -0,0 +1,262 @@
+package script
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/change"
+	"github.com/ppacher/system-deploy/pkg/utils"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// builtinFunc is the signature of Starlark built-in functions.
+type builtinFunc func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error)
+
+// builtins returns all predeclared values available to
+// scripts.
+func (a *action) builtins(ctx context.Context) starlark.StringDict {
+	env := starlark.NewDict(len(a.task.Environment))
+	for _, e := range a.task.Environment {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 {
+			// later values win, like in the task environment.
+			_ = env.SetKey(starlark.String(parts[0]), starlark.String(parts[1]))
+		}
+	}
+	env.Freeze()
+
+	return starlark.StringDict{
+		"file": module("file", map[string]builtinFunc{
+			"read":     a.fileRead,
+			"write":    a.fileWrite(ctx),
+			"exists":   a.fileExists,
+			"remove":   a.fileRemove(ctx),
+			"checksum": a.fileChecksum,
+		}),
+		"log": module("log", map[string]builtinFunc{
+			"debug": a.logFunc(a.Debugf),
+			"info":  a.logFunc(a.Infof),
+			"warn":  a.logFunc(a.Warnf),
+		}),
+		"task": starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"name":      starlark.String(a.task.FileName),
+			"directory": starlark.String(a.task.Directory),
+			"env":       env,
+		}),
+		"checksum": starlark.NewBuiltin("checksum", checksum),
+		"run":      starlark.NewBuiltin("run", a.run(ctx)),
+	}
+}
+
+// module returns a new Starlark module called name.
+func module(name string, funcs map[string]builtinFunc) *starlarkstruct.Module {
+	members := make(starlark.StringDict, len(funcs))
+	for fnName, fn := range funcs {
+		members[fnName] = starlark.NewBuiltin(name+"."+fnName, fn)
+	}
+
+	return &starlarkstruct.Module{
+		Name:    name,
+		Members: members,
+	}
+}
+
+// path resolves p relative to the task directory.
+func (a *action) path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(a.task.Directory, p)
+}
+
+func (a *action) fileRead(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &path); err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(a.path(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return starlark.None, nil
+		}
+		return nil, err
+	}
+
+	return starlark.String(content), nil
+}
+
+func (a *action) fileWrite(ctx context.Context) builtinFunc {
+	return func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var (
+			path    string
+			content string
+			mode    = 0644
+		)
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &path, "content", &content, "mode?", &mode); err != nil {
+			return nil, err
+		}
+
+		path = a.path(path)
+
+		needsUpdate, err := change.ContentUpdateNeeded([]byte(content), path)
+		if err != nil {
+			return nil, err
+		}
+
+		modeChanged := false
+		if needsUpdate {
+			if err := utils.CreateAtomic(path, os.FileMode(mode), strings.NewReader(content)); err != nil {
+				return nil, err
+			}
+		} else {
+			modeChanged, err = change.EnsureFileMode(path, os.FileMode(mode))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		changed := needsUpdate || modeChanged
+		if changed {
+			a.Debugf("%s: updated %s", a.name, path)
+			actions.RecordChange(ctx, "files", path)
+		}
+
+		return starlark.Bool(changed), nil
+	}
+}
+
+func (a *action) fileExists(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &path); err != nil {
+		return nil, err
+	}
+
+	_, err := os.Stat(a.path(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return starlark.False, nil
+		}
+		return nil, err
+	}
+
+	return starlark.True, nil
+}
+
+func (a *action) fileRemove(ctx context.Context) builtinFunc {
+	return func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var path string
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &path); err != nil {
+			return nil, err
+		}
+
+		path = a.path(path)
+		if err := os.Remove(path); err != nil {
+			if os.IsNotExist(err) {
+				return starlark.False, nil
+			}
+			return nil, err
+		}
+
+		actions.RecordChange(ctx, "files", path)
+		return starlark.True, nil
+	}
+}
+
+func (a *action) fileChecksum(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &path); err != nil {
+		return nil, err
+	}
+
+	sum, err := change.FileChecksum(a.path(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return starlark.None, nil
+		}
+		return nil, err
+	}
+
+	return starlark.String(sum), nil
+}
+
+func checksum(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var data string
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "data", &data); err != nil {
+		return nil, err
+	}
+
+	sum, err := change.Checksum(strings.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return starlark.String(sum), nil
+}
+
+func (a *action) run(ctx context.Context) builtinFunc {
+	return func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var (
+			command string
+			envDict *starlark.Dict
+		)
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "command", &command, "env?", &envDict); err != nil {
+			return nil, err
+		}
+
+		env := make(map[string]string)
+		if envDict != nil {
+			for _, item := range envDict.Items() {
+				key, ok := starlark.AsString(item[0])
+				if !ok {
+					return nil, errors.New("run: env keys must be strings")
+				}
+				value, ok := starlark.AsString(item[1])
+				if !ok {
+					return nil, errors.New("run: env values must be strings")
+				}
+				env[key] = value
+			}
+		}
+
+		var (
+			output   bytes.Buffer
+			exitCode int64
+		)
+		err := utils.ExecCommand(ctx, a.task.Directory, command, &utils.ExecOptions{
+			Env:      env,
+			Output:   &output,
+			ExitCode: &exitCode,
+		})
+		if err != nil {
+			var exitErr *utils.ExitCodeError
+			if !errors.As(err, &exitErr) {
+				return nil, err
+			}
+		}
+
+		return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"code":   starlark.MakeInt64(exitCode),
+			"output": starlark.String(output.String()),
+		}), nil
+	}
+}
+
+func (a *action) logFunc(log func(string, ...interface{})) builtinFunc {
+	return func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var msg string
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "msg", &msg); err != nil {
+			return nil, err
+		}
+
+		log("%s", msg)
+		return starlark.None, nil
+	}
+}
@@ -0,0 +1,185 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"go.starlark.net/starlark"
+)
+
+func init() {
+	actions.MustRegister(actions.Plugin{
+		Name:        "Script",
+		Description: "Run an embedded Starlark script",
+		Setup:       setupAction,
+		Example:     example,
+		Author:      "Patrick Pacher <patrick.pacher@gmail.com>",
+		Website:     "https://github.com/ppacher/system-deploy",
+		Help: []actions.HelpSection{
+			{
+				Title: "Reporting Changes",
+				Description: "" +
+					"Scripts are written in Starlark, a dialect of Python. If the script defines a `main()` function it is called " +
+					"after the script has been loaded and its return value (a boolean) tells whether the action changed something. " +
+					"Otherwise the script may set the global variable `changed`. Scripts that don't do either are reported as unchanged.",
+			},
+			{
+				Title: "Sandboxed API",
+				Description: "" +
+					"Scripts cannot access the system except through the following built-ins. Relative paths are resolved against " +
+					"the directory of the task. " +
+					"`file.read(path)` returns the content of a file or None if it does not exist. " +
+					"`file.write(path, content, mode=0o644)` atomically writes content to path and returns True if the file was changed. " +
+					"`file.exists(path)` tests if a path exists. " +
+					"`file.remove(path)` removes a file and returns True if it existed. " +
+					"`file.checksum(path)` returns the checksum of a file or None. " +
+					"`checksum(data)` returns the checksum of a string. " +
+					"`run(command, env={})` executes a command and returns a struct with `code` and `output`. " +
+					"`task` holds the `name`, `directory` and `env` (a dictionary) of the task. " +
+					"`log.debug(msg)`, `log.info(msg)` and `log.warn(msg)` log a message. `print()` logs at info level.",
+			},
+		},
+		Options: []conf.OptionSpec{
+			{
+				Name:        "Script",
+				Type:        conf.StringType,
+				Description: "Path to the script file. Relative paths are resolved against the task directory.",
+			},
+			{
+				Name: "Inline",
+				Type: conf.StringSliceType,
+				Description: "An inline script. May be specified multiple times, each value is a line of the script. Leading whitespace of option values is removed " +
+					"so prefix a line with | to keep its indentation. The | itself is removed. Mutually exclusive with Script=.",
+			},
+		},
+	})
+}
+
+func setupAction(task deploy.Task, sec conf.Section) (actions.Action, error) {
+	path, err := sec.GetString("Script")
+	if err != nil && !conf.IsNotSet(err) {
+		return nil, err
+	}
+
+	// the parser trims option values so indentation must
+	// be preserved using a leading |.
+	inline := sec.GetStringSlice("Inline")
+	for idx, line := range inline {
+		inline[idx] = strings.TrimPrefix(line, "|")
+	}
+
+	if path == "" && len(inline) == 0 {
+		return nil, errors.New("one of Script= or Inline= is required")
+	}
+
+	if path != "" && len(inline) > 0 {
+		return nil, errors.New("options Script= and Inline= are mutually exclusive")
+	}
+
+	a := &action{
+		task: task,
+		name: "inline script",
+		src:  strings.Join(inline, "\n") + "\n",
+	}
+
+	if path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(task.Directory, path)
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		a.name = path
+		a.src = string(content)
+	}
+
+	return a, nil
+}
+
+type action struct {
+	actions.Base
+
+	task deploy.Task
+	name string
+	src  string
+}
+
+func (a *action) Name() string {
+	return "Script " + a.name
+}
+
+func (a *action) Execute(ctx context.Context) (bool, error) {
+	thread := &starlark.Thread{
+		Name: a.name,
+		Print: func(_ *starlark.Thread, msg string) {
+			a.Infof("%s", msg)
+		},
+	}
+
+	// abort the script once ctx is cancelled.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	globals, err := starlark.ExecFile(thread, a.name, a.src, a.builtins(ctx))
+	if err != nil {
+		return false, scriptError(err)
+	}
+
+	result := globals["changed"]
+	if main, ok := globals["main"]; ok {
+		fn, ok := main.(starlark.Callable)
+		if !ok {
+			return false, fmt.Errorf("main is not a function")
+		}
+
+		result, err = starlark.Call(thread, fn, nil, nil)
+		if err != nil {
+			return false, scriptError(err)
+		}
+	}
+
+	switch v := result.(type) {
+	case nil, starlark.NoneType:
+		return false, nil
+	case starlark.Bool:
+		return bool(v), nil
+	default:
+		return false, fmt.Errorf("script must report changes as bool but got %s", result.Type())
+	}
+}
+
+// scriptError adds the Starlark backtrace to err if
+// available.
+func scriptError(err error) error {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(evalErr.Backtrace())
+	}
+	return err
+}
+
+const example = `[Task]
+Description= Keep the MOTD in sync with the hostname
+
+[Script]
+Inline= def main():
+Inline= |    name = run("hostname").output.strip()
+Inline= |    return file.write("/etc/motd", "Welcome to %s\n" % name)
+`
@@ -0,0 +1,198 @@
+package script
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setup(t *testing.T, dir string, opts conf.Options) actions.Executor {
+	act, err := actions.Setup("Script", actions.NewLogger(), deploy.Task{
+		FileName:    "10-test.task",
+		Directory:   dir,
+		Environment: []string{"GREETING=hello", "NAME=world"},
+	}, conf.Section{
+		Name:    "Script",
+		Options: opts,
+	})
+	require.NoError(t, err)
+
+	return act.(actions.Executor)
+}
+
+// setupFile decodes content as a task file and sets up its
+// first section.
+func setupFile(t *testing.T, dir, content string) actions.Executor {
+	task, err := deploy.Decode(filepath.Join(dir, "10-test.task"), strings.NewReader(content))
+	require.NoError(t, err)
+
+	act, err := actions.Setup("Script", actions.NewLogger(), *task, task.Sections[0])
+	require.NoError(t, err)
+
+	return act.(actions.Executor)
+}
+
+func inline(lines ...string) conf.Options {
+	var opts conf.Options
+	for _, l := range lines {
+		opts = append(opts, conf.Option{Name: "Inline", Value: l})
+	}
+	return opts
+}
+
+func TestScriptFileWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "script-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	act := setup(t, dir, inline(
+		`content = "%s %s\n" % (task.env["GREETING"], task.env.get("NAME"))`,
+		`changed = file.write("out.txt", content, mode=0o600)`,
+	))
+
+	ctx := actions.WithChanges(context.Background())
+
+	changed, err := act.Execute(ctx)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "out.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello world\n", string(content))
+
+	stat, err := os.Stat(filepath.Join(dir, "out.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	assert.Equal(t, []string{filepath.Join(dir, "out.txt")}, actions.ChangesFromContext(ctx).Get("files"))
+
+	changed, err = act.Execute(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestScriptMain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "script-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	script := `
+def main():
+    if file.exists("marker"):
+        log.info("marker exists, checksum " + file.checksum("marker"))
+        return False
+
+    res = run("sh -c 'echo $FOO; exit 3'", env={"FOO": "bar"})
+    if res.code != 3 or res.output != "bar\n":
+        fail("unexpected result: %s" % res)
+
+    file.write("marker", checksum("data"))
+    return True
+`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "test.star"), []byte(script), 0644))
+
+	act := setup(t, dir, conf.Options{{Name: "Script", Value: "test.star"}})
+
+	changed, err := act.Execute(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	changed, err = act.Execute(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestScriptErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "script-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cases := []conf.Options{
+		inline(`fail("boom")`),
+		inline(`changed = "yes"`),
+		inline(`main = 1`),
+		inline(`open("/etc/passwd")`),
+	}
+
+	for _, opts := range cases {
+		act := setup(t, dir, opts)
+		_, err := act.Execute(context.Background())
+		assert.Error(t, err, opts[0].Value)
+	}
+
+	_, err = actions.Setup("Script", actions.NewLogger(), deploy.Task{}, conf.Section{Name: "Script"})
+	assert.Error(t, err)
+
+	_, err = actions.Setup("Script", actions.NewLogger(), deploy.Task{}, conf.Section{
+		Name: "Script",
+		Options: conf.Options{
+			{Name: "Script", Value: "test.star"},
+			{Name: "Inline", Value: "changed = True"},
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestScriptCancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "script-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	act := setupFile(t, dir, `[Task]
+Description= Never ends
+
+[Script]
+Inline= def main():
+Inline= |    for i in range(1000000000):
+Inline= |        pass
+Inline= |    return True
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = act.Execute(ctx)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+	}
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
+
+func TestScriptInlineIndentation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "script-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	act := setupFile(t, dir, `[Task]
+Description= Write a file
+
+[Script]
+Inline= def main():
+Inline= |    if not file.exists("out.txt"):
+Inline= |        return file.write("out.txt", "|kept")
+Inline= |    return False
+`)
+
+	changed, err := act.Execute(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "out.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "|kept", string(content))
+
+	changed, err = act.Execute(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
@@ -0,0 +1,109 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+)
+
+// backend is used to communicate with the systemd service
+// manager.
+type backend interface {
+	// UnitFileState returns the UnitFileState property of
+	// unit (like "enabled", "disabled", "static" or "masked").
+	UnitFileState(ctx context.Context, unit string) (string, error)
+
+	// ActiveState returns the ActiveState property of unit
+	// (like "active", "inactive" or "failed").
+	ActiveState(ctx context.Context, unit string) (string, error)
+
+	// Enable enables unit. Like Disable, Mask and Unmask it
+	// does not reload the service manager. Callers are
+	// expected to call Reload once all unit files have been
+	// changed.
+	Enable(ctx context.Context, unit string) error
+
+	// Disable disables unit.
+	Disable(ctx context.Context, unit string) error
+
+	// Mask masks unit.
+	Mask(ctx context.Context, unit string) error
+
+	// Unmask unmasks unit.
+	Unmask(ctx context.Context, unit string) error
+
+	// Start starts unit and waits for the job to finish.
+	Start(ctx context.Context, unit string) error
+
+	// Stop stops unit and waits for the job to finish.
+	Stop(ctx context.Context, unit string) error
+
+	// Restart restarts unit and waits for the job to finish.
+	Restart(ctx context.Context, unit string) error
+
+	// ReloadOrRestart reloads unit if supported and restarts
+	// it otherwise. It waits for the job to finish.
+	ReloadOrRestart(ctx context.Context, unit string) error
+
+	// Reload reloads the configuration of the service
+	// manager (daemon-reload).
+	Reload(ctx context.Context) error
+}
+
+// Supported backend names.
+const (
+	backendSystemctl = "systemctl"
+	backendDBus      = "dbus"
+)
+
+// JobError is returned by backends if a job did not finish
+// successfully.
+type JobError struct {
+	// Unit is the name of the unit.
+	Unit string
+
+	// Job is the type of the job (like "start" or "stop").
+	Job string
+
+	// Result is the result reported by systemd (like "failed",
+	// "timeout" or "dependency").
+	Result string
+}
+
+func (err *JobError) Error() string {
+	return fmt.Sprintf("%s job for %s finished with result %q", err.Job, err.Unit, err.Result)
+}
+
+// newBackend returns a new backend by name.
+func newBackend(name string, user bool, machine string) (backend, error) {
+	switch name {
+	case backendSystemctl, "":
+		return newSystemctlBackend(user, machine)
+	case backendDBus:
+		return newDBusBackend(user, machine)
+	default:
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+}
+
+// isEnabledState returns true if state (as returned by
+// UnitFileState) is considered enabled. It matches the
+// states for which systemctl is-enabled succeeds.
+func isEnabledState(state string) bool {
+	switch state {
+	case "enabled", "enabled-runtime", "static", "indirect", "generated", "transient", "alias":
+		return true
+	default:
+		return false
+	}
+}
+
+// isActiveState returns true if state (as returned by
+// ActiveState) is considered active.
+func isActiveState(state string) bool {
+	switch state {
+	case "active", "activating", "reloading":
+		return true
+	default:
+		return false
+	}
+}
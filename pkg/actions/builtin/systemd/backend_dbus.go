@@ -0,0 +1,169 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+)
+
+// dbusBackend implements backend by talking to the service
+// manager via its D-Bus API (org.freedesktop.systemd1).
+type dbusBackend struct {
+	conn *dbus.Conn
+}
+
+// dbusConns holds the connections to the system and user
+// service managers. They are shared by all actions of a run
+// and closed by closeDBusConns.
+var (
+	dbusConnsLock sync.Mutex
+	dbusConns     = make(map[bool]*dbus.Conn)
+)
+
+func newDBusBackend(user bool, machine string) (*dbusBackend, error) {
+	if machine != "" {
+		return nil, fmt.Errorf("the %s backend does not support Machine=", backendDBus)
+	}
+
+	dbusConnsLock.Lock()
+	defer dbusConnsLock.Unlock()
+
+	if conn, ok := dbusConns[user]; ok && conn.Connected() {
+		return &dbusBackend{conn: conn}, nil
+	}
+
+	connect := dbus.NewSystemConnectionContext
+	if user {
+		connect = dbus.NewUserConnectionContext
+	}
+
+	conn, err := connect(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to systemd: %w", err)
+	}
+	dbusConns[user] = conn
+
+	return &dbusBackend{conn: conn}, nil
+}
+
+// closeDBusConns closes all connections opened by
+// newDBusBackend.
+func closeDBusConns() {
+	dbusConnsLock.Lock()
+	defer dbusConnsLock.Unlock()
+
+	for key, conn := range dbusConns {
+		conn.Close()
+		delete(dbusConns, key)
+	}
+}
+
+func (b *dbusBackend) UnitFileState(ctx context.Context, unit string) (string, error) {
+	return b.property(ctx, unit, "UnitFileState")
+}
+
+func (b *dbusBackend) ActiveState(ctx context.Context, unit string) (string, error) {
+	return b.property(ctx, unit, "ActiveState")
+}
+
+func (b *dbusBackend) Enable(ctx context.Context, unit string) error {
+	if _, _, err := b.conn.EnableUnitFilesContext(ctx, []string{unit}, false, false); err != nil {
+		return fmt.Errorf("failed to enable %s: %w", unit, err)
+	}
+
+	return nil
+}
+
+func (b *dbusBackend) Disable(ctx context.Context, unit string) error {
+	if _, err := b.conn.DisableUnitFilesContext(ctx, []string{unit}, false); err != nil {
+		return fmt.Errorf("failed to disable %s: %w", unit, err)
+	}
+
+	return nil
+}
+
+func (b *dbusBackend) Mask(ctx context.Context, unit string) error {
+	if _, err := b.conn.MaskUnitFilesContext(ctx, []string{unit}, false, false); err != nil {
+		return fmt.Errorf("failed to mask %s: %w", unit, err)
+	}
+
+	return nil
+}
+
+func (b *dbusBackend) Unmask(ctx context.Context, unit string) error {
+	if _, err := b.conn.UnmaskUnitFilesContext(ctx, []string{unit}, false); err != nil {
+		return fmt.Errorf("failed to unmask %s: %w", unit, err)
+	}
+
+	return nil
+}
+
+func (b *dbusBackend) Start(ctx context.Context, unit string) error {
+	return b.runJob(ctx, "start", unit, b.conn.StartUnitContext)
+}
+
+func (b *dbusBackend) Stop(ctx context.Context, unit string) error {
+	return b.runJob(ctx, "stop", unit, b.conn.StopUnitContext)
+}
+
+func (b *dbusBackend) Restart(ctx context.Context, unit string) error {
+	return b.runJob(ctx, "restart", unit, b.conn.RestartUnitContext)
+}
+
+func (b *dbusBackend) ReloadOrRestart(ctx context.Context, unit string) error {
+	return b.runJob(ctx, "reload-or-restart", unit, b.conn.ReloadOrRestartUnitContext)
+}
+
+func (b *dbusBackend) Reload(ctx context.Context) error {
+	if err := b.conn.ReloadContext(ctx); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	return nil
+}
+
+// jobFunc starts a new systemd job for a unit.
+type jobFunc func(ctx context.Context, name string, mode string, ch chan<- string) (int, error)
+
+// runJob starts a new job using fn and waits for it to
+// complete. If the job does not finish with result "done"
+// a *JobError is returned.
+func (b *dbusBackend) runJob(ctx context.Context, job, unit string, fn jobFunc) error {
+	ch := make(chan string, 1)
+
+	if _, err := fn(ctx, unit, "replace", ch); err != nil {
+		return fmt.Errorf("failed to %s %s: %w", job, unit, err)
+	}
+
+	select {
+	case result := <-ch:
+		if result != "done" {
+			return &JobError{
+				Unit:   unit,
+				Job:    job,
+				Result: result,
+			}
+		}
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// property returns the value of the string property name
+// for unit.
+func (b *dbusBackend) property(ctx context.Context, unit, name string) (string, error) {
+	prop, err := b.conn.GetUnitPropertyContext(ctx, unit, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s of %s: %w", name, unit, err)
+	}
+
+	value, ok := prop.Value.Value().(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected type for %s of %s: %s", name, unit, prop.Value.Signature())
+	}
+
+	return value, nil
+}
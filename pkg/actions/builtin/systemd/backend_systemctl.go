@@ -0,0 +1,110 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// systemctlBackend implements backend by executing the
+// systemctl command.
+type systemctlBackend struct {
+	// scopeArgs holds additional arguments passed to each
+	// invocation of systemctl (like --user or --machine).
+	scopeArgs []string
+}
+
+func newSystemctlBackend(user bool, machine string) (*systemctlBackend, error) {
+	if _, err := exec.LookPath("systemctl"); err != nil {
+		return nil, fmt.Errorf("failed to find systemctl binary")
+	}
+
+	var scopeArgs []string
+	if user {
+		scopeArgs = append(scopeArgs, "--user")
+	}
+	if machine != "" {
+		scopeArgs = append(scopeArgs, "--machine="+machine)
+	}
+
+	return &systemctlBackend{scopeArgs: scopeArgs}, nil
+}
+
+func (b *systemctlBackend) UnitFileState(ctx context.Context, unit string) (string, error) {
+	return b.show(ctx, unit, "UnitFileState")
+}
+
+func (b *systemctlBackend) ActiveState(ctx context.Context, unit string) (string, error) {
+	return b.show(ctx, unit, "ActiveState")
+}
+
+func (b *systemctlBackend) Enable(ctx context.Context, unit string) error {
+	return b.systemctl(ctx, "enable", "--no-reload", unit)
+}
+
+func (b *systemctlBackend) Disable(ctx context.Context, unit string) error {
+	return b.systemctl(ctx, "disable", "--no-reload", unit)
+}
+
+func (b *systemctlBackend) Mask(ctx context.Context, unit string) error {
+	return b.systemctl(ctx, "mask", "--no-reload", unit)
+}
+
+func (b *systemctlBackend) Unmask(ctx context.Context, unit string) error {
+	return b.systemctl(ctx, "unmask", "--no-reload", unit)
+}
+
+func (b *systemctlBackend) Start(ctx context.Context, unit string) error {
+	return b.systemctl(ctx, "start", unit)
+}
+
+func (b *systemctlBackend) Stop(ctx context.Context, unit string) error {
+	return b.systemctl(ctx, "stop", unit)
+}
+
+func (b *systemctlBackend) Restart(ctx context.Context, unit string) error {
+	return b.systemctl(ctx, "restart", unit)
+}
+
+func (b *systemctlBackend) ReloadOrRestart(ctx context.Context, unit string) error {
+	return b.systemctl(ctx, "reload-or-restart", unit)
+}
+
+func (b *systemctlBackend) Reload(ctx context.Context) error {
+	return b.systemctl(ctx, "daemon-reload")
+}
+
+// show returns the value of property for unit.
+func (b *systemctlBackend) show(ctx context.Context, unit, property string) (string, error) {
+	output, err := b.output(ctx, "show", "--property="+property, "--value", unit)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// systemctl executes systemctl with args and returns an
+// errof if it fails.
+func (b *systemctlBackend) systemctl(ctx context.Context, args ...string) error {
+	_, err := b.output(ctx, args...)
+	return err
+}
+
+// output executes systemctl with args and returns the output
+// of the command.
+func (b *systemctlBackend) output(ctx context.Context, args ...string) (string, error) {
+	cmdArgs := make([]string, 0, len(b.scopeArgs)+len(args))
+	cmdArgs = append(cmdArgs, b.scopeArgs...)
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := exec.CommandContext(ctx, "systemctl", cmdArgs...)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("systemctl: %w\n%s", err, string(output))
+	}
+
+	return string(output), nil
+}
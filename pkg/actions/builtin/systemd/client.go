@@ -1,7 +1,10 @@
 package systemd
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -10,12 +13,25 @@ import (
 	"github.com/ppacher/system-deploy/pkg/utils"
 )
 
-// systemctl wraps the systemd systemctl command.
-type systemctl struct {
+// client manages units using a backend and takes care of
+// installing unit files. All unit operations check the
+// current state of the unit first and are only performed
+// if required.
+type client struct {
+	backend
+
 	installDirectory string
+
+	// user is set to true if the client talks to the user
+	// service manager.
+	user bool
+
+	// scope identifies the service manager the client is
+	// talking to.
+	scope string
 }
 
-func newClient(installDirectory string) (*systemctl, error) {
+func newClient(b backend, installDirectory string, user bool, scope string) (*client, error) {
 	if f, err := os.Stat(installDirectory); err != nil || !f.IsDir() {
 		if err == nil {
 			err = fmt.Errorf("not a directory")
@@ -23,84 +39,286 @@ func newClient(installDirectory string) (*systemctl, error) {
 		return nil, fmt.Errorf("invalid installation directory %s: %w", installDirectory, err)
 	}
 
-	if _, err := exec.LookPath("systemctl"); err != nil {
-		return nil, fmt.Errorf("failed to find systemctl binary")
+	cli := &client{
+		backend:          b,
+		installDirectory: installDirectory,
+		user:             user,
+		scope:            scope,
 	}
-
-	cli := &systemctl{installDirectory: installDirectory}
 	return cli, nil
 }
 
 // enable enables all units and returns at the first error
-// encountered. If now is true all units will be started
-// immediately (systemctl enable --now)
-func (cli *systemctl) enable(now bool, units ...string) ([]string, error) {
-	enabled := []string{}
-	args := []string{"enable"}
-	if now {
-		args = append(args, "--now")
+// encountered. If now is true all units that have been
+// enabled will be started immediately (like
+// systemctl enable --now).
+func (cli *client) enable(ctx context.Context, now bool, units ...string) ([]string, error) {
+	enabled, err := cli.forEachUnitFile(ctx, func(unit string) (bool, error) {
+		state, err := cli.UnitFileState(ctx, unit)
+		return !isEnabledState(state), err
+	}, cli.Enable, units...)
+	if err != nil || !now {
+		return enabled, err
+	}
+
+	if err := cli.flushReload(ctx); err != nil {
+		return nil, err
 	}
 
+	if _, err := cli.start(ctx, enabled...); err != nil {
+		return nil, err
+	}
+
+	return enabled, nil
+}
+
+// disable disables all units that are currently enabled.
+func (cli *client) disable(ctx context.Context, units ...string) ([]string, error) {
+	return cli.forEachUnitFile(ctx, func(unit string) (bool, error) {
+		state, err := cli.UnitFileState(ctx, unit)
+		return state == "enabled" || state == "enabled-runtime", err
+	}, cli.Disable, units...)
+}
+
+// mask masks all units that are not yet masked.
+func (cli *client) mask(ctx context.Context, units ...string) ([]string, error) {
+	return cli.forEachUnitFile(ctx, func(unit string) (bool, error) {
+		masked, err := cli.isMasked(ctx, unit)
+		return !masked, err
+	}, cli.Mask, units...)
+}
+
+// unmask unmasks all units that are currently masked.
+func (cli *client) unmask(ctx context.Context, units ...string) ([]string, error) {
+	return cli.forEachUnitFile(ctx, func(unit string) (bool, error) {
+		return cli.isMasked(ctx, unit)
+	}, cli.Unmask, units...)
+}
+
+// start starts all units that are not yet active.
+func (cli *client) start(ctx context.Context, units ...string) ([]string, error) {
+	return cli.forEachUnit(ctx, func(unit string) (bool, error) {
+		active, err := cli.isActive(ctx, unit)
+		return !active, err
+	}, cli.Start, units...)
+}
+
+// stop stops all units that are currently active.
+func (cli *client) stop(ctx context.Context, units ...string) ([]string, error) {
+	return cli.forEachUnit(ctx, func(unit string) (bool, error) {
+		return cli.isActive(ctx, unit)
+	}, cli.Stop, units...)
+}
+
+// restart restarts all units.
+func (cli *client) restart(ctx context.Context, units ...string) ([]string, error) {
+	return cli.forEachUnit(ctx, nil, cli.Restart, units...)
+}
+
+// reloadOrRestart reloads all units if they support it and
+// restarts them otherwise.
+func (cli *client) reloadOrRestart(ctx context.Context, units ...string) ([]string, error) {
+	return cli.forEachUnit(ctx, nil, cli.ReloadOrRestart, units...)
+}
+
+// forEachUnit executes fn for each unit for which needed
+// returns true. If needed is nil, fn is executed for all
+// units. It returns a list of units that have been changed
+// and aborts at the first error encountered.
+func (cli *client) forEachUnit(ctx context.Context, needed func(string) (bool, error), fn func(context.Context, string) error, units ...string) ([]string, error) {
+	changed := []string{}
+
 	for _, unit := range units {
-		if err := cli.systemctl("is-enabled", unit); err == nil {
-			continue
+		if needed != nil {
+			ok, err := needed(unit)
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				continue
+			}
 		}
 
-		if err := cli.systemctl(append(args, unit)...); err != nil {
+		if err := fn(ctx, unit); err != nil {
 			return nil, err
 		}
-		enabled = append(enabled, unit)
+		changed = append(changed, unit)
 	}
 
-	return enabled, nil
+	return changed, nil
+}
+
+// forEachUnitFile is like forEachUnit but for operations
+// that change unit files. Backends do not reload the service
+// manager after such a change so a daemon-reload is scheduled
+// if at least one unit has been changed.
+func (cli *client) forEachUnitFile(ctx context.Context, needed func(string) (bool, error), fn func(context.Context, string) error, units ...string) ([]string, error) {
+	changed, err := cli.forEachUnit(ctx, needed, fn, units...)
+	if len(changed) > 0 {
+		cli.scheduleReload()
+	}
+	return changed, err
+}
+
+// isActive returns true if unit is active.
+func (cli *client) isActive(ctx context.Context, unit string) (bool, error) {
+	state, err := cli.ActiveState(ctx, unit)
+	if err != nil {
+		return false, err
+	}
+	return isActiveState(state), nil
+}
+
+// isMasked returns true if unit is masked.
+func (cli *client) isMasked(ctx context.Context, unit string) (bool, error) {
+	state, err := cli.UnitFileState(ctx, unit)
+	if err != nil {
+		return false, err
+	}
+	return state == "masked" || state == "masked-runtime", nil
+}
+
+// unitFile describes a unit file or drop-in that should be
+// installed.
+type unitFile struct {
+	// name is the path of the file relative to the
+	// installation directory.
+	name string
+
+	// unit is the name of the unit the file belongs to.
+	unit string
+
+	// source is the path of the file to install. If
+	// empty, content is used.
+	source string
+
+	// content holds the content of inline units.
+	content []byte
+}
+
+// isDropIn returns true if f is a unit drop-in.
+func (f unitFile) isDropIn() bool {
+	return f.name != f.unit
 }
 
 // install installs units to the installation directory. Only
 // files that are either missing or have the wrong content
-// are installed.
-func (cli *systemctl) install(unitFiles ...string) ([]string, error) {
-	var filesInstalled []string
+// are installed. If verify is true, all unit files are verified
+// using systemd-analyze before being installed.
+func (cli *client) install(ctx context.Context, verify bool, unitFiles ...unitFile) ([]unitFile, error) {
+	var filesInstalled []unitFile
 	for _, unit := range unitFiles {
-		changed, err := cli.copyUnitFile(unit)
+		target := filepath.Join(cli.installDirectory, unit.name)
+
+		var (
+			update bool
+			err    error
+		)
+		if unit.source != "" {
+			update, err = change.FileUpdateNeeded(unit.source, target)
+		} else {
+			update, err = change.ContentUpdateNeeded(unit.content, target)
+		}
 		if err != nil {
 			return nil, err
 		}
 
-		if changed {
-			filesInstalled = append(filesInstalled, unit)
+		if !update {
+			continue
+		}
+
+		if verify && !unit.isDropIn() {
+			if err := cli.verify(ctx, unit); err != nil {
+				return nil, err
+			}
 		}
+
+		if err := cli.installFile(unit, target); err != nil {
+			return nil, err
+		}
+
+		filesInstalled = append(filesInstalled, unit)
 	}
 	return filesInstalled, nil
 }
 
-// runSystemCtl executes systemctl with args and returns an
-// errof if it fails.
-func (cli *systemctl) systemctl(args ...string) error {
-	cmd := exec.Command("systemctl", args...)
+// installFile writes unit to target.
+func (cli *client) installFile(unit unitFile, target string) error {
+	if unit.isDropIn() {
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+	}
 
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("systemctl: %w\n%s", err, string(output))
+	if unit.source != "" {
+		return utils.CopyAtomicKeepMode(unit.source, target, 0600)
 	}
 
-	return nil
+	mode := os.FileMode(0600)
+	if m, err := utils.FileMode(target); err == nil {
+		mode = m
+	}
+
+	return utils.CreateAtomic(target, mode, bytes.NewReader(unit.content))
 }
 
-// reloadDaemon reloads the systemd deamon via systemctl daemon-reload
-func (cli *systemctl) reloadDaemon() error {
-	return cli.systemctl("daemon-reload")
+// uninstall removes unit from the installation directory. Empty
+// drop-in directories are removed as well.
+func (cli *client) uninstall(unit unitFile) error {
+	target := filepath.Join(cli.installDirectory, unit.name)
+
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if unit.isDropIn() {
+		// os.Remove fails for non-empty directories which
+		// is exactly what we want.
+		_ = os.Remove(filepath.Dir(target))
+	}
+
+	return nil
 }
 
-// copyUnitFile copies file to the unit directory pointed to
-// by cli.installDirectory.
-func (cli *systemctl) copyUnitFile(file string) (bool, error) {
-	targetFileName := filepath.Join(cli.installDirectory, filepath.Base(file))
+// verify verifies unit using systemd-analyze verify. If
+// systemd-analyze is not available verify is a no-op.
+func (cli *client) verify(ctx context.Context, unit unitFile) error {
+	if _, err := exec.LookPath("systemd-analyze"); err != nil {
+		return nil
+	}
+
+	path := unit.source
+	if path == "" || filepath.Base(path) != unit.unit {
+		// systemd-analyze requires the file to be named
+		// like the unit.
+		dir, err := ioutil.TempDir("", "system-deploy-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		path = filepath.Join(dir, unit.unit)
+		if unit.source != "" {
+			err = utils.CopyAtomicMode(unit.source, path, 0600)
+		} else {
+			err = ioutil.WriteFile(path, unit.content, 0600)
+		}
+		if err != nil {
+			return err
+		}
+	}
 
-	if update, err := change.FileUpdateNeeded(file, targetFileName); err != nil || !update {
-		return update, err
+	args := []string{"verify"}
+	if cli.user {
+		args = append(args, "--user")
 	}
+	args = append(args, path)
 
-	if err := utils.CopyAtomicKeepMode(file, targetFileName, 0600); err != nil {
-		return false, err
+	cmd := exec.CommandContext(ctx, "systemd-analyze", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to verify %s: %w\n%s", unit.unit, err, string(output))
 	}
-	return true, nil
+
+	return nil
 }
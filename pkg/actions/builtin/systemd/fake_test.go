@@ -0,0 +1,149 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// fakeUnit holds the state of a unit inside the fake backend.
+type fakeUnit struct {
+	fileState   string
+	activeState string
+}
+
+// fakeBackend is an in-memory implementation of backend.
+type fakeBackend struct {
+	l       sync.Mutex
+	units   map[string]*fakeUnit
+	calls   []string
+	reloads int
+
+	// fail may be set to a unit name for which all jobs
+	// fail.
+	fail string
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		units: make(map[string]*fakeUnit),
+	}
+}
+
+// unit returns the state of name and creates it if
+// required.
+func (f *fakeBackend) unit(name string) *fakeUnit {
+	u, ok := f.units[name]
+	if !ok {
+		u = &fakeUnit{
+			fileState:   "disabled",
+			activeState: "inactive",
+		}
+		f.units[name] = u
+	}
+	return u
+}
+
+func (f *fakeBackend) record(call, unit string) {
+	f.calls = append(f.calls, call+" "+unit)
+}
+
+func (f *fakeBackend) UnitFileState(_ context.Context, unit string) (string, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.unit(unit).fileState, nil
+}
+
+func (f *fakeBackend) ActiveState(_ context.Context, unit string) (string, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.unit(unit).activeState, nil
+}
+
+func (f *fakeBackend) Enable(_ context.Context, unit string) error {
+	return f.setFileState("enable", unit, "enabled")
+}
+
+func (f *fakeBackend) Disable(_ context.Context, unit string) error {
+	return f.setFileState("disable", unit, "disabled")
+}
+
+func (f *fakeBackend) Mask(_ context.Context, unit string) error {
+	return f.setFileState("mask", unit, "masked")
+}
+
+func (f *fakeBackend) Unmask(_ context.Context, unit string) error {
+	return f.setFileState("unmask", unit, "disabled")
+}
+
+func (f *fakeBackend) Start(_ context.Context, unit string) error {
+	return f.job("start", unit, "active")
+}
+
+func (f *fakeBackend) Stop(_ context.Context, unit string) error {
+	return f.job("stop", unit, "inactive")
+}
+
+func (f *fakeBackend) Restart(_ context.Context, unit string) error {
+	return f.job("restart", unit, "active")
+}
+
+func (f *fakeBackend) ReloadOrRestart(_ context.Context, unit string) error {
+	return f.job("reload-or-restart", unit, "active")
+}
+
+func (f *fakeBackend) Reload(_ context.Context) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	f.reloads++
+	f.calls = append(f.calls, "daemon-reload")
+	return nil
+}
+
+func (f *fakeBackend) setFileState(call, unit, state string) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	u := f.unit(unit)
+	if u.fileState == "masked" && call != "unmask" {
+		return fmt.Errorf("unit %s is masked", unit)
+	}
+
+	f.record(call, unit)
+	u.fileState = state
+	return nil
+}
+
+func (f *fakeBackend) job(call, unit, state string) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	f.record(call, unit)
+
+	if f.fail == unit {
+		u := f.unit(unit)
+		u.activeState = "failed"
+		return &JobError{Unit: unit, Job: call, Result: "failed"}
+	}
+
+	if f.unit(unit).fileState == "masked" && state == "active" {
+		return &JobError{Unit: unit, Job: call, Result: "masked"}
+	}
+
+	f.unit(unit).activeState = state
+	return nil
+}
+
+// takeCalls returns all recorded calls and resets the
+// call log.
+func (f *fakeBackend) takeCalls() []string {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	calls := f.calls
+	f.calls = nil
+	return calls
+}
@@ -0,0 +1,155 @@
+package systemd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ppacher/system-deploy/pkg/utils"
+)
+
+// manifest records all files that have been installed
+// into an installation directory by a single task.
+type manifest struct {
+	path  string
+	files []string
+}
+
+// loadManifest loads the manifest from path. If path does
+// not exist an empty manifest is returned.
+func loadManifest(path string) (*manifest, error) {
+	m := &manifest{path: path}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m.files = append(m.files, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+// save writes the manifest to disk.
+func (m *manifest) save() error {
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Managed by system-deploy. Do not edit.\n")
+	for _, file := range m.files {
+		buf.WriteString(file + "\n")
+	}
+
+	return utils.CreateAtomic(m.path, 0644, &buf)
+}
+
+// stale returns all files recorded in m that are not
+// part of current.
+func (m *manifest) stale(current []string) []string {
+	lm := make(map[string]struct{}, len(current))
+	for _, file := range current {
+		lm[file] = struct{}{}
+	}
+
+	var result []string
+	for _, file := range m.files {
+		if _, ok := lm[file]; !ok {
+			result = append(result, file)
+		}
+	}
+
+	return result
+}
+
+// manifestPath returns the path of the manifest for the
+// section at index sectionIdx of the task at taskPath and the
+// installation directory. The manifest is keyed on the full
+// task path and the section index so multiple sections and
+// tasks with the same file name don't share a manifest.
+func manifestPath(stateDirectory, taskPath string, sectionIdx int, installDirectory string) string {
+	dir := strings.Trim(strings.Replace(filepath.Clean(installDirectory), string(filepath.Separator), "-", -1), "-")
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s#%d", taskPath, sectionIdx)))
+	return filepath.Join(stateDirectory, dir, fmt.Sprintf("%s-%x.units", filepath.Base(taskPath), sum[:8]))
+}
+
+// prune disables, stops and removes all units (and drop-ins)
+// that have been installed by a previous run but are not
+// part of the action anymore. It returns the names of all
+// removed files.
+func (a *systemdAction) prune(ctx context.Context) ([]string, error) {
+	m, err := loadManifest(a.manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make([]string, 0, len(a.unitsToInstall)+len(a.dropIns))
+	for _, file := range append(a.unitsToInstall, a.dropIns...) {
+		current = append(current, file.name)
+	}
+	sort.Strings(current)
+
+	stale := m.stale(current)
+	for _, name := range stale {
+		unit := unitFile{
+			name: name,
+			unit: name,
+		}
+		if dir := filepath.Dir(name); dir != "." {
+			unit.unit = strings.TrimSuffix(dir, ".d")
+		}
+
+		if !unit.isDropIn() {
+			if err := a.cli.flushReload(ctx); err != nil {
+				return nil, err
+			}
+
+			if _, err := a.cli.stop(ctx, unit.unit); err != nil {
+				return nil, err
+			}
+
+			if _, err := a.cli.disable(ctx, unit.unit); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := a.cli.uninstall(unit); err != nil {
+			return nil, err
+		}
+
+		a.Infof("pruned %s", name)
+	}
+
+	if len(stale) == 0 && strings.Join(m.files, "\n") == strings.Join(current, "\n") {
+		return nil, nil
+	}
+
+	m.files = current
+	if err := m.save(); err != nil {
+		return nil, fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	return stale, nil
+}
@@ -0,0 +1,59 @@
+package systemd
+
+import (
+	"context"
+	"sync"
+)
+
+// pendingReloads holds all clients for which a daemon-reload is
+// pending. It's keyed by the scope of the client so
+// each service manager is reloaded only once even if multiple
+// tasks installed units.
+var (
+	pendingReloadsLock sync.Mutex
+	pendingReloads     = make(map[string]*client)
+)
+
+// scheduleReload marks the service manager of cli for a
+// daemon-reload.
+func (cli *client) scheduleReload() {
+	pendingReloadsLock.Lock()
+	defer pendingReloadsLock.Unlock()
+
+	pendingReloads[cli.scope] = cli
+}
+
+// flushReload performs a pending daemon-reload for the service
+// manager of cli. It's a no-op if no reload is pending.
+func (cli *client) flushReload(ctx context.Context) error {
+	pendingReloadsLock.Lock()
+	defer pendingReloadsLock.Unlock()
+
+	key := cli.scope
+	if _, ok := pendingReloads[key]; !ok {
+		return nil
+	}
+
+	if err := cli.Reload(ctx); err != nil {
+		return err
+	}
+
+	delete(pendingReloads, key)
+	return nil
+}
+
+// flushAllReloads performs all pending daemon-reloads and returns
+// the first error encountered.
+func flushAllReloads(ctx context.Context) error {
+	pendingReloadsLock.Lock()
+	defer pendingReloadsLock.Unlock()
+
+	for key, cli := range pendingReloads {
+		if err := cli.Reload(ctx); err != nil {
+			return err
+		}
+		delete(pendingReloads, key)
+	}
+
+	return nil
+}
@@ -3,13 +3,21 @@ package systemd
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/ppacher/system-conf/conf"
 	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/change"
 	"github.com/ppacher/system-deploy/pkg/deploy"
 )
 
+// continuationRegex matches line continuations including
+// any whitespace before the backslash.
+var continuationRegex = regexp.MustCompile("[ \t]*\\\\\n")
+
 func init() {
 	actions.MustRegister(actions.Plugin{
 		Name:        "Systemd",
@@ -17,12 +25,53 @@ func init() {
 		Author:      "Patrick Pacher <patrick.pacher@gmail.com>",
 		Website:     "https://github.com/ppacher/system-deploy",
 		Setup:       setupAction,
+		Example:     example,
+		Help: []actions.HelpSection{
+			{
+				Title: "Reloading",
+				Description: "" +
+					"If unit files or drop-ins have been installed, the service manager needs to reload its configuration. " +
+					"The daemon-reload is delayed until a unit is started, stopped or restarted or, at the latest, until all tasks " +
+					"have been executed. This ensures that the daemon is only reloaded once even if multiple tasks install units.",
+			},
+			{
+				Title: "Order of Operations",
+				Description: "" +
+					"Units are first installed and, if Prune= is enabled, stale units are removed. Afterwards, units are unmasked, " +
+					"enabled, disabled, masked, stopped, started, restarted and finally reloaded-or-restarted. Units listed in " +
+					"RestartOnChange= are restarted last. All operations apart from Restart= and ReloadOrRestart= check the current unit state first " +
+					"and are only performed (and reported as an update) if required.",
+			},
+		},
 		Options: []conf.OptionSpec{
 			{
 				Name:        "Install",
 				Description: "Path to a systemd unit file to install.\nMultiple files can be split using a space character. May be specified multiple times.",
 				Type:        conf.StringSliceType,
 			},
+			{
+				Name: "DropIn",
+				Description: "Install a drop-in for a unit. The value must be in the format <unit>:<file> and the file is installed to " +
+					"<unit>.d/ inside InstallDirectory=. The drop-in file name must end in \".conf\". May be specified multiple times.",
+				Type: conf.StringSliceType,
+			},
+			{
+				Name:        "UnitName",
+				Description: "The name of the unit defined in UnitContent=.",
+				Type:        conf.StringType,
+			},
+			{
+				Name: "UnitContent",
+				Description: "The content of an inline unit file that is installed as UnitName=. Use a trailing backslash " +
+					"to continue the content on the next line.",
+				Type: conf.StringType,
+			},
+			{
+				Name:        "Verify",
+				Description: "Whether or not unit files should be verified using systemd-analyze verify before being installed. Verification is skipped if systemd-analyze is not available.",
+				Type:        conf.BoolType,
+				Default:     "yes",
+			},
 			{
 				Name:        "AutoEnable",
 				Description: "Whether or not to automatically enable all installed units.",
@@ -40,23 +89,155 @@ func init() {
 				Description: "A list of systemd units to enable",
 				Type:        conf.StringSliceType,
 			},
+			{
+				Name:        "Disable",
+				Description: "A list of systemd units to disable.",
+				Type:        conf.StringSliceType,
+			},
+			{
+				Name:        "Mask",
+				Description: "A list of systemd units to mask.",
+				Type:        conf.StringSliceType,
+			},
+			{
+				Name:        "Unmask",
+				Description: "A list of systemd units to unmask.",
+				Type:        conf.StringSliceType,
+			},
+			{
+				Name:        "Start",
+				Description: "A list of systemd units to start if they are not yet active.",
+				Type:        conf.StringSliceType,
+			},
+			{
+				Name:        "Stop",
+				Description: "A list of systemd units to stop if they are active.",
+				Type:        conf.StringSliceType,
+			},
+			{
+				Name:        "Restart",
+				Description: "A list of systemd units to restart. Note that units are restarted each time the task is executed. See RestartOnChange= for an alternative.",
+				Type:        conf.StringSliceType,
+			},
+			{
+				Name:        "ReloadOrRestart",
+				Description: "A list of systemd units to reload (or restart if reloading is not supported). Units are reloaded each time the task is executed.",
+				Type:        conf.StringSliceType,
+			},
+			{
+				Name: "RestartOnChange",
+				Description: "A list of systemd units to restart if their unit file has been installed or updated by this action " +
+					"or if any file listed in WatchFiles= changed during this run.",
+				Type: conf.StringSliceType,
+			},
+			{
+				Name: "WatchFiles",
+				Description: "A list of files that are watched for changes. If any of them changes between the preparation and the " +
+					"execution phase (for example because another task updated it) all units from RestartOnChange= are restarted.",
+				Type: conf.StringSliceType,
+			},
+			{
+				Name: "Backend",
+				Description: "The backend used to talk to systemd. Either \"systemctl\" to execute the systemctl command " +
+					"or \"dbus\" to use the D-Bus API of the service manager. Note that Machine= is not supported by the dbus backend.",
+				Type:    conf.StringType,
+				Default: backendSystemctl,
+			},
+			{
+				Name:        "Scope",
+				Description: "Whether to manage the \"system\" or the \"user\" service manager.",
+				Type:        conf.StringType,
+				Default:     "system",
+			},
+			{
+				Name:        "Machine",
+				Description: "Connect to the service manager of the specified container or host (see systemctl --machine). Use \"user@\" together with Scope=user to connect to the user service manager of another user.",
+				Type:        conf.StringType,
+			},
+			{
+				Name: "Prune",
+				Description: "If set, all unit files and drop-ins installed by this task are recorded. Units that are later removed " +
+					"from Install=, UnitName= or DropIn= are stopped, disabled and removed from InstallDirectory=.",
+				Type:    conf.BoolType,
+				Default: "no",
+			},
+			{
+				Name:        "StateDirectory",
+				Description: "The directory used to record installed units if Prune= is enabled.",
+				Type:        conf.StringType,
+				Default:     "/var/lib/system-deploy/systemd",
+			},
 			{
 				Name:        "InstallDirectory",
-				Description: "Path to the systemd unit directoy used to install units.",
+				Description: "Path to the systemd unit directoy used to install units. Defaults to /etc/systemd/system or $XDG_CONFIG_HOME/systemd/user if Scope=user.",
 				Type:        conf.StringType,
-				Default:     "/etc/systemd/system",
 			},
 		},
 	})
 }
 
 func setupAction(task deploy.Task, sec conf.Section) (actions.Action, error) {
-	installUnits := sec.GetStringSlice("Install")
+	var installUnits []unitFile
+	for _, file := range resolvePaths(task, sec.GetStringSlice("Install")) {
+		installUnits = append(installUnits, unitFile{
+			name:   filepath.Base(file),
+			unit:   filepath.Base(file),
+			source: file,
+		})
+	}
+
+	unitName, err := sec.GetString("UnitName")
+	if err != nil && !conf.IsNotSet(err) {
+		return nil, err
+	}
+
+	unitContent, err := sec.GetString("UnitContent")
+	if err != nil && !conf.IsNotSet(err) {
+		return nil, err
+	}
+
+	if (unitName == "") != (unitContent == "") {
+		return nil, fmt.Errorf("options UnitName= and UnitContent= must be specified together")
+	}
+
+	if unitName != "" {
+		if unitName != filepath.Base(unitName) {
+			return nil, fmt.Errorf("invalid value for UnitName: %q", unitName)
+		}
+
+		installUnits = append(installUnits, unitFile{
+			name:    unitName,
+			unit:    unitName,
+			content: []byte(inlineContent(unitContent)),
+		})
+	}
+
+	var dropIns []unitFile
+	for _, value := range sec.GetStringSlice("DropIn") {
+		parts := strings.SplitN(value, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid value for DropIn: %q", value)
+		}
+
+		unit := parts[0]
+		file := resolvePaths(task, []string{parts[1]})[0]
+		if filepath.Ext(file) != ".conf" {
+			return nil, fmt.Errorf("invalid value for DropIn: %q: drop-in files must end in .conf", value)
+		}
+
+		dropIns = append(dropIns, unitFile{
+			name:   filepath.Join(unit+".d", filepath.Base(file)),
+			unit:   unit,
+			source: file,
+		})
+	}
 
-	for idx := range installUnits {
-		if !filepath.IsAbs(installUnits[idx]) {
-			installUnits[idx] = filepath.Clean(filepath.Join(task.Directory, installUnits[idx]))
+	verify, err := sec.GetBool("Verify")
+	if err != nil {
+		if !conf.IsNotSet(err) {
+			return nil, err
 		}
+		verify = true
 	}
 
 	autoEnable, err := sec.GetBool("AutoEnable")
@@ -69,7 +250,40 @@ func setupAction(task deploy.Task, sec conf.Section) (actions.Action, error) {
 		return nil, err
 	}
 
-	enableUnits := sec.GetStringSlice("Enable")
+	scope, err := sec.GetString("Scope")
+	if err != nil {
+		if !conf.IsNotSet(err) {
+			return nil, err
+		}
+		scope = "system"
+	}
+
+	var user bool
+	switch strings.ToLower(scope) {
+	case "system":
+	case "user":
+		user = true
+	default:
+		return nil, fmt.Errorf("invalid value for Scope: %q", scope)
+	}
+
+	machine, err := sec.GetString("Machine")
+	if err != nil && !conf.IsNotSet(err) {
+		return nil, err
+	}
+
+	backendName, err := sec.GetString("Backend")
+	if err != nil {
+		if !conf.IsNotSet(err) {
+			return nil, err
+		}
+		backendName = backendSystemctl
+	}
+
+	backendName = strings.ToLower(backendName)
+	if backendName != backendSystemctl && backendName != backendDBus {
+		return nil, fmt.Errorf("invalid value for Backend: %q", backendName)
+	}
 
 	installDirectory, err := sec.GetString("InstallDirectory")
 	if err != nil {
@@ -78,38 +292,191 @@ func setupAction(task deploy.Task, sec conf.Section) (actions.Action, error) {
 		}
 
 		installDirectory = "/etc/systemd/system"
+		if user {
+			installDirectory, err = userUnitDirectory()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	prune, err := sec.GetBool("Prune")
+	if err != nil && !conf.IsNotSet(err) {
+		return nil, err
+	}
+
+	var stateDirectory, taskPath string
+	if prune {
+		if task.FileName == "" {
+			return nil, fmt.Errorf("option Prune= can only be used inside task files")
+		}
+
+		stateDirectory, err = sec.GetString("StateDirectory")
+		if err != nil {
+			if !conf.IsNotSet(err) {
+				return nil, err
+			}
+			stateDirectory = "/var/lib/system-deploy/systemd"
+		}
+
+		taskPath, err = filepath.Abs(filepath.Join(task.Directory, task.FileName))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	a := &systemdAction{
 		installDirectory:    installDirectory,
-		unitsToEnable:       enableUnits,
+		pruneUnits:          prune,
+		stateDirectory:      stateDirectory,
+		taskPath:            taskPath,
+		sectionIdx:          -1,
+		backendName:         backendName,
+		user:                user,
+		machine:             machine,
+		unitsToEnable:       sec.GetStringSlice("Enable"),
+		unitsToDisable:      sec.GetStringSlice("Disable"),
+		unitsToMask:         sec.GetStringSlice("Mask"),
+		unitsToUnmask:       sec.GetStringSlice("Unmask"),
+		unitsToStart:        sec.GetStringSlice("Start"),
+		unitsToStop:         sec.GetStringSlice("Stop"),
+		unitsToRestart:      sec.GetStringSlice("Restart"),
+		unitsToReload:       sec.GetStringSlice("ReloadOrRestart"),
+		restartOnChange:     sec.GetStringSlice("RestartOnChange"),
+		watchFiles:          resolvePaths(task, sec.GetStringSlice("WatchFiles")),
 		enableNow:           enableNow,
 		autoEnableInstalled: autoEnable,
 		unitsToInstall:      installUnits,
+		dropIns:             dropIns,
+		verify:              verify,
 	}
 	return a, nil
 }
 
+// resolvePaths makes all relative paths relative to the
+// task directory.
+func resolvePaths(task deploy.Task, paths []string) []string {
+	for idx := range paths {
+		if !filepath.IsAbs(paths[idx]) {
+			paths[idx] = filepath.Clean(filepath.Join(task.Directory, paths[idx]))
+		}
+	}
+	return paths
+}
+
+// userUnitDirectory returns the directory for user units.
+func userUnitDirectory() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "systemd", "user"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".config", "systemd", "user"), nil
+}
+
 type systemdAction struct {
 	actions.Base
 
-	unitsToInstall      []string
+	unitsToInstall      []unitFile
+	dropIns             []unitFile
 	unitsToEnable       []string
+	unitsToDisable      []string
+	unitsToMask         []string
+	unitsToUnmask       []string
+	unitsToStart        []string
+	unitsToStop         []string
+	unitsToRestart      []string
+	unitsToReload       []string
+	restartOnChange     []string
+	watchFiles          []string
 	autoEnableInstalled bool
 	enableNow           bool
+	verify              bool
 	installDirectory    string
+	backendName         string
+	user                bool
+	machine             string
 
-	cli *systemctl
+	// pruneUnits is set to true if Prune= is enabled. The
+	// manifest of installed units is stored in stateDirectory
+	// and keyed on taskPath and sectionIdx.
+	pruneUnits     bool
+	stateDirectory string
+	taskPath       string
+	sectionIdx     int
+
+	// manifestPath is set to the path of the manifest file
+	// during preparation if Prune= is enabled.
+	manifestPath string
+
+	// watchSums holds the checksums of all watchFiles
+	// at preparation time.
+	watchSums map[string]string
+
+	cli *client
 }
 
 func (*systemdAction) Name() string { return "Systemd" }
 
 func (a *systemdAction) Prepare(graph actions.ExecGraph) error {
-	cli, err := newClient(a.installDirectory)
+	b, err := newBackend(a.backendName, a.user, a.machine)
+	if err != nil {
+		return err
+	}
+
+	return a.prepare(graph, b)
+}
+
+// SetSectionIndex implements actions.SectionIndexer.
+func (a *systemdAction) SetSectionIndex(idx int) {
+	a.sectionIdx = idx
+}
+
+// prepare prepares the action to use b as the systemd backend.
+func (a *systemdAction) prepare(graph actions.ExecGraph, b backend) error {
+	if a.pruneUnits {
+		if a.sectionIdx < 0 {
+			return fmt.Errorf("option Prune= can only be used inside task files")
+		}
+		a.manifestPath = manifestPath(a.stateDirectory, a.taskPath, a.sectionIdx, a.installDirectory)
+	}
+
+	scope := "system"
+	if a.user {
+		scope = "user"
+	}
+	if a.machine != "" {
+		scope += "@" + a.machine
+	}
+
+	cli, err := newClient(b, a.installDirectory, a.user, scope)
 	if err != nil {
 		return err
 	}
 
+	a.watchSums = make(map[string]string, len(a.watchFiles))
+	for _, file := range a.watchFiles {
+		sum, err := change.FileChecksum(file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		a.watchSums[file] = sum
+	}
+
+	graph.AddPostRun(func(ctx context.Context, _ bool) {
+		if err := flushAllReloads(ctx); err != nil {
+			a.Warnf("failed to reload systemd: %s", err)
+		}
+
+		// all pending reloads have been flushed so the
+		// connections are not needed anymore.
+		closeDBusConns()
+	})
+
 	a.cli = cli
 	return nil
 }
@@ -117,40 +484,150 @@ func (a *systemdAction) Prepare(graph actions.ExecGraph) error {
 func (a *systemdAction) Execute(ctx context.Context) (bool, error) {
 	var changed bool
 
-	if len(a.unitsToInstall) > 0 {
-		installed, err := a.cli.install(a.unitsToInstall...)
+	installed, err := a.cli.install(ctx, a.verify, append(a.unitsToInstall, a.dropIns...)...)
+	if err != nil {
+		return false, fmt.Errorf("failed to install units: %w", err)
+	}
+
+	if len(installed) > 0 {
+		changed = true
+		a.cli.scheduleReload()
+	}
+
+	if a.manifestPath != "" {
+		pruned, err := a.prune(ctx)
 		if err != nil {
-			return false, fmt.Errorf("failed to install units: %w", err)
+			return false, fmt.Errorf("failed to prune units: %w", err)
 		}
 
-		if len(installed) > 0 {
+		if len(pruned) > 0 {
 			changed = true
-			if err := a.cli.reloadDaemon(); err != nil {
-				return false, fmt.Errorf("failed to reload systemd: %w", err)
-			}
+			a.cli.scheduleReload()
 		}
+	}
 
-		if a.autoEnableInstalled {
-			enabled, err := a.cli.enable(a.enableNow, a.unitsToInstall...)
-			if err != nil {
-				return false, fmt.Errorf("failed to enable installed units: %w", err)
-			}
+	unitsToEnable := a.unitsToEnable
+	if a.autoEnableInstalled {
+		unitsToEnable = append(unitNames(a.unitsToInstall), unitsToEnable...)
+	}
+
+	// steps that affect the runtime state of units require
+	// a pending daemon-reload to be performed first.
+	steps := []struct {
+		what    string
+		units   []string
+		fn      func(context.Context, ...string) ([]string, error)
+		runtime bool
+	}{
+		{"unmask", a.unitsToUnmask, a.cli.unmask, false},
+		{"enable", unitsToEnable, a.enable, a.enableNow},
+		{"disable", a.unitsToDisable, a.cli.disable, false},
+		{"mask", a.unitsToMask, a.cli.mask, false},
+		{"stop", a.unitsToStop, a.cli.stop, true},
+		{"start", a.unitsToStart, a.cli.start, true},
+		{"restart", a.unitsToRestart, a.cli.restart, true},
+		{"reload-or-restart", a.unitsToReload, a.cli.reloadOrRestart, true},
+		{"restart", a.changedUnits(installed), a.cli.restart, true},
+	}
+
+	for _, step := range steps {
+		if len(step.units) == 0 {
+			continue
+		}
 
-			if len(enabled) > 0 {
-				changed = true
+		if step.runtime {
+			if err := a.cli.flushReload(ctx); err != nil {
+				return false, fmt.Errorf("failed to reload systemd: %w", err)
 			}
 		}
-	}
 
-	if len(a.unitsToEnable) > 0 {
-		enabled, err := a.cli.enable(a.enableNow, a.unitsToEnable...)
+		done, err := step.fn(ctx, step.units...)
 		if err != nil {
-			return false, fmt.Errorf("failed to enable units: %w", err)
+			return false, fmt.Errorf("failed to %s units: %w", step.what, err)
 		}
-		if len(enabled) > 0 {
+
+		if len(done) > 0 {
+			a.Debugf("%s: %s", step.what, strings.Join(done, ", "))
 			changed = true
 		}
 	}
 
 	return changed, nil
 }
+
+func (a *systemdAction) enable(ctx context.Context, units ...string) ([]string, error) {
+	return a.cli.enable(ctx, a.enableNow, units...)
+}
+
+// changedUnits returns all units from RestartOnChange= that
+// need to be restarted because either their unit file has
+// been installed or one of the watched files changed.
+func (a *systemdAction) changedUnits(installed []unitFile) []string {
+	if len(a.restartOnChange) == 0 {
+		return nil
+	}
+
+	for _, file := range a.watchFiles {
+		sum, err := change.FileChecksum(file)
+		if err != nil && !os.IsNotExist(err) {
+			a.Warnf("failed to check watched file %s: %s", file, err)
+			continue
+		}
+
+		if sum != a.watchSums[file] {
+			a.Debugf("watched file %s changed", file)
+			return a.restartOnChange
+		}
+	}
+
+	var units []string
+	for _, unit := range a.restartOnChange {
+		for _, file := range installed {
+			if file.unit == unit {
+				units = append(units, unit)
+				break
+			}
+		}
+	}
+
+	return units
+}
+
+// unitNames returns the unit names for all unit files.
+func unitNames(files []unitFile) []string {
+	names := make([]string, len(files))
+	for idx, file := range files {
+		names[idx] = file.unit
+	}
+	return names
+}
+
+// inlineContent removes the line continuation characters
+// from inline unit content.
+func inlineContent(value string) string {
+	content := continuationRegex.ReplaceAllString(value, "\n")
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content
+}
+
+const example = `[Task]
+Description= Install and start the backup service
+
+[Systemd]
+Install= ./units/backup.service ./units/backup.timer
+Enable= backup.timer
+Start= backup.timer
+Mask= apt-daily.timer
+RestartOnChange= backup.service
+WatchFiles= /etc/backup/config.yaml
+
+[Systemd]
+UnitName= cleanup.service
+UnitContent= [Service] \
+Type=oneshot \
+ExecStart=/usr/local/bin/cleanup
+DropIn= nginx.service:./units/limits.conf
+RestartOnChange= nginx.service
+`
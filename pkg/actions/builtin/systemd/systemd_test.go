@@ -0,0 +1,368 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/ppacher/system-deploy/pkg/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	taskDir    string
+	installDir string
+	backend    *fakeBackend
+	graph      *runner.Runner
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	taskDir, err := ioutil.TempDir("", "system-deploy-test-")
+	require.NoError(t, err)
+
+	installDir := filepath.Join(taskDir, "install")
+	require.NoError(t, os.Mkdir(installDir, 0755))
+
+	log := actions.NewLogger()
+
+	return &testEnv{
+		taskDir:    taskDir,
+		installDir: installDir,
+		backend:    newFakeBackend(),
+		graph: &runner.Runner{
+			TaskManager: runner.NewTaskManager(log),
+			Hooker:      runner.NewHooker(),
+		},
+	}
+}
+
+func (env *testEnv) cleanup() {
+	os.RemoveAll(env.taskDir)
+}
+
+func (env *testEnv) writeFile(t *testing.T, name, content string) {
+	require.NoError(t, ioutil.WriteFile(filepath.Join(env.taskDir, name), []byte(content), 0644))
+}
+
+// action creates and prepares a new systemd action for opts.
+func (env *testEnv) action(t *testing.T, opts ...conf.Option) *systemdAction {
+	opts = append(opts,
+		conf.Option{Name: "InstallDirectory", Value: env.installDir},
+		conf.Option{Name: "Verify", Value: "no"},
+	)
+
+	task := deploy.Task{
+		FileName:  "test.task",
+		Directory: env.taskDir,
+	}
+
+	act, err := setupAction(task, conf.Section{Name: "Systemd", Options: opts})
+	require.NoError(t, err)
+
+	a := act.(*systemdAction)
+	a.SetLogger(actions.NewLogger())
+	require.NoError(t, a.prepare(env.graph, env.backend))
+
+	return a
+}
+
+func TestExecuteInstallAndStart(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	env.writeFile(t, "backup.service", "[Service]\nExecStart=/bin/true\n")
+
+	opts := []conf.Option{
+		{Name: "Install", Value: "backup.service"},
+		{Name: "AutoEnable", Value: "yes"},
+		{Name: "Start", Value: "backup.service"},
+	}
+
+	changed, err := env.action(t, opts...).Execute(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{
+		"enable backup.service",
+		"daemon-reload",
+		"start backup.service",
+	}, env.backend.takeCalls())
+	assert.FileExists(t, filepath.Join(env.installDir, "backup.service"))
+
+	// a second run must not change anything.
+	changed, err = env.action(t, opts...).Execute(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, env.backend.takeCalls())
+}
+
+func TestExecuteStateChanges(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	env.backend.unit("a.service").activeState = "active"
+	env.backend.unit("a.service").fileState = "enabled"
+	env.backend.unit("b.service").fileState = "masked"
+
+	opts := []conf.Option{
+		{Name: "Stop", Value: "a.service"},
+		{Name: "Disable", Value: "a.service"},
+		{Name: "Mask", Value: "a.service"},
+		{Name: "Unmask", Value: "b.service"},
+		{Name: "Enable", Value: "b.service"},
+	}
+
+	changed, err := env.action(t, opts...).Execute(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{
+		"unmask b.service",
+		"enable b.service",
+		"disable a.service",
+		"mask a.service",
+		"daemon-reload",
+		"stop a.service",
+	}, env.backend.takeCalls())
+
+	changed, err = env.action(t, opts...).Execute(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, env.backend.takeCalls())
+}
+
+func TestExecuteRestartOnChange(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	env.writeFile(t, "app.service", "[Service]\nExecStart=/bin/true\n")
+	env.writeFile(t, "app.conf", "key=value\n")
+
+	opts := []conf.Option{
+		{Name: "Install", Value: "app.service"},
+		{Name: "RestartOnChange", Value: "app.service"},
+		{Name: "WatchFiles", Value: "app.conf"},
+	}
+
+	// the unit file is installed so the unit must be restarted.
+	_, err := env.action(t, opts...).Execute(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"daemon-reload", "restart app.service"}, env.backend.takeCalls())
+
+	// nothing changed
+	changed, err := env.action(t, opts...).Execute(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, env.backend.takeCalls())
+
+	// the watched file is changed between prepare and execute.
+	a := env.action(t, opts...)
+	env.writeFile(t, "app.conf", "key=other-value\n")
+
+	changed, err = a.Execute(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"restart app.service"}, env.backend.takeCalls())
+}
+
+func TestExecuteSingleDaemonReload(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	env.writeFile(t, "a.service", "[Service]\nExecStart=/bin/true\n")
+	env.writeFile(t, "b.service", "[Service]\nExecStart=/bin/true\n")
+
+	first := env.action(t, conf.Option{Name: "Install", Value: "a.service"})
+	second := env.action(t, conf.Option{Name: "Install", Value: "b.service"})
+
+	for _, a := range []*systemdAction{first, second} {
+		changed, err := a.Execute(context.Background())
+		require.NoError(t, err)
+		assert.True(t, changed)
+	}
+	assert.Equal(t, 0, env.backend.reloads)
+
+	env.graph.ExecutePostRun(context.Background(), true)
+	assert.Equal(t, 1, env.backend.reloads)
+}
+
+func TestExecuteUnitFileChangesReloadOnce(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	env.backend.unit("a.service").fileState = "enabled"
+	env.backend.unit("b.service").fileState = "masked"
+
+	changed, err := env.action(t,
+		conf.Option{Name: "Disable", Value: "a.service"},
+		conf.Option{Name: "Mask", Value: "a.service"},
+		conf.Option{Name: "Unmask", Value: "b.service"},
+		conf.Option{Name: "Enable", Value: "b.service c.service"},
+	).Execute(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, 0, env.backend.reloads)
+
+	env.graph.ExecutePostRun(context.Background(), true)
+	assert.Equal(t, 1, env.backend.reloads)
+}
+
+func TestExecuteJobError(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	env.backend.fail = "broken.service"
+
+	_, err := env.action(t, conf.Option{Name: "Start", Value: "broken.service"}).Execute(context.Background())
+	require.Error(t, err)
+
+	var jobErr *JobError
+	require.True(t, errors.As(err, &jobErr))
+	assert.Equal(t, "broken.service", jobErr.Unit)
+	assert.Equal(t, "failed", jobErr.Result)
+}
+
+// pruneTask returns a task in the test directory with one
+// [Systemd] section with Prune= enabled per list of units.
+func (env *testEnv) pruneTask(dir string, units ...[]string) deploy.Task {
+	task := deploy.Task{
+		FileName:  "test.task",
+		Directory: dir,
+	}
+
+	for _, list := range units {
+		sec := conf.Section{Name: "Systemd"}
+		for _, unit := range list {
+			sec.Options = append(sec.Options, conf.Option{Name: "Install", Value: filepath.Join(env.taskDir, unit)})
+		}
+		sec.Options = append(sec.Options,
+			conf.Option{Name: "Prune", Value: "yes"},
+			conf.Option{Name: "StateDirectory", Value: filepath.Join(env.taskDir, "state")},
+			conf.Option{Name: "InstallDirectory", Value: env.installDir},
+			conf.Option{Name: "Verify", Value: "no"},
+		)
+		task.Sections = append(task.Sections, sec)
+	}
+
+	return task
+}
+
+// runTask sets up, prepares and executes all sections of task.
+func (env *testEnv) runTask(t *testing.T, task deploy.Task) {
+	for idx := range task.Sections {
+		act, err := actions.SetupSection(actions.NewLogger(), task, idx)
+		require.NoError(t, err)
+
+		a := act.(*systemdAction)
+		require.NoError(t, a.prepare(env.graph, env.backend))
+
+		_, err = a.Execute(context.Background())
+		require.NoError(t, err)
+	}
+}
+
+func TestPruneRemovedUnit(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	env.writeFile(t, "a.service", "[Service]\nExecStart=/bin/true\n")
+	env.writeFile(t, "b.service", "[Service]\nExecStart=/bin/true\n")
+
+	env.runTask(t, env.pruneTask(env.taskDir, []string{"a.service", "b.service"}))
+	assert.FileExists(t, filepath.Join(env.installDir, "a.service"))
+	assert.FileExists(t, filepath.Join(env.installDir, "b.service"))
+	env.backend.takeCalls()
+	env.backend.units["b.service"] = &fakeUnit{fileState: "enabled", activeState: "active"}
+
+	// b.service has been removed from the task.
+	env.runTask(t, env.pruneTask(env.taskDir, []string{"a.service"}))
+	assert.FileExists(t, filepath.Join(env.installDir, "a.service"))
+	_, err := os.Stat(filepath.Join(env.installDir, "b.service"))
+	assert.True(t, os.IsNotExist(err))
+
+	calls := env.backend.takeCalls()
+	assert.Contains(t, calls, "stop b.service")
+	assert.Contains(t, calls, "disable b.service")
+	assert.NotContains(t, calls, "stop a.service")
+}
+
+func TestPruneMultipleSections(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	env.writeFile(t, "a.service", "[Service]\nExecStart=/bin/true\n")
+	env.writeFile(t, "b.service", "[Service]\nExecStart=/bin/true\n")
+
+	task := env.pruneTask(env.taskDir, []string{"a.service"}, []string{"b.service"})
+
+	// sections of the same task must not prune each other.
+	for i := 0; i < 2; i++ {
+		env.runTask(t, task)
+		assert.FileExists(t, filepath.Join(env.installDir, "a.service"))
+		assert.FileExists(t, filepath.Join(env.installDir, "b.service"))
+
+		for _, call := range env.backend.takeCalls() {
+			assert.NotContains(t, call, "stop")
+			assert.NotContains(t, call, "disable")
+		}
+	}
+
+	// neither must tasks with the same file name in different
+	// directories.
+	other := filepath.Join(env.taskDir, "other")
+	require.NoError(t, os.Mkdir(other, 0755))
+	env.runTask(t, env.pruneTask(other, []string{"a.service"}))
+	assert.FileExists(t, filepath.Join(env.installDir, "b.service"))
+
+	assert.NotEqual(t,
+		manifestPath("/state", "/srv/a/test.task", 0, "/etc/systemd/system"),
+		manifestPath("/state", "/srv/b/test.task", 0, "/etc/systemd/system"),
+	)
+}
+
+func TestPruneIdenticalSections(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	env.writeFile(t, "a.service", "[Service]\nExecStart=/bin/true\n")
+	env.writeFile(t, "b.service", "[Service]\nExecStart=/bin/true\n")
+
+	// both sections install a.service but only the second
+	// one installs b.service in the second run.
+	env.runTask(t, env.pruneTask(env.taskDir, []string{"a.service"}, []string{"a.service"}))
+	env.backend.takeCalls()
+
+	// identical sections must still use their own manifest.
+	stateDir := filepath.Join(env.taskDir, "state")
+	taskPath := filepath.Join(env.taskDir, "test.task")
+	assert.FileExists(t, manifestPath(stateDir, taskPath, 0, env.installDir))
+	assert.FileExists(t, manifestPath(stateDir, taskPath, 1, env.installDir))
+
+	env.runTask(t, env.pruneTask(env.taskDir, []string{"a.service"}, []string{"a.service", "b.service"}))
+	for _, call := range env.backend.takeCalls() {
+		assert.NotContains(t, call, "disable")
+	}
+	assert.FileExists(t, filepath.Join(env.installDir, "b.service"))
+
+	// the manifest of the first section must not contain
+	// b.service.
+	m, err := loadManifest(manifestPath(stateDir, taskPath, 0, env.installDir))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.service"}, m.files)
+}
+
+func TestPruneRequiresSectionIndex(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+
+	task := env.pruneTask(env.taskDir, []string{"a.service"})
+	act, err := actions.Setup("Systemd", actions.NewLogger(), task, task.Sections[0])
+	require.NoError(t, err)
+
+	assert.EqualError(t, act.(*systemdAction).prepare(env.graph, env.backend), "option Prune= can only be used inside task files")
+}
@@ -0,0 +1,82 @@
+package actions
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type changesKey struct{}
+
+// Changes collects details about the changes performed by
+// the actions of a task. Changes are grouped by kind (like
+// "packages") and are made available to post-task hooks
+// like OnChange.
+type Changes struct {
+	l     sync.Mutex
+	items map[string][]string
+}
+
+// WithChanges returns a new context that carries an empty
+// Changes collection. Use RecordChange to add changes and
+// ChangesFromContext to retrieve them.
+func WithChanges(ctx context.Context) context.Context {
+	return context.WithValue(ctx, changesKey{}, &Changes{
+		items: make(map[string][]string),
+	})
+}
+
+// ChangesFromContext returns the Changes collection carried
+// by ctx or nil.
+func ChangesFromContext(ctx context.Context) *Changes {
+	c, _ := ctx.Value(changesKey{}).(*Changes)
+	return c
+}
+
+// RecordChange records items as changes of kind. It's a
+// no-op if ctx does not carry a Changes collection.
+func RecordChange(ctx context.Context, kind string, items ...string) {
+	c := ChangesFromContext(ctx)
+	if c == nil || len(items) == 0 {
+		return
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.items[kind] = append(c.items[kind], items...)
+}
+
+// Get returns all changes recorded for kind.
+func (c *Changes) Get(kind string) []string {
+	if c == nil {
+		return nil
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	return append([]string(nil), c.items[kind]...)
+}
+
+// Env returns all recorded changes as environment variables.
+// Each kind is exported as CHANGED_<KIND> with a space
+// separated list of items.
+func (c *Changes) Env() map[string]string {
+	if c == nil {
+		return nil
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	env := make(map[string]string, len(c.items))
+	for kind, items := range c.items {
+		items = append([]string(nil), items...)
+		sort.Strings(items)
+		env["CHANGED_"+strings.ToUpper(kind)] = strings.Join(items, " ")
+	}
+
+	return env
+}
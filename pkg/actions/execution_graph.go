@@ -77,6 +77,7 @@ type ExecGraph interface {
 	//AddPreRun(fn PreRunFunc)
 
 	// AddPostRun registeres a post-run function that is executed
-	// afterh the execution graph finished.
-	//AddPostRun(fn PostRunFunc)
+	// after the execution graph finished. Post-run functions are
+	// executed in the order they have been registered.
+	AddPostRun(fn PostRunFunc)
 }
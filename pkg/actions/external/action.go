@@ -0,0 +1,108 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+)
+
+func setupAction(path, name string, task deploy.Task, sec conf.Section) actions.Action {
+	req := Request{
+		Task: Task{
+			FileName:    task.FileName,
+			Directory:   task.Directory,
+			Environment: task.Environment,
+		},
+		Options: make(map[string][]string),
+	}
+
+	for _, opt := range sec.Options {
+		req.Options[opt.Name] = append(req.Options[opt.Name], opt.Value)
+	}
+
+	return &action{
+		path:    path,
+		name:    name,
+		request: req,
+	}
+}
+
+type action struct {
+	actions.Base
+
+	path    string
+	name    string
+	request Request
+}
+
+func (a *action) Name() string {
+	return a.name
+}
+
+func (a *action) Prepare(_ actions.ExecGraph) error {
+	_, err := a.call(context.Background(), CommandPrepare)
+	return err
+}
+
+func (a *action) Execute(ctx context.Context) (bool, error) {
+	res, err := a.call(ctx, CommandExecute)
+	if err != nil {
+		return false, err
+	}
+
+	return res.Changed, nil
+}
+
+// call executes command and forwards all log lines of the
+// response.
+func (a *action) call(ctx context.Context, command string) (*Response, error) {
+	input, err := json.Marshal(a.request)
+	if err != nil {
+		return nil, err
+	}
+
+	out, callErr := call(ctx, a.path, command, input)
+
+	res, err := decodeResponse(out)
+	if err != nil {
+		if callErr != nil {
+			return nil, callErr
+		}
+		return nil, fmt.Errorf("%s: invalid %s response: %w", a.name, command, err)
+	}
+
+	for _, line := range res.Logs {
+		a.log(line)
+	}
+
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
+	}
+
+	if callErr != nil {
+		return nil, callErr
+	}
+
+	return &res, nil
+}
+
+func (a *action) log(line LogLine) {
+	if a.Logger == nil {
+		return
+	}
+
+	switch strings.ToLower(line.Level) {
+	case "debug":
+		a.Debugf("%s: %s", a.name, line.Message)
+	case "warn", "warning":
+		a.Warnf("%s: %s", a.name, line.Message)
+	default:
+		a.Infof("%s: %s", a.name, line.Message)
+	}
+}
@@ -0,0 +1,160 @@
+// Package external adds support for action plugins that are
+// implemented as external executables. Plugins are called
+// system-deploy-action-<name> and communicate with
+// system-deploy using JSON over stdin and stdout.
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/sirupsen/logrus"
+)
+
+// Prefix is the file name prefix of plugin executables.
+const Prefix = "system-deploy-action-"
+
+// DefaultDirs holds the default plugin directories.
+var DefaultDirs = []string{
+	"/usr/lib/system-deploy/plugins",
+	"/etc/system-deploy/plugins",
+}
+
+// Load searches dirs for plugin executables and registers
+// them as actions. Directories that do not exist are ignored.
+// Plugins that fail to describe themselves or conflict with
+// already registered actions are skipped with a warning.
+func Load(dirs []string) error {
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+
+		for _, fi := range files {
+			if fi.IsDir() || !strings.HasPrefix(fi.Name(), Prefix) || fi.Mode()&0111 == 0 {
+				continue
+			}
+
+			path := filepath.Join(dir, fi.Name())
+			plg, err := Describe(path)
+			if err != nil {
+				logrus.Warnf("skipping action plugin %s: %s", path, err)
+				continue
+			}
+
+			if err := actions.Register(plg); err != nil {
+				logrus.Warnf("skipping action plugin %s: %s: %s", path, plg.Name, err)
+				continue
+			}
+
+			logrus.Debugf("registered action plugin %s from %s", plg.Name, path)
+		}
+	}
+
+	return nil
+}
+
+// Describe executes the describe command of the plugin at
+// path and returns the action plugin for it.
+func Describe(path string) (actions.Plugin, error) {
+	out, err := call(context.Background(), path, CommandDescribe, nil)
+	if err != nil {
+		if res, decodeErr := decodeResponse(out); decodeErr == nil && res.Error != "" {
+			return actions.Plugin{}, errors.New(res.Error)
+		}
+		return actions.Plugin{}, err
+	}
+
+	var desc Description
+	if err := json.Unmarshal(out, &desc); err != nil {
+		return actions.Plugin{}, fmt.Errorf("invalid describe response: %w", err)
+	}
+
+	if desc.Name == "" {
+		return actions.Plugin{}, fmt.Errorf("plugin did not report a name")
+	}
+
+	plg := actions.Plugin{
+		Name:        desc.Name,
+		Description: desc.Description,
+		Example:     desc.Example,
+		Author:      desc.Author,
+		Website:     desc.Website,
+		Setup: func(task deploy.Task, sec conf.Section) (actions.Action, error) {
+			return setupAction(path, desc.Name, task, sec), nil
+		},
+	}
+
+	for _, h := range desc.Help {
+		plg.Help = append(plg.Help, actions.HelpSection{
+			Title:       h.Title,
+			Description: h.Description,
+		})
+	}
+
+	for _, opt := range desc.Options {
+		typeName := opt.Type
+		if typeName == "" {
+			typeName = "string"
+		}
+
+		optType := conf.TypeFromString(typeName)
+		if optType == nil {
+			return actions.Plugin{}, fmt.Errorf("option %s: unsupported type %q", opt.Name, opt.Type)
+		}
+
+		plg.Options = append(plg.Options, conf.OptionSpec{
+			Name:        opt.Name,
+			Aliases:     opt.Aliases,
+			Description: opt.Description,
+			Type:        *optType,
+			Required:    opt.Required,
+			Default:     opt.Default,
+		})
+	}
+
+	return plg, nil
+}
+
+// call executes the plugin at path with command and writes
+// input to its standard input. It returns the standard output
+// of the plugin even if the plugin failed so the response can
+// be decoded by the caller.
+func call(ctx context.Context, path, command string, input []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, path, command)
+	cmd.Stdin = bytes.NewReader(input)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return stdout.Bytes(), fmt.Errorf("%s %s: %w\n%s", filepath.Base(path), command, err, stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// decodeResponse decodes the response of a plugin. Plugins
+// that exit with a non-zero code may still report the reason
+// of the failure in their response.
+func decodeResponse(out []byte) (Response, error) {
+	var res Response
+	err := json.Unmarshal(out, &res)
+	return res, err
+}
@@ -0,0 +1,174 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPlugin = `#!/bin/sh
+case "$1" in
+describe)
+	cat <<EOT
+{
+	"name": "TestExternal",
+	"description": "An external test action",
+	"options": [
+		{"name": "Path", "required": true},
+		{"name": "Mode", "type": "int", "default": "644"},
+		{"name": "Tags", "type": "[]string"}
+	],
+	"help": [{"title": "Usage", "description": "Just a test"}]
+}
+EOT
+	;;
+prepare)
+	cat > "$(dirname "$0")/prepare.json"
+	echo '{"logs": [{"level": "debug", "message": "prepared"}]}'
+	;;
+execute)
+	cat > "$(dirname "$0")/execute.json"
+	echo '{"changed": true, "logs": [{"message": "executed"}]}'
+	;;
+*)
+	exit 1
+	;;
+esac
+`
+
+const failingPlugin = `#!/bin/sh
+case "$1" in
+describe)
+	echo '{"name": "TestExternalFailing"}'
+	;;
+*)
+	echo '{"error": "something went wrong"}'
+	;;
+esac
+`
+
+const exitingPlugin = `#!/bin/sh
+case "$1" in
+describe)
+	echo '{"name": "TestExternalExiting"}'
+	;;
+*)
+	echo 'some noise' >&2
+	echo '{"error": "disk full"}'
+	exit 2
+	;;
+esac
+`
+
+func writePlugin(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0755))
+	return path
+}
+
+func TestDescribe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writePlugin(t, dir, Prefix+"test", testPlugin)
+
+	plg, err := Describe(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "TestExternal", plg.Name)
+	assert.Equal(t, "An external test action", plg.Description)
+	assert.Equal(t, []actions.HelpSection{{Title: "Usage", Description: "Just a test"}}, plg.Help)
+	require.Len(t, plg.Options, 3)
+	assert.Equal(t, conf.StringType, plg.Options[0].Type)
+	assert.True(t, plg.Options[0].Required)
+	assert.Equal(t, conf.IntType, plg.Options[1].Type)
+	assert.Equal(t, conf.StringSliceType, plg.Options[2].Type)
+
+	invalid := writePlugin(t, dir, Prefix+"invalid", "#!/bin/sh\necho '{\"name\": \"X\", \"options\": [{\"name\": \"A\", \"type\": \"map\"}]}'\n")
+	_, err = Describe(invalid)
+	assert.Error(t, err)
+
+	broken := writePlugin(t, dir, Prefix+"broken", "#!/bin/sh\necho '{\"error\": \"missing dependency\"}'\nexit 1\n")
+	_, err = Describe(broken)
+	assert.EqualError(t, err, "missing dependency")
+}
+
+func TestLoadAndExecute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writePlugin(t, dir, Prefix+"test", testPlugin)
+	writePlugin(t, dir, Prefix+"failing", failingPlugin)
+	writePlugin(t, dir, Prefix+"exiting", exitingPlugin)
+	writePlugin(t, dir, "not-a-plugin", "#!/bin/sh\nexit 1\n")
+
+	require.NoError(t, Load([]string{dir, filepath.Join(dir, "does-not-exist")}))
+
+	_, ok := actions.GetPlugin("TestExternal")
+	require.True(t, ok)
+
+	task := deploy.Task{
+		FileName:    "10-test.task",
+		Directory:   "/etc/deploy",
+		Environment: []string{"FOO=bar"},
+	}
+
+	_, err = actions.Setup("TestExternal", actions.NewLogger(), task, conf.Section{
+		Name: "TestExternal",
+		Options: conf.Options{
+			{Name: "Mode", Value: "600"},
+		},
+	})
+	assert.Error(t, err, "missing required options must be rejected")
+
+	act, err := actions.Setup("TestExternal", actions.NewLogger(), task, conf.Section{
+		Name: "TestExternal",
+		Options: conf.Options{
+			{Name: "Path", Value: "/tmp/file"},
+			{Name: "Tags", Value: "a"},
+			{Name: "Tags", Value: "b"},
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, act.(actions.Preparer).Prepare(nil))
+
+	changed, err := act.(actions.Executor).Execute(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "execute.json"))
+	require.NoError(t, err)
+
+	var req Request
+	require.NoError(t, json.Unmarshal(content, &req))
+	assert.Equal(t, Task{FileName: "10-test.task", Directory: "/etc/deploy", Environment: []string{"FOO=bar"}}, req.Task)
+	assert.Equal(t, []string{"/tmp/file"}, req.Options["Path"])
+	assert.Equal(t, []string{"644"}, req.Options["Mode"])
+	assert.Equal(t, []string{"a", "b"}, req.Options["Tags"])
+
+	act, err = actions.Setup("TestExternalFailing", actions.NewLogger(), task, conf.Section{Name: "TestExternalFailing"})
+	require.NoError(t, err)
+
+	_, err = act.(actions.Executor).Execute(context.Background())
+	assert.EqualError(t, err, "something went wrong")
+
+	// the error reported by a plugin that exits with a
+	// non-zero code is preferred over its stderr.
+	act, err = actions.Setup("TestExternalExiting", actions.NewLogger(), task, conf.Section{Name: "TestExternalExiting"})
+	require.NoError(t, err)
+
+	_, err = act.(actions.Executor).Execute(context.Background())
+	assert.EqualError(t, err, "disk full")
+}
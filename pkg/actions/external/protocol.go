@@ -0,0 +1,95 @@
+package external
+
+// Commands supported by external action plugins. The command
+// is passed as the first argument to the plugin executable.
+const (
+	CommandDescribe = "describe"
+	CommandPrepare  = "prepare"
+	CommandExecute  = "execute"
+)
+
+// Description is returned by a plugin for the describe
+// command.
+type Description struct {
+	// Name is the name of the action and used to find
+	// matching sections.
+	Name string `json:"name"`
+
+	// Description is a short, human readable description
+	// of the action.
+	Description string `json:"description,omitempty"`
+
+	// Options describes all supported options.
+	Options []Option `json:"options,omitempty"`
+
+	// Help may contain additional help sections.
+	Help []HelpSection `json:"help,omitempty"`
+
+	// Example may contain an example task.
+	Example string `json:"example,omitempty"`
+
+	// Author may hold the name of the plugin author.
+	Author string `json:"author,omitempty"`
+
+	// Website may hold the website of the plugin.
+	Website string `json:"website,omitempty"`
+}
+
+// Option describes an option supported by a plugin.
+type Option struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Description string   `json:"description,omitempty"`
+	// Type is one of string, []string, bool, int, []int,
+	// float and []float. Defaults to string.
+	Type     string `json:"type,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	Default  string `json:"default,omitempty"`
+}
+
+// HelpSection is an additional help section.
+type HelpSection struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Request is written to the standard input of a plugin for
+// the prepare and execute commands.
+type Request struct {
+	// Task describes the task the action belongs to.
+	Task Task `json:"task"`
+
+	// Options holds all values of the action section by
+	// option name. Defaults have already been applied.
+	Options map[string][]string `json:"options"`
+}
+
+// Task describes the task of an action.
+type Task struct {
+	FileName    string   `json:"fileName"`
+	Directory   string   `json:"directory"`
+	Environment []string `json:"environment,omitempty"`
+}
+
+// Response is read from the standard output of a plugin for
+// the prepare and execute commands.
+type Response struct {
+	// Changed should be set to true if the action modified
+	// the system. Only used for execute.
+	Changed bool `json:"changed,omitempty"`
+
+	// Error holds an error message if the command failed.
+	Error string `json:"error,omitempty"`
+
+	// Logs may contain log lines that are forwarded to the
+	// logger of system-deploy.
+	Logs []LogLine `json:"logs,omitempty"`
+}
+
+// LogLine is a single log message.
+type LogLine struct {
+	// Level is one of debug, info or warn. Defaults to
+	// info.
+	Level   string `json:"level,omitempty"`
+	Message string `json:"message"`
+}
@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"github.com/ppacher/system-deploy/pkg/secrets"
 	"github.com/sirupsen/logrus"
 )
 
@@ -38,6 +39,33 @@ func NewLogger() Logger {
 	}
 
 	l.SetLevel(logrus.GetLevel())
+	l.AddHook(RedactHook{})
 
 	return l
 }
+
+// RedactHook is a logrus hook that redacts values marked as
+// sensitive from log messages and string fields. See
+// secrets.MarkSensitive for more information.
+type RedactHook struct{}
+
+// Levels implements logrus.Hook.
+func (RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook.
+func (RedactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = secrets.Redact(entry.Message)
+
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = secrets.Redact(v)
+		case error:
+			entry.Data[key] = secrets.Redact(v.Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package actions
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ppacher/system-deploy/pkg/secrets"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactHook(t *testing.T) {
+	secrets.MarkSensitive("logger-secret")
+
+	var buf bytes.Buffer
+	l := NewLogger().(*simpleLogger)
+	l.SetOutput(&buf)
+	l.SetLevel(logrus.InfoLevel)
+
+	l.Infof("connecting with password %s", "logger-secret")
+	l.WithField("pass", "logger-secret").WithError(errors.New("invalid logger-secret")).Warnf("failed")
+
+	assert.NotContains(t, buf.String(), "logger-secret")
+	assert.Contains(t, buf.String(), "connecting with password "+secrets.Redacted)
+}
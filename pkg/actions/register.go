@@ -2,6 +2,7 @@ package actions
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/ppacher/system-conf/conf"
@@ -16,6 +17,10 @@ var ErrNoSetupFunc = errors.New("no setup function defined")
 // (nil) action
 var ErrInvalidAction = errors.New("invalid (nil) action returned")
 
+// ErrConditionsNotSupported is returned when conditions are
+// defined for an action that does not implement Conditional.
+var ErrConditionsNotSupported = errors.New("action does not support conditions")
+
 // Register registers a new action fn under Rname.
 func Register(plg Plugin) error {
 	actionsLock.Lock()
@@ -73,6 +78,27 @@ func ListActions() []string {
 	return names
 }
 
+// SetupSection is like Setup but creates the action for the
+// section at index idx of task. Actions that implement
+// SectionIndexer are passed idx.
+func SetupSection(log Logger, task deploy.Task, idx int) (Action, error) {
+	if idx < 0 || idx >= len(task.Sections) {
+		return nil, fmt.Errorf("invalid section index %d", idx)
+	}
+
+	sec := task.Sections[idx]
+	act, err := Setup(sec.Name, log, task, sec)
+	if err != nil {
+		return nil, err
+	}
+
+	if indexer, ok := act.(SectionIndexer); ok {
+		indexer.SetSectionIndex(idx)
+	}
+
+	return act, nil
+}
+
 // Setup returns the action function for name.
 func Setup(name string, log Logger, task deploy.Task, section conf.Section) (Action, error) {
 	actionsLock.RLock()
@@ -85,6 +111,13 @@ func Setup(name string, log Logger, task deploy.Task, section conf.Section) (Act
 		return nil, errors.New("unknown action")
 	}
 
+	// ConditionXxx= and AssertXxx= are allowed in all sections
+	// and handled here rather than by the plugin.
+	conditions, section := deploy.SplitConditions(section)
+	if err := deploy.CheckConditionPhase(&task, conditions); err != nil {
+		return nil, err
+	}
+
 	// prepare the section by applying defaults and validating all options.
 	prepared, err := conf.Prepare(section, plg.Options)
 	if err != nil {
@@ -100,6 +133,14 @@ func Setup(name string, log Logger, task deploy.Task, section conf.Section) (Act
 		return nil, ErrInvalidAction
 	}
 
+	if len(conditions) > 0 {
+		c, ok := act.(Conditional)
+		if !ok {
+			return nil, ErrConditionsNotSupported
+		}
+		c.SetActionConditions(conditions)
+	}
+
 	act.SetLogger(log)
 
 	return act, nil
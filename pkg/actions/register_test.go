@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/condition"
 	"github.com/ppacher/system-deploy/pkg/deploy"
 	"github.com/stretchr/testify/assert"
 )
@@ -48,3 +49,89 @@ func TestRegisterPlugin(t *testing.T) {
 
 	actions = make(map[string]*Plugin)
 }
+
+type testAction struct {
+	Base
+}
+
+func (*testAction) Name() string { return "test" }
+
+type plainAction struct{}
+
+func (*plainAction) Name() string        { return "plain" }
+func (*plainAction) SetLogger(Logger)    {}
+func (*plainAction) SetTask(deploy.Task) {}
+
+func TestSetupActionConditions(t *testing.T) {
+	defer func() { actions = make(map[string]*Plugin) }()
+
+	cond := condition.New("TestAction", "A condition for testing", condition.Simple(func(value string) (bool, error) {
+		return value == "yes", nil
+	}))
+	assert.NoError(t, deploy.RegisterCondition(cond))
+
+	assert.NoError(t, Register(Plugin{
+		Name: "Conditional",
+		Setup: func(_ deploy.Task, sec conf.Section) (Action, error) {
+			assert.Len(t, sec.Options, 1, "conditions must not be passed to the plugin")
+			return &testAction{}, nil
+		},
+		Options: []conf.OptionSpec{
+			{Name: "Key", Type: conf.StringType},
+		},
+	}))
+
+	assert.NoError(t, Register(Plugin{
+		Name: "Plain",
+		Setup: func(_ deploy.Task, sec conf.Section) (Action, error) {
+			return &plainAction{}, nil
+		},
+	}))
+
+	sec := conf.Section{
+		Name: "Conditional",
+		Options: conf.Options{
+			{Name: "Key", Value: "value"},
+			{Name: "ConditionTestAction", Value: "yes"},
+			{Name: "AssertTestAction", Value: "!no"},
+		},
+	}
+
+	act, err := Setup("conditional", nil, deploy.Task{}, sec)
+	assert.NoError(t, err)
+	if assert.Implements(t, (*Conditional)(nil), act) {
+		instances := act.(Conditional).ActionConditions()
+		assert.Len(t, instances, 2)
+	}
+
+	deferred := condition.New("TestActionDeferred", "A condition that requires the execute phase", condition.Simple(func(value string) (bool, error) {
+		return true, nil
+	}))
+	deferred.RequiresExecutePhase = true
+	assert.NoError(t, deploy.RegisterCondition(deferred))
+
+	deferredSec := conf.Section{
+		Name: "Conditional",
+		Options: conf.Options{
+			{Name: "Key", Value: "value"},
+			{Name: "ConditionTestActionDeferred", Value: "other"},
+		},
+	}
+	_, err = Setup("conditional", nil, deploy.Task{}, deferredSec)
+	assert.Error(t, err, "conditions that require the execute phase must be rejected")
+
+	_, err = Setup("conditional", nil, deploy.Task{ConditionPhase: deploy.PhaseExecute}, deferredSec)
+	assert.NoError(t, err)
+
+	sec.Options = append(sec.Options, conf.Option{Name: "ConditionDoesNotExist", Value: "yes"})
+	_, err = Setup("conditional", nil, deploy.Task{}, sec)
+	assert.Error(t, err)
+
+	_, err = Setup("plain", nil, deploy.Task{}, conf.Section{
+		Name: "Plain",
+		Options: conf.Options{
+			{Name: "ConditionTestAction", Value: "yes"},
+		},
+	})
+	assert.Equal(t, ErrConditionsNotSupported, err)
+}
@@ -1,6 +1,7 @@
 package change
 
 import (
+	"bytes"
 	"encoding/hex"
 	"io"
 	"os"
@@ -17,9 +18,15 @@ func FileChecksum(path string) (string, error) {
 	}
 	defer f.Close()
 
+	return Checksum(f)
+}
+
+// Checksum is like FileChecksum but computes the hash
+// of all data read from r.
+func Checksum(r io.Reader) (string, error) {
 	h := murmur3.New128()
 
-	if _, err := io.Copy(h, f); err != nil {
+	if _, err := io.Copy(h, r); err != nil {
 		return "", err
 	}
 
@@ -46,6 +53,22 @@ func FileUpdateNeeded(ref, target string) (bool, error) {
 	return refSum != targetSum, nil
 }
 
+// ContentUpdateNeeded is like FileUpdateNeeded but
+// compares target against content.
+func ContentUpdateNeeded(content []byte, target string) (bool, error) {
+	targetSum, err := FileChecksum(target)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	refSum, err := Checksum(bytes.NewReader(content))
+	if err != nil {
+		return false, err
+	}
+
+	return refSum != targetSum, nil
+}
+
 // EqualFileMode checks if f1 and f2 have the same
 // mode bits set. If either f1 or f2 does not exist
 // or failed to LStat, an error is returned.
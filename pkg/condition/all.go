@@ -1,10 +1,13 @@
 package condition
 
 import (
+	"context"
 	"os"
 	"os/user"
 	"runtime"
 	"strings"
+
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
 )
 
 // BuiltinConditions is a slice of all built-in conditions.
@@ -12,28 +15,39 @@ var BuiltinConditions = []Condition{
 	{
 		Name:        "OperatingSystem",
 		Description: "Match against the operating system. All values from GOOS are supported.",
-		check: func(value string) (bool, error) {
+		Check: Simple(func(value string) (bool, error) {
 			return strings.EqualFold(runtime.GOOS, value), nil
-		},
+		}),
 	},
 	{
 		Name:        "Architecture",
 		Description: "Match against the architecture system-deploy was compiled for.",
-		check: func(value string) (bool, error) {
+		Check: Simple(func(value string) (bool, error) {
 			return strings.EqualFold(runtime.GOARCH, value), nil
-		},
+		}),
 	},
 	{
 		Name:        "PackageManager",
 		Description: "Match against the installed package-managers.",
-		check: func(value string) (bool, error) {
-			return HasPackageManager(value), nil
-		},
+		Check: Simple(func(value string) (bool, error) {
+			return pkgmgr.IsAvailable(value), nil
+		}),
+	},
+	{
+		Name:        "Distribution",
+		Description: "Match against the ID or ID_LIKE field of /etc/os-release.",
+		Check:       Simple(checkDistribution),
+	},
+	{
+		Name:        "DistributionVersion",
+		Description: "Match against the VERSION_ID or VERSION_CODENAME field of /etc/os-release. Glob patterns like 22.* are supported.",
+		Check:       Simple(checkDistributionVersion),
 	},
 	{
 		Name:        "FileExists",
 		Description: "Test against the existence of a file.",
-		check: func(path string) (bool, error) {
+		Check: func(_ context.Context, task Task, path string) (bool, error) {
+			path = task.Path(path)
 			stat, err := os.Stat(path)
 			if err != nil {
 				if os.IsNotExist(err) {
@@ -48,7 +62,8 @@ var BuiltinConditions = []Condition{
 	{
 		Name:        "DirectoryExists",
 		Description: "Test against the existence of a directory.",
-		check: func(path string) (bool, error) {
+		Check: func(_ context.Context, task Task, path string) (bool, error) {
+			path = task.Path(path)
 			stat, err := os.Stat(path)
 			if err != nil {
 				if os.IsNotExist(err) {
@@ -63,7 +78,7 @@ var BuiltinConditions = []Condition{
 	{
 		Name:        "UserExists",
 		Description: "Test against the existence of a user or userid",
-		check: func(value string) (bool, error) {
+		Check: Simple(func(value string) (bool, error) {
 			_, err := user.Lookup(value)
 			if err == nil {
 				return true, nil
@@ -74,12 +89,12 @@ var BuiltinConditions = []Condition{
 				return true, nil
 			}
 			return false, nil
-		},
+		}),
 	},
 	{
 		Name:        "GroupExists",
 		Description: "Test against the existence of a group or groupid",
-		check: func(value string) (bool, error) {
+		Check: Simple(func(value string) (bool, error) {
 			_, err := user.LookupGroup(value)
 			if err == nil {
 				return true, nil
@@ -90,6 +105,104 @@ var BuiltinConditions = []Condition{
 				return true, nil
 			}
 			return false, nil
-		},
+		}),
+	},
+	{
+		Name:        "Host",
+		Description: "Match against the hostname or the machine ID. Glob patterns are supported for hostnames.",
+		Check:       Simple(checkHost),
+	},
+	{
+		Name:        "Virtualization",
+		Description: "Match against the virtualization environment. Accepts yes, no, vm, container or a specific technology like kvm or docker.",
+		Check:       Simple(checkVirtualization),
+	},
+	{
+		Name:        "Memory",
+		Description: "Compare the physical memory against a size with an optional K, M, G or T suffix. The size may be prefixed with one of <, <=, =, !=, >= or >. Defaults to >=.",
+		Check:       Simple(checkMemory),
+	},
+	{
+		Name:        "CPUs",
+		Description: "Compare the number of usable CPUs. The number may be prefixed with one of <, <=, =, !=, >= or >. Defaults to >=.",
+		Check:       Simple(checkCPUs),
+	},
+	{
+		Name:        "KernelCommandLine",
+		Description: "Test if a kernel command line option is set. If the value contains an equal sign the option must match exactly.",
+		Check:       Simple(checkKernelCommandLine),
+	},
+	{
+		Name:        "FirstBoot",
+		Description: "Test whether the system is booting for the first time. Takes a boolean.",
+		Check:       Simple(checkFirstBoot),
+	},
+	{
+		Name:        "PathIsMountPoint",
+		Description: "Test whether a path is a mount point.",
+		Check:       checkPathIsMountPoint,
+	},
+	{
+		Name:        "KernelVersion",
+		Description: "Compare the release of the running kernel. The version may be prefixed with one of <, <=, =, !=, >= or >. Without an operator the value is matched as a glob pattern.",
+		Check:       Simple(checkKernelVersion),
+	},
+	{
+		Name:        "OSVersion",
+		Description: "Compare the VERSION_ID field of /etc/os-release. Supports the same operators as ConditionKernelVersion=.",
+		Check:       Simple(checkOSVersion),
+	},
+	{
+		Name:        "PackageVersion",
+		Description: "Test if a package is installed by any of the available package managers. The package name may be followed by a version constraint like \"nginx >= 1.20\". Versions are compared using the rules of the package manager.",
+		Check:       checkPackageVersion,
+	},
+	{
+		Name:        "SystemDeployVersion",
+		Description: "Compare the version of system-deploy. Supports the same operators as ConditionKernelVersion=.",
+		Check:       Simple(checkSystemDeployVersion),
+	},
+	{
+		Name:        "FileContains",
+		Description: "Test if a file matches a regular expression. The value is in the format <path>:<regex>.",
+		Check:       checkFileContains,
+	},
+	{
+		Name:        "FileNotEmpty",
+		Description: "Test if a path is a regular file with a size greater than zero.",
+		Check:       checkFileNotEmpty,
+	},
+	{
+		Name:        "PathIsSymlink",
+		Description: "Test if a path is a symbolic link.",
+		Check:       checkPathIsSymlink,
+	},
+	{
+		Name:        "Environment",
+		Description: "Test if the task environment contains KEY=value. If only KEY is specified it's enough for the variable to be set.",
+		Check:       checkEnvironment,
+	},
+	{
+		Name:        "Command",
+		Description: "Execute a command in the task directory and test if it exits with status 0. The command is killed after 30 seconds.",
+		Check:       checkCommand,
+	},
+	{
+		Name:                 "TaskChanged",
+		Description:          "Test if another task of the same run has been executed and reported changes. Requires EvaluateConditions=execute.",
+		Check:                checkTaskChanged,
+		RequiresExecutePhase: true,
+	},
+	{
+		Name:                 "TaskSucceeded",
+		Description:          "Test if another task of the same run has been executed successfully. Requires EvaluateConditions=execute.",
+		Check:                checkTaskSucceeded,
+		RequiresExecutePhase: true,
+	},
+	{
+		Name:                 "TaskFailed",
+		Description:          "Test if another task of the same run has been executed and failed. Requires EvaluateConditions=execute and the --keep-going flag.",
+		Check:                checkTaskFailed,
+		RequiresExecutePhase: true,
 	},
 }
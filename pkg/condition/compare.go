@@ -0,0 +1,99 @@
+package condition
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// operators holds all supported comparison operators. Longer
+// operators must come first.
+var operators = []string{"<=", ">=", "!=", "<", ">", "="}
+
+// parseComparison splits value into a comparison operator and
+// the operand. If value does not start with an operator def
+// is returned as the operator.
+func parseComparison(value, def string) (string, string) {
+	value = strings.TrimSpace(value)
+	for _, op := range operators {
+		if strings.HasPrefix(value, op) {
+			return op, strings.TrimSpace(value[len(op):])
+		}
+	}
+	return def, value
+}
+
+// compareResult returns whether the result of a three-way
+// comparison (-1, 0, 1) satisfies op.
+func compareResult(op string, cmp int) (bool, error) {
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case "=":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return false, fmt.Errorf("unsupported operator %q", op)
+	}
+}
+
+// compareInt compares a and b.
+func compareInt(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// sizeSuffixes maps size suffixes to their multiplier.
+var sizeSuffixes = map[byte]uint64{
+	'K': 1 << 10,
+	'M': 1 << 20,
+	'G': 1 << 30,
+	'T': 1 << 40,
+}
+
+// parseSize parses a size in bytes with an optional K, M, G
+// or T suffix (base 1024).
+func parseSize(value string) (uint64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+
+	multiplier := uint64(1)
+	if m, ok := sizeSuffixes[strings.ToUpper(value[len(value)-1:])[0]]; ok {
+		multiplier = m
+		value = value[:len(value)-1]
+	}
+
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+
+	return n * multiplier, nil
+}
+
+// parseBool parses a boolean value like systemd does.
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "yes", "y", "true", "t", "on":
+		return true, nil
+	case "0", "no", "n", "false", "f", "off":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid boolean value %q", value)
+	}
+}
@@ -1,22 +1,112 @@
 package condition
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 )
 
-// Condition describes a generic condtion.
+// ErrNoCheckFunc is returned when a condition without a
+// Check function is registered.
+var ErrNoCheckFunc = errors.New("no check function defined")
+
+// Task describes the task a condition is evaluated for.
+type Task struct {
+	// FileName is the name of the file that describes the
+	// task.
+	FileName string
+
+	// Directory holds the directory of the task.
+	Directory string
+
+	// Environment holds the environment of the task in the
+	// form "KEY=value".
+	Environment []string
+}
+
+// Path returns p resolved relative to the task directory.
+// Absolute paths are returned unchanged.
+func (t Task) Path(p string) string {
+	if filepath.IsAbs(p) || t.Directory == "" {
+		return p
+	}
+	return filepath.Join(t.Directory, p)
+}
+
+// Getenv returns the value of key from the task's
+// environment. If key is set multiple times the last value
+// wins.
+func (t Task) Getenv(key string) (string, bool) {
+	prefix := key + "="
+	for i := len(t.Environment) - 1; i >= 0; i-- {
+		if strings.HasPrefix(t.Environment[i], prefix) {
+			return t.Environment[i][len(prefix):], true
+		}
+	}
+	return "", false
+}
+
+// CheckFunc checks a single value of a condition and returns
+// the evaluation result.
+type CheckFunc func(ctx context.Context, task Task, value string) (bool, error)
+
+// HelpSection is an additional help section of a condition.
+type HelpSection struct {
+	Title       string
+	Description string
+}
+
+// Condition describes a generic condtion. Conditions are
+// added to the task meta section as ConditionXxx= and
+// AssertXxx= by deploy.RegisterCondition.
 type Condition struct {
 	// Name is the name of the condition.
 	Name string
 
 	// Description holds a human readable description of
-	// the condition.
+	// the condition. Description should be a single short
+	// line. Use Help for more detailed documentation.
 	Description string
 
-	// check the condition and return the evaluation
-	// result.
-	check func(value string) (bool, error) //nolint:structcheck // it's not unused ...
+	// Check checks a single value of the condition.
+	Check CheckFunc `json:"-"`
+
+	// RequiresExecutePhase may be set to true if the
+	// condition depends on the results of other tasks and
+	// can only be used with EvaluateConditions=execute.
+	RequiresExecutePhase bool
+
+	// Help may contain additional help sections.
+	Help []HelpSection
+
+	// Example may contain an example task.
+	Example string
+
+	// Author may hold the name of the condition author.
+	Author string
+
+	// Website may hold the name of the condition website.
+	Website string
+}
+
+// New returns a new condition called name that uses check to
+// evaluate values.
+func New(name, description string, check CheckFunc) Condition {
+	return Condition{
+		Name:        name,
+		Description: description,
+		Check:       check,
+	}
+}
+
+// Simple is a convenience wrapper for CheckFuncs that neither
+// require the context nor the task.
+func Simple(fn func(value string) (bool, error)) CheckFunc {
+	return func(_ context.Context, _ Task, value string) (bool, error) {
+		return fn(value)
+	}
 }
 
 // Instance is a specific instance of a condition that
@@ -35,35 +125,152 @@ type Instance struct {
 	Values []string
 }
 
-// Run checks c against all values. The results of checking
-// each value is ANDed. If a value is prefixed with an
-// exclamation mark the check is negated. Run aborts on the
-// first values that evalutes to false.
-func (instance *Instance) Run() error {
-	for idx, v := range instance.Values {
-		negate := false
-		if strings.HasPrefix(v, "!") {
-			negate = true
-			v = v[1:]
-		} else if strings.HasPrefix(v, "\\!") {
-			// the exclamation mark is part of the
-			// value and has been escaped.
-			v = v[1:]
+// Result holds the result of evaluating all values of an
+// instance.
+type Result struct {
+	// Failed holds the first non-triggering value that
+	// evaluated to false. It's empty if all of them passed.
+	Failed string
+
+	// Triggers holds the number of triggering values.
+	Triggers int
+
+	// Triggered is true if at least one triggering value
+	// evaluated to true.
+	Triggered bool
+}
+
+// Err returns an error if r describes a failed evaluation.
+func (r Result) Err() error {
+	if r.Failed != "" {
+		return fmt.Errorf("%q failed", r.Failed)
+	}
+
+	if r.Triggers > 0 && !r.Triggered {
+		return ErrNotTriggered
+	}
+
+	return nil
+}
+
+// ErrNotTriggered is returned if none of the triggering
+// values of a set of conditions evaluated to true.
+var ErrNotTriggered = errors.New("none of the triggering conditions matched")
+
+// parseValue parses the prefixes of a condition value. A
+// leading pipe marks the value as triggering and a following
+// exclamation mark negates it. Both characters can be escaped
+// using a backslash.
+func parseValue(v string) (value string, trigger bool, negate bool) {
+	if strings.HasPrefix(v, "|") {
+		trigger = true
+		v = v[1:]
+	}
+
+	if strings.HasPrefix(v, "!") {
+		negate = true
+		v = v[1:]
+	}
+
+	if strings.HasPrefix(v, "\\!") || strings.HasPrefix(v, "\\|") {
+		// the first character is part of the value and
+		// has been escaped.
+		v = v[1:]
+	}
+
+	return v, trigger, negate
+}
+
+// Evaluate checks c against all values for task and returns
+// the result. Non-triggering values are ANDed while values
+// prefixed with a pipe are triggering and ORed together.
+// If a value is prefixed with an exclamation mark (after the
+// pipe, if any) the check is negated. An error is only
+// returned if a check itself failed.
+func (instance *Instance) Evaluate(ctx context.Context, task Task) (Result, error) {
+	var res Result
+
+	for idx, raw := range instance.Values {
+		v, trigger, negate := parseValue(raw)
+
+		if trigger {
+			res.Triggers++
+			if res.Triggered {
+				// at least one triggering value already
+				// matched.
+				continue
+			}
 		}
 
-		result, err := instance.check(v)
+		result, err := instance.Check(ctx, task, v)
 		if err != nil {
-			return fmt.Errorf("%q: %w", instance.Values[idx], err)
+			return res, fmt.Errorf("%q: %w", instance.Values[idx], err)
 		}
 
-		switch {
-		case negate && !result,
-			!negate && result:
-			return nil
-		default:
-			return fmt.Errorf("%q failed", instance.Values[idx])
+		if negate {
+			result = !result
+		}
+
+		if trigger {
+			res.Triggered = res.Triggered || result
+			continue
+		}
+
+		if !result {
+			res.Failed = raw
+			return res, nil
 		}
 	}
 
-	return nil
+	return res, nil
+}
+
+// Run checks c against all values for task and returns an error if
+// the instance does not pass. See Evaluate for the rules
+// applied to the values.
+func (instance *Instance) Run(ctx context.Context, task Task) error {
+	res, err := instance.Evaluate(ctx, task)
+	if err != nil {
+		return err
+	}
+
+	return res.Err()
+}
+
+// Check evaluates all instances for task following the semantics of
+// systemd: all non-triggering values must pass and, if there
+// are triggering values in any of the instances, at least one
+// of them must pass. Check returns the instance that caused
+// the evaluation to fail along with an error.
+func Check(ctx context.Context, task Task, instances []Instance) (*Instance, error) {
+	var (
+		triggers  int
+		triggered bool
+		last      *Instance
+	)
+
+	for idx := range instances {
+		instance := &instances[idx]
+
+		res, err := instance.Evaluate(ctx, task)
+		if err != nil {
+			return instance, err
+		}
+
+		if res.Failed != "" {
+			return instance, res.Err()
+		}
+
+		if res.Triggers > 0 {
+			last = instance
+			triggers += res.Triggers
+			triggered = triggered || res.Triggered
+		}
+	}
+
+	if triggers > 0 && !triggered {
+		return last, ErrNotTriggered
+	}
+
+	return nil, nil
 }
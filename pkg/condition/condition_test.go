@@ -0,0 +1,146 @@
+package condition
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCondition passes for "true", fails for everything
+// else and returns an error for "error".
+var testCondition = &Condition{
+	Name: "Test",
+	Check: Simple(func(value string) (bool, error) {
+		if value == "error" {
+			return false, errors.New("check failed")
+		}
+		return value == "true" || value == "!escaped" || value == "|escaped", nil
+	}),
+}
+
+func TestInstanceRun(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name   string
+		values []string
+		pass   bool
+		err    bool
+	}{
+		{"no values", nil, true, false},
+		{"single true", []string{"true"}, true, false},
+		{"single false", []string{"false"}, false, false},
+		{"all values are ANDed", []string{"true", "false"}, false, false},
+		{"all values are checked", []string{"true", "true", "false"}, false, false},
+		{"ANDed values pass", []string{"true", "true"}, true, false},
+		{"negation", []string{"!false"}, true, false},
+		{"negation fails", []string{"!true"}, false, false},
+		{"negation is ANDed", []string{"!false", "false"}, false, false},
+		{"escaped negation", []string{"\\!escaped"}, true, false},
+		{"triggering values are ORed", []string{"|false", "|true"}, true, false},
+		{"no triggering value matches", []string{"|false", "|false"}, false, false},
+		{"negated triggering value", []string{"|!false", "|false"}, true, false},
+		{"negated triggering value fails", []string{"|!true"}, false, false},
+		{"escaped trigger", []string{"\\|escaped"}, true, false},
+		{"triggers and mandatory values", []string{"|true", "false"}, false, false},
+		{"mandatory values and triggers", []string{"true", "|false", "|true"}, true, false},
+		{"mandatory pass but no trigger", []string{"true", "|false"}, false, false},
+		{"check error", []string{"true", "error"}, false, true},
+		{"error after trigger matched is skipped", []string{"|true", "|error"}, true, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			instance := &Instance{
+				Condition: testCondition,
+				Values:    c.values,
+			}
+
+			err := instance.Run(ctx, Task{})
+			if c.pass {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.Error(t, err)
+
+			res, evalErr := instance.Evaluate(ctx, Task{})
+			if c.err {
+				assert.Error(t, evalErr)
+			} else {
+				assert.NoError(t, evalErr)
+				assert.Error(t, res.Err())
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+
+	instance := func(values ...string) Instance {
+		return Instance{
+			Condition: testCondition,
+			Values:    values,
+		}
+	}
+
+	cases := []struct {
+		name      string
+		instances []Instance
+		failed    int
+		err       error
+	}{
+		{"empty", nil, -1, nil},
+		{"all pass", []Instance{instance("true"), instance("!false")}, -1, nil},
+		{"second fails", []Instance{instance("true"), instance("false")}, 1, nil},
+		{"triggers across instances", []Instance{instance("|false"), instance("|true")}, -1, nil},
+		{"no trigger across instances", []Instance{instance("|false"), instance("true", "|false")}, 1, ErrNotTriggered},
+		{"mandatory fails with trigger", []Instance{instance("|true"), instance("false")}, 1, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			failed, err := Check(ctx, Task{}, c.instances)
+			if c.failed < 0 {
+				assert.NoError(t, err)
+				assert.Nil(t, failed)
+				return
+			}
+
+			assert.Error(t, err)
+			if c.err != nil {
+				assert.True(t, errors.Is(err, c.err))
+			}
+			assert.Same(t, &c.instances[c.failed], failed)
+		})
+	}
+}
+
+func TestCustomCondition(t *testing.T) {
+	var received Task
+	cond := New("Custom", "A custom condition", func(_ context.Context, task Task, value string) (bool, error) {
+		received = task
+		v, ok := task.Getenv(value)
+		return ok && v == "yes", nil
+	})
+
+	task := Task{
+		FileName:    "/etc/deploy/10-test.task",
+		Directory:   "/etc/deploy",
+		Environment: []string{"ENABLED=yes", "DISABLED=no"},
+	}
+
+	instance := &Instance{
+		Condition: &cond,
+		Values:    []string{"ENABLED", "!DISABLED"},
+	}
+
+	assert.NoError(t, instance.Run(context.Background(), task))
+	assert.Equal(t, task, received)
+
+	assert.Equal(t, "/etc/deploy/file", task.Path("file"))
+	assert.Equal(t, "/file", task.Path("/file"))
+}
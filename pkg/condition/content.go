@@ -0,0 +1,116 @@
+package condition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ppacher/system-deploy/pkg/utils"
+)
+
+// commandTimeout is the maximum time a command executed by
+// ConditionCommand= may run.
+var commandTimeout = 30 * time.Second
+
+// checkFileContains returns true if the file at the path
+// before the first colon of value matches the regular
+// expression after it.
+func checkFileContains(_ context.Context, task Task, value string) (bool, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return false, fmt.Errorf("invalid value %q, expected <path>:<regex>", value)
+	}
+
+	re, err := regexp.Compile(parts[1])
+	if err != nil {
+		return false, fmt.Errorf("invalid regular expression: %w", err)
+	}
+
+	content, err := ioutil.ReadFile(task.Path(parts[0]))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return re.Match(content), nil
+}
+
+// checkFileNotEmpty returns true if path is a regular file
+// with a size greater than zero.
+func checkFileNotEmpty(_ context.Context, task Task, path string) (bool, error) {
+	stat, err := os.Stat(task.Path(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return stat.Mode().IsRegular() && stat.Size() > 0, nil
+}
+
+// checkPathIsSymlink returns true if path is a symbolic
+// link.
+func checkPathIsSymlink(_ context.Context, task Task, path string) (bool, error) {
+	stat, err := os.Lstat(task.Path(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return stat.Mode()&os.ModeSymlink != 0, nil
+}
+
+// checkEnvironment returns true if the task's environment
+// contains the assignment in value. If value does not contain
+// an equal sign it's enough for the variable to be set.
+func checkEnvironment(_ context.Context, task Task, value string) (bool, error) {
+	parts := strings.SplitN(value, "=", 2)
+
+	actual, ok := task.Getenv(parts[0])
+	if !ok || len(parts) == 1 {
+		return ok, nil
+	}
+
+	return actual == parts[1], nil
+}
+
+// checkCommand executes value and returns true if it exits
+// with status 0. The command is executed in the task
+// directory and with the task's environment.
+func checkCommand(ctx context.Context, task Task, value string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	env := make(map[string]string, len(task.Environment))
+	for _, e := range task.Environment {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+
+	err := utils.ExecCommand(ctx, task.Directory, value, &utils.ExecOptions{Env: env})
+	if ctx.Err() == context.DeadlineExceeded {
+		return false, fmt.Errorf("command %q timed out after %s", value, commandTimeout)
+	}
+
+	if err != nil {
+		var exitErr *utils.ExitCodeError
+		if errors.As(err, &exitErr) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,109 @@
+package condition
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileConditions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "condition-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "config"), []byte("listen 80;\nserver_name example.com;\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "empty"), nil, 0644))
+	require.NoError(t, os.Symlink("config", filepath.Join(dir, "link")))
+	require.NoError(t, os.Symlink("missing", filepath.Join(dir, "dangling")))
+
+	task := Task{Directory: dir}
+	ctx := context.Background()
+
+	cases := []struct {
+		check CheckFunc
+		value string
+		pass  bool
+	}{
+		{checkFileContains, "config:listen 80", true},
+		{checkFileContains, "config:(?m)^server_name .*\\.com;$", true},
+		{checkFileContains, filepath.Join(dir, "config") + ":listen 443", false},
+		{checkFileContains, "missing:listen", false},
+		{checkFileContains, "link:listen", true},
+		{checkFileNotEmpty, "config", true},
+		{checkFileNotEmpty, "empty", false},
+		{checkFileNotEmpty, "missing", false},
+		{checkFileNotEmpty, ".", false},
+		{checkFileNotEmpty, "link", true},
+		{checkPathIsSymlink, "link", true},
+		{checkPathIsSymlink, "dangling", true},
+		{checkPathIsSymlink, "config", false},
+		{checkPathIsSymlink, "missing", false},
+	}
+
+	for _, c := range cases {
+		res, err := c.check(ctx, task, c.value)
+		assert.NoError(t, err, c.value)
+		assert.Equal(t, c.pass, res, c.value)
+	}
+
+	for _, value := range []string{"config", ":listen", "config:("} {
+		_, err := checkFileContains(ctx, task, value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestCheckEnvironment(t *testing.T) {
+	task := Task{
+		Environment: []string{"ROLE=db", "EMPTY=", "ROLE=web"},
+	}
+
+	cases := map[string]bool{
+		"ROLE":     true,
+		"ROLE=web": true,
+		"ROLE=db":  false,
+		"EMPTY":    true,
+		"EMPTY=":   true,
+		"MISSING":  false,
+		"MISSING=": false,
+	}
+
+	for value, expected := range cases {
+		res, err := checkEnvironment(context.Background(), task, value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, res, value)
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+	ctx := context.Background()
+	task := Task{
+		Environment: []string{"ROLE=web"},
+	}
+
+	res, err := checkCommand(ctx, task, "true")
+	assert.NoError(t, err)
+	assert.True(t, res)
+
+	res, err = checkCommand(ctx, task, "false")
+	assert.NoError(t, err)
+	assert.False(t, res)
+
+	res, err = checkCommand(ctx, task, `sh -c 'test "$ROLE" = web'`)
+	assert.NoError(t, err)
+	assert.True(t, res)
+
+	_, err = checkCommand(ctx, task, "/does/not/exist")
+	assert.Error(t, err)
+
+	defer func(d time.Duration) { commandTimeout = d }(commandTimeout)
+	commandTimeout = 10 * time.Millisecond
+
+	_, err = checkCommand(ctx, task, "sleep 5")
+	assert.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package condition
+
+import (
+	"os"
+	"path"
+
+	"github.com/ppacher/system-deploy/pkg/utils/osrelease"
+)
+
+// readOSRelease reads the os-release file. It returns nil
+// if the system does not have one.
+func readOSRelease() (*osrelease.Info, error) {
+	info, err := osrelease.Read()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return info, nil
+}
+
+// checkDistribution returns true if value matches the ID or
+// one of the ID_LIKE values of the os-release file.
+func checkDistribution(value string) (bool, error) {
+	info, err := readOSRelease()
+	if err != nil || info == nil {
+		return false, err
+	}
+
+	return info.Is(value), nil
+}
+
+// checkDistributionVersion returns true if the glob pattern
+// value matches either VERSION_ID or VERSION_CODENAME of the
+// os-release file.
+func checkDistributionVersion(value string) (bool, error) {
+	info, err := readOSRelease()
+	if err != nil || info == nil {
+		return false, err
+	}
+
+	for _, v := range []string{info.VersionID, info.VersionCodename} {
+		if v == "" {
+			continue
+		}
+
+		matched, err := path.Match(value, v)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,205 @@
+package condition
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/ppacher/system-deploy/pkg/utils/virt"
+)
+
+// Paths inspected by the host conditions.
+var (
+	machineIDPath = "/etc/machine-id"
+	firstBootPath = "/run/systemd/first-boot"
+	procCmdline   = "/proc/cmdline"
+	procMeminfo   = "/proc/meminfo"
+	procMountinfo = "/proc/self/mountinfo"
+)
+
+// machineIDRegex matches a machine ID as defined in
+// machine-id(5).
+var machineIDRegex = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+// checkHost returns true if value matches the machine ID or
+// the hostname. Glob patterns are supported for host names.
+func checkHost(value string) (bool, error) {
+	if machineIDRegex.MatchString(value) {
+		id, err := ioutil.ReadFile(machineIDPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		return strings.EqualFold(strings.TrimSpace(string(id)), value), nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return false, err
+	}
+
+	return path.Match(strings.ToLower(value), strings.ToLower(hostname))
+}
+
+// checkVirtualization returns true if value matches the
+// detected virtualization environment.
+func checkVirtualization(value string) (bool, error) {
+	return virt.Detect().Matches(value), nil
+}
+
+// checkMemory compares the physical memory against value.
+func checkMemory(value string) (bool, error) {
+	op, operand := parseComparison(value, ">=")
+
+	expected, err := parseSize(operand)
+	if err != nil {
+		return false, err
+	}
+
+	total, err := memTotal()
+	if err != nil {
+		return false, err
+	}
+
+	return compareResult(op, compareInt(total, expected))
+}
+
+// memTotal returns the total amount of physical memory in
+// bytes.
+func memTotal() (uint64, error) {
+	f, err := os.Open(procMeminfo)
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine memory size: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid MemTotal: %w", err)
+		}
+		return kb * 1024, nil
+	}
+
+	return 0, fmt.Errorf("MemTotal not found in %s", procMeminfo)
+}
+
+// checkCPUs compares the number of usable CPUs against value.
+func checkCPUs(value string) (bool, error) {
+	op, operand := parseComparison(value, ">=")
+
+	expected, err := strconv.ParseUint(operand, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid number of CPUs %q", operand)
+	}
+
+	return compareResult(op, compareInt(uint64(runtime.NumCPU()), expected))
+}
+
+// checkKernelCommandLine returns true if value is part of the
+// kernel command line.
+func checkKernelCommandLine(value string) (bool, error) {
+	cmdline, err := ioutil.ReadFile(procCmdline)
+	if err != nil {
+		return false, err
+	}
+
+	return matchCommandLine(string(cmdline), value), nil
+}
+
+// matchCommandLine returns true if value matches one of the
+// words of cmdline. If value contains an equal sign it must
+// match a word exactly. Otherwise it matches words that are
+// either equal to value or assign a value to it.
+func matchCommandLine(cmdline, value string) bool {
+	for _, word := range strings.Fields(cmdline) {
+		if word == value {
+			return true
+		}
+
+		if !strings.Contains(value, "=") && strings.HasPrefix(word, value+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFirstBoot returns true if the system is booted for
+// the first time and value is true or vice versa.
+func checkFirstBoot(value string) (bool, error) {
+	expected, err := parseBool(value)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(firstBootPath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	return (err == nil) == expected, nil
+}
+
+// checkPathIsMountPoint returns true if p is a mount point.
+func checkPathIsMountPoint(_ context.Context, task Task, p string) (bool, error) {
+	resolved, err := filepath.EvalSymlinks(task.Path(p))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	f, err := os.Open(procMountinfo)
+	if err != nil {
+		return false, fmt.Errorf("failed to read mount table: %w", err)
+	}
+	defer f.Close()
+
+	mountPoints := parseMountInfo(f)
+	return mountPoints[filepath.Clean(resolved)], nil
+}
+
+// mountInfoEscape replaces octal escapes in mountinfo fields.
+var mountInfoEscape = strings.NewReplacer(
+	`\040`, " ",
+	`\011`, "\t",
+	`\012`, "\n",
+	`\134`, `\`,
+)
+
+// parseMountInfo parses the mount points from a file in the
+// format of /proc/self/mountinfo.
+func parseMountInfo(r io.Reader) map[string]bool {
+	mountPoints := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+
+		mountPoints[mountInfoEscape.Replace(fields[4])] = true
+	}
+
+	return mountPoints
+}
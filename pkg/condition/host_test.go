@@ -0,0 +1,232 @@
+package condition
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseComparison(t *testing.T) {
+	cases := []struct {
+		value   string
+		op      string
+		operand string
+	}{
+		{"4G", ">=", "4G"},
+		{"<4G", "<", "4G"},
+		{"<= 4G", "<=", "4G"},
+		{"=2", "=", "2"},
+		{"!=2", "!=", "2"},
+		{">= 5.10", ">=", "5.10"},
+		{"> 1", ">", "1"},
+	}
+
+	for _, c := range cases {
+		op, operand := parseComparison(c.value, ">=")
+		assert.Equal(t, c.op, op, c.value)
+		assert.Equal(t, c.operand, operand, c.value)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]uint64{
+		"1024": 1024,
+		"1K":   1024,
+		"512M": 512 << 20,
+		"4g":   4 << 30,
+		"1T":   1 << 40,
+	}
+
+	for value, expected := range cases {
+		size, err := parseSize(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, size, value)
+	}
+
+	for _, value := range []string{"", "G", "1.5G", "-1"} {
+		_, err := parseSize(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestCheckMemory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "condition-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	procMeminfo = filepath.Join(dir, "meminfo")
+	defer func() { procMeminfo = "/proc/meminfo" }()
+
+	require.NoError(t, ioutil.WriteFile(procMeminfo, []byte("MemTotal:        4194304 kB\nMemFree:         1024 kB\n"), 0644))
+
+	cases := map[string]bool{
+		"4G":    true,
+		"5G":    false,
+		"<5G":   true,
+		"=4G":   true,
+		"!=4G":  false,
+		">4G":   false,
+		"<=4G":  true,
+		">= 1M": true,
+	}
+
+	for value, expected := range cases {
+		res, err := checkMemory(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, res, value)
+	}
+
+	_, err = checkMemory("lots")
+	assert.Error(t, err)
+}
+
+func TestCheckCPUs(t *testing.T) {
+	n := strconv.Itoa(runtime.NumCPU())
+
+	cases := map[string]bool{
+		"1":      true,
+		n:        true,
+		"=" + n:  true,
+		"!=" + n: false,
+		">" + n:  false,
+		"<" + n:  false,
+	}
+
+	for value, expected := range cases {
+		res, err := checkCPUs(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, res, value)
+	}
+}
+
+func TestMatchCommandLine(t *testing.T) {
+	cmdline := "BOOT_IMAGE=/vmlinuz root=/dev/sda1 ro quiet systemd.unit=rescue.target\n"
+
+	cases := map[string]bool{
+		"quiet":                       true,
+		"ro":                          true,
+		"root":                        true,
+		"root=/dev/sda1":              true,
+		"root=/dev/sda2":              false,
+		"systemd.unit":                true,
+		"systemd.unit=rescue.target":  true,
+		"systemd.unit=default.target": false,
+		"splash":                      false,
+		"roo":                         false,
+	}
+
+	for value, expected := range cases {
+		assert.Equal(t, expected, matchCommandLine(cmdline, value), value)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	dir, err := ioutil.TempDir("", "condition-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	machineIDPath = filepath.Join(dir, "machine-id")
+	defer func() { machineIDPath = "/etc/machine-id" }()
+
+	id := "0123456789abcdef0123456789abcdef"
+	require.NoError(t, ioutil.WriteFile(machineIDPath, []byte(id+"\n"), 0644))
+
+	res, err := checkHost(id)
+	assert.NoError(t, err)
+	assert.True(t, res)
+
+	res, err = checkHost(strings.ToUpper(id))
+	assert.NoError(t, err)
+	assert.True(t, res)
+
+	res, err = checkHost("fedcba9876543210fedcba9876543210")
+	assert.NoError(t, err)
+	assert.False(t, res)
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	res, err = checkHost(hostname)
+	assert.NoError(t, err)
+	assert.True(t, res)
+
+	res, err = checkHost("*")
+	assert.NoError(t, err)
+	assert.True(t, res)
+
+	res, err = checkHost(hostname + "-does-not-exist")
+	assert.NoError(t, err)
+	assert.False(t, res)
+}
+
+func TestCheckFirstBoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "condition-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	firstBootPath = filepath.Join(dir, "first-boot")
+	defer func() { firstBootPath = "/run/systemd/first-boot" }()
+
+	res, err := checkFirstBoot("yes")
+	assert.NoError(t, err)
+	assert.False(t, res)
+
+	res, err = checkFirstBoot("no")
+	assert.NoError(t, err)
+	assert.True(t, res)
+
+	require.NoError(t, ioutil.WriteFile(firstBootPath, nil, 0644))
+
+	res, err = checkFirstBoot("true")
+	assert.NoError(t, err)
+	assert.True(t, res)
+
+	_, err = checkFirstBoot("maybe")
+	assert.Error(t, err)
+}
+
+func TestCheckPathIsMountPoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "condition-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dir, err = filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+
+	mnt := filepath.Join(dir, "my mount")
+	require.NoError(t, os.Mkdir(mnt, 0755))
+	require.NoError(t, os.Symlink(mnt, filepath.Join(dir, "link")))
+
+	procMountinfo = filepath.Join(dir, "mountinfo")
+	defer func() { procMountinfo = "/proc/self/mountinfo" }()
+
+	mountinfo := "22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n" +
+		"36 22 0:32 / " + strings.Replace(mnt, " ", `\040`, -1) + " rw - tmpfs tmpfs rw\n"
+	require.NoError(t, ioutil.WriteFile(procMountinfo, []byte(mountinfo), 0644))
+
+	task := Task{Directory: dir}
+	ctx := context.Background()
+
+	cases := map[string]bool{
+		"/":          true,
+		mnt:          true,
+		"my mount":   true,
+		"link":       true,
+		dir:          false,
+		"not-exists": false,
+	}
+
+	for value, expected := range cases {
+		res, err := checkPathIsMountPoint(ctx, task, value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, res, value)
+	}
+}
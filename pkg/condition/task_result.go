@@ -0,0 +1,98 @@
+package condition
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TaskResult describes the result of a task in the current
+// run.
+type TaskResult struct {
+	// Executed is true if the task has already been
+	// executed.
+	Executed bool
+
+	// Changed is true if the task reported changes.
+	Changed bool
+
+	// Err holds the error returned by the task, if any.
+	Err error
+}
+
+// TaskResults provides access to the results of tasks in the
+// current run. It's implemented by the task manager of the
+// runner.
+type TaskResults interface {
+	// TaskResult returns the result of the task name. It
+	// returns false if no such task exists.
+	TaskResult(name string) (TaskResult, bool)
+}
+
+type taskResultsKey struct{}
+
+// WithTaskResults returns a new context that carries results.
+func WithTaskResults(ctx context.Context, results TaskResults) context.Context {
+	return context.WithValue(ctx, taskResultsKey{}, results)
+}
+
+// TaskResultsFromContext returns the task results carried by
+// ctx or nil.
+func TaskResultsFromContext(ctx context.Context) TaskResults {
+	results, _ := ctx.Value(taskResultsKey{}).(TaskResults)
+	return results
+}
+
+// lookupTaskResult returns the result of the task name. The
+// .task extension may be omitted.
+func lookupTaskResult(ctx context.Context, name string) (TaskResult, error) {
+	results := TaskResultsFromContext(ctx)
+	if results == nil {
+		return TaskResult{}, fmt.Errorf("task results not available")
+	}
+
+	if res, ok := results.TaskResult(name); ok {
+		return res, nil
+	}
+
+	if !strings.HasSuffix(name, ".task") {
+		if res, ok := results.TaskResult(name + ".task"); ok {
+			return res, nil
+		}
+	}
+
+	return TaskResult{}, fmt.Errorf("unknown task %q", name)
+}
+
+// checkTaskChanged returns true if the task value has been
+// executed and reported changes.
+func checkTaskChanged(ctx context.Context, _ Task, value string) (bool, error) {
+	res, err := lookupTaskResult(ctx, value)
+	if err != nil {
+		return false, err
+	}
+
+	return res.Executed && res.Err == nil && res.Changed, nil
+}
+
+// checkTaskSucceeded returns true if the task value has been
+// executed without errors.
+func checkTaskSucceeded(ctx context.Context, _ Task, value string) (bool, error) {
+	res, err := lookupTaskResult(ctx, value)
+	if err != nil {
+		return false, err
+	}
+
+	return res.Executed && res.Err == nil, nil
+}
+
+// checkTaskFailed returns true if the task value has been
+// executed and returned an error.
+func checkTaskFailed(ctx context.Context, _ Task, value string) (bool, error) {
+	res, err := lookupTaskResult(ctx, value)
+	if err != nil {
+		return false, err
+	}
+
+	return res.Executed && res.Err != nil, nil
+}
@@ -0,0 +1,58 @@
+package condition
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testResults map[string]TaskResult
+
+func (r testResults) TaskResult(name string) (TaskResult, bool) {
+	res, ok := r[name]
+	return res, ok
+}
+
+func TestTaskResultConditions(t *testing.T) {
+	ctx := WithTaskResults(context.Background(), testResults{
+		"10-changed.task":  {Executed: true, Changed: true},
+		"20-pristine.task": {Executed: true},
+		"30-failed.task":   {Executed: true, Err: errors.New("failed")},
+		"40-pending.task":  {},
+	})
+
+	cases := []struct {
+		task      string
+		changed   bool
+		succeeded bool
+		failed    bool
+	}{
+		{"10-changed.task", true, true, false},
+		{"10-changed", true, true, false},
+		{"20-pristine.task", false, true, false},
+		{"30-failed.task", false, false, true},
+		{"40-pending.task", false, false, false},
+	}
+
+	for _, c := range cases {
+		res, err := checkTaskChanged(ctx, Task{}, c.task)
+		assert.NoError(t, err, c.task)
+		assert.Equal(t, c.changed, res, c.task)
+
+		res, err = checkTaskSucceeded(ctx, Task{}, c.task)
+		assert.NoError(t, err, c.task)
+		assert.Equal(t, c.succeeded, res, c.task)
+
+		res, err = checkTaskFailed(ctx, Task{}, c.task)
+		assert.NoError(t, err, c.task)
+		assert.Equal(t, c.failed, res, c.task)
+	}
+
+	_, err := checkTaskChanged(ctx, Task{}, "unknown.task")
+	assert.Error(t, err)
+
+	_, err = checkTaskChanged(context.Background(), Task{}, "10-changed.task")
+	assert.Error(t, err)
+}
@@ -0,0 +1,127 @@
+package condition
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
+	"github.com/ppacher/system-deploy/pkg/version"
+)
+
+// kernelReleasePath holds the release of the running kernel.
+var kernelReleasePath = "/proc/sys/kernel/osrelease"
+
+// compareFunc compares two versions and returns -1, 0 or 1.
+type compareFunc func(a, b string) int
+
+// checkVersion compares actual against value. value may be
+// prefixed with a comparison operator. If not, it's used as a
+// glob pattern that must match actual.
+func checkVersion(value, actual string, cmp compareFunc) (bool, error) {
+	op, operand := parseComparison(value, "")
+	if operand == "" {
+		return false, fmt.Errorf("missing version in %q", value)
+	}
+
+	if op == "" {
+		return path.Match(operand, actual)
+	}
+
+	return compareResult(op, cmp(actual, operand))
+}
+
+// checkKernelVersion compares the release of the running
+// kernel against value.
+func checkKernelVersion(value string) (bool, error) {
+	release, err := ioutil.ReadFile(kernelReleasePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to determine kernel version: %w", err)
+	}
+
+	return checkVersion(value, strings.TrimSpace(string(release)), version.Compare)
+}
+
+// checkOSVersion compares the VERSION_ID of the os-release
+// file against value.
+func checkOSVersion(value string) (bool, error) {
+	info, err := readOSRelease()
+	if err != nil || info == nil || info.VersionID == "" {
+		return false, err
+	}
+
+	return checkVersion(value, info.VersionID, version.Compare)
+}
+
+// checkSystemDeployVersion compares the version of
+// system-deploy against value.
+func checkSystemDeployVersion(value string) (bool, error) {
+	return checkVersion(value, strings.TrimPrefix(version.Version, "v"), version.Compare)
+}
+
+// checkPackageVersion checks if a package is installed and
+// optionally compares its version. value is in the format
+// "<name> [<op>] [<version>]".
+func checkPackageVersion(ctx context.Context, _ Task, value string) (bool, error) {
+	name, constraint := splitPackageConstraint(value)
+	if name == "" {
+		return false, fmt.Errorf("missing package name in %q", value)
+	}
+
+	for _, m := range pkgmgr.Detect() {
+		installed, err := m.Installed(ctx)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", m.Name(), err)
+		}
+
+		versions := installed.Lookup(name)
+		if len(versions) == 0 {
+			continue
+		}
+
+		if constraint == "" {
+			return true, nil
+		}
+
+		// the package may be installed for multiple
+		// architectures or in multiple versions.
+		for _, v := range versions {
+			matched, err := checkVersion(constraint, v, packageCompareFunc(m.Name()))
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+
+		return false, nil
+	}
+
+	return false, nil
+}
+
+// splitPackageConstraint splits value into the package name
+// and the version constraint.
+func splitPackageConstraint(value string) (string, string) {
+	value = strings.TrimSpace(value)
+
+	idx := strings.IndexAny(value, "<>=! \t")
+	if idx < 0 {
+		return value, ""
+	}
+
+	return value[:idx], strings.TrimSpace(value[idx:])
+}
+
+// packageCompareFunc returns the version comparison used by
+// the package manager called name.
+func packageCompareFunc(name string) compareFunc {
+	switch name {
+	case pkgmgr.Dnf, pkgmgr.Zypper:
+		return version.CompareRPM
+	case pkgmgr.Pacman:
+		return version.ComparePacman
+	default:
+		return version.CompareDebian
+	}
+}
@@ -0,0 +1,97 @@
+package condition
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
+	"github.com/ppacher/system-deploy/pkg/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckVersion(t *testing.T) {
+	cases := []struct {
+		value  string
+		actual string
+		cmp    compareFunc
+		pass   bool
+	}{
+		{">=5.10", "5.15.0-91-generic", version.Compare, true},
+		{"<5.10", "5.15.0-91-generic", version.Compare, false},
+		{"5.15.*", "5.15.0-91-generic", version.Compare, true},
+		{"5.10*", "5.15.0-91-generic", version.Compare, false},
+		{"= 22.04", "22.04", version.Compare, true},
+		{"!= 22.04", "22.04", version.Compare, false},
+		{"> 1.20", "1.18.0-6ubuntu14", version.CompareDebian, false},
+		{">= 1.20", "1:1.20.1-1.fc38", version.CompareRPM, true},
+		{"< 1.0", "1.0~rc1", version.Compare, true},
+		{"< 1.0", "1.0a", packageCompareFunc(pkgmgr.Pacman), true},
+		{"< 1.0", "1.0rc1", packageCompareFunc(pkgmgr.Pacman), true},
+		{">= 1.0", "1.0rc1", packageCompareFunc(pkgmgr.Pacman), false},
+		{"> 1.0", "1.0.1-2", packageCompareFunc(pkgmgr.Pacman), true},
+	}
+
+	for _, c := range cases {
+		res, err := checkVersion(c.value, c.actual, c.cmp)
+		assert.NoError(t, err, c.value)
+		assert.Equal(t, c.pass, res, "%s %s", c.actual, c.value)
+	}
+
+	_, err := checkVersion(">=", "1.0", version.Compare)
+	assert.Error(t, err)
+}
+
+func TestSplitPackageConstraint(t *testing.T) {
+	cases := []struct {
+		value      string
+		name       string
+		constraint string
+	}{
+		{"nginx", "nginx", ""},
+		{"nginx >= 1.20", "nginx", ">= 1.20"},
+		{"nginx>=1.20", "nginx", ">=1.20"},
+		{"nginx 1.20*", "nginx", "1.20*"},
+		{" nginx != 1.20 ", "nginx", "!= 1.20"},
+	}
+
+	for _, c := range cases {
+		name, constraint := splitPackageConstraint(c.value)
+		assert.Equal(t, c.name, name, c.value)
+		assert.Equal(t, c.constraint, constraint, c.value)
+	}
+}
+
+func TestCheckKernelVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "condition-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	kernelReleasePath = filepath.Join(dir, "osrelease")
+	defer func() { kernelReleasePath = "/proc/sys/kernel/osrelease" }()
+
+	require.NoError(t, ioutil.WriteFile(kernelReleasePath, []byte("5.15.0-91-generic\n"), 0644))
+
+	res, err := checkKernelVersion(">=5.10")
+	assert.NoError(t, err)
+	assert.True(t, res)
+
+	res, err = checkKernelVersion(">= 6.1")
+	assert.NoError(t, err)
+	assert.False(t, res)
+}
+
+func TestCheckSystemDeployVersion(t *testing.T) {
+	defer func(v string) { version.Version = v }(version.Version)
+	version.Version = "v0.5.1"
+
+	res, err := checkSystemDeployVersion(">=0.5")
+	assert.NoError(t, err)
+	assert.True(t, res)
+
+	res, err = checkSystemDeployVersion("<0.5")
+	assert.NoError(t, err)
+	assert.False(t, res)
+}
@@ -1,28 +1,210 @@
 package deploy
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
 	"github.com/ppacher/system-conf/conf"
 	"github.com/ppacher/system-deploy/pkg/condition"
 	"github.com/sirupsen/logrus"
 )
 
+// conditions holds all registered conditions by name and
+// conditionOrder the names in the order of registration.
+var (
+	conditionsLock sync.RWMutex
+	conditions     = make(map[string]*condition.Condition)
+	conditionOrder []string
+)
+
+// GetCondition returns the registered condition name.
+func GetCondition(name string) (*condition.Condition, bool) {
+	conditionsLock.RLock()
+	defer conditionsLock.RUnlock()
+
+	cond, ok := conditions[name]
+	return cond, ok
+}
+
+// Conditions returns all registered conditions in the order
+// they have been registered.
+func Conditions() []*condition.Condition {
+	conditionsLock.RLock()
+	defer conditionsLock.RUnlock()
+
+	result := make([]*condition.Condition, len(conditionOrder))
+	for idx, name := range conditionOrder {
+		result[idx] = conditions[name]
+	}
+	return result
+}
+
+// ConditionTask returns the description of t passed to
+// condition checks.
+func (t *Task) ConditionTask() condition.Task {
+	return condition.Task{
+		FileName:    t.FileName,
+		Directory:   t.Directory,
+		Environment: t.Environment,
+	}
+}
+
 // EvaluateConditions evalutes all conditions of a task. If any
 // condition fails the condition is returned along with an error.
 // If all conditions are met, EvalutateConditions returns nil, nil.
-func EvaluateConditions(t *Task) (*condition.Instance, error) {
-	for _, cond := range t.Conditions {
+// Conditions are evaluated before assertions and triggering
+// values are ORed separately for both of them.
+func EvaluateConditions(ctx context.Context, t *Task) (*condition.Instance, error) {
+	return EvaluateInstances(ctx, t, t.Conditions)
+}
+
+// EvaluateInstances is like EvaluateConditions but evaluates
+// instances instead of the conditions of the task meta section.
+// It's used for conditions defined in action sections.
+func EvaluateInstances(ctx context.Context, t *Task, instances []condition.Instance) (*condition.Instance, error) {
+	var conds, assertions []condition.Instance
+	for _, cond := range instances {
 		logrus.Debugf("%s: evaluating conditon %s", t.FileName, cond.Name)
-		if err := cond.Run(); err != nil {
-			return &cond, err
+		if cond.Assertion {
+			assertions = append(assertions, cond)
+		} else {
+			conds = append(conds, cond)
 		}
 	}
 
-	return nil, nil
+	task := t.ConditionTask()
+	if failed, err := condition.Check(ctx, task, conds); err != nil {
+		return failed, err
+	}
+
+	return condition.Check(ctx, task, assertions)
+}
+
+// CheckConditionPhase returns an error if any of instances
+// requires EvaluateConditions=execute but t evaluates its
+// conditions while preparing.
+func CheckConditionPhase(t *Task, instances []condition.Instance) error {
+	if t.ConditionPhase == PhaseExecute {
+		return nil
+	}
+
+	for _, instance := range instances {
+		if !instance.RequiresExecutePhase {
+			continue
+		}
+
+		prefix := "Condition"
+		if instance.Assertion {
+			prefix = "Assert"
+		}
+
+		return fmt.Errorf("%s%s= requires EvaluateConditions=%s", prefix, instance.Name, PhaseExecute)
+	}
+
+	return nil
+}
+
+// ConditionOptions returns the option specs for ConditionXxx=
+// and AssertXxx= of all registered conditions. Those options are
+// allowed in every action section.
+func ConditionOptions() []conf.OptionSpec {
+	var specs []conf.OptionSpec
+	for _, cond := range Conditions() {
+		specs = append(specs,
+			conf.OptionSpec{
+				Name:        "Condition" + cond.Name,
+				Description: cond.Description,
+				Type:        conf.StringSliceType,
+				Internal:    true,
+			},
+			conf.OptionSpec{
+				Name:        "Assert" + cond.Name,
+				Description: cond.Description,
+				Type:        conf.StringSliceType,
+				Internal:    true,
+			},
+		)
+	}
+	return specs
+}
+
+// SplitConditions removes all ConditionXxx= and AssertXxx=
+// options of registered conditions from sec and returns them as
+// condition instances together with the remaining section. All
+// values of the same option are combined into a single instance.
+func SplitConditions(sec conf.Section) ([]condition.Instance, conf.Section) {
+	rest := conf.Section{
+		Name: sec.Name,
+	}
+
+	var instances []condition.Instance
+	index := make(map[string]int)
+
+	for _, opt := range sec.Options {
+		cond, assert, ok := lookupConditionOption(opt.Name)
+		if !ok {
+			rest.Options = append(rest.Options, opt)
+			continue
+		}
+
+		key := strings.ToLower(opt.Name)
+		idx, ok := index[key]
+		if !ok {
+			idx = len(instances)
+			index[key] = idx
+			instances = append(instances, condition.Instance{
+				Condition: cond,
+				Assertion: assert,
+			})
+		}
+
+		instances[idx].Values = append(instances[idx].Values, opt.Value)
+	}
+
+	return instances, rest
+}
+
+// lookupConditionOption returns the condition for the option
+// called name and whether it's an assertion.
+func lookupConditionOption(name string) (*condition.Condition, bool, bool) {
+	conditionsLock.RLock()
+	defer conditionsLock.RUnlock()
+
+	lower := strings.ToLower(name)
+	for prefix, assert := range map[string]bool{"condition": false, "assert": true} {
+		if !strings.HasPrefix(lower, prefix) {
+			continue
+		}
+
+		for condName, cond := range conditions {
+			if strings.EqualFold(name[len(prefix):], condName) {
+				return cond, assert, true
+			}
+		}
+	}
+
+	return nil, false, false
 }
 
 // RegisterCondition registers a new condition type for
-// the task meta-section.
-func RegisterCondition(cond condition.Condition) {
+// the task meta-section. The condition is available as
+// Condition<Name>= and Assert<Name>=.
+func RegisterCondition(cond condition.Condition) error {
+	if cond.Check == nil {
+		return condition.ErrNoCheckFunc
+	}
+
+	conditionsLock.Lock()
+	if _, ok := conditions[cond.Name]; ok {
+		conditionsLock.Unlock()
+		return fmt.Errorf("condition %s already registered", cond.Name)
+	}
+	conditions[cond.Name] = &cond
+	conditionOrder = append(conditionOrder, cond.Name)
+	conditionsLock.Unlock()
+
 	logrus.Debugf("registering condition %s", cond.Name)
 
 	condName := "Condition" + cond.Name
@@ -96,12 +278,22 @@ func RegisterCondition(cond condition.Condition) {
 		get:        get,
 		set:        getSetter(true),
 	})
+
+	return nil
 }
 
 // RegisterAllConditions registers all built-in conditions
 // from the condition package.
 func RegisterAllConditions() {
 	for _, cond := range condition.BuiltinConditions {
-		RegisterCondition(cond)
+		MustRegisterCondition(cond)
+	}
+}
+
+// MustRegisterCondition is like RegisterCondition but panics
+// on error.
+func MustRegisterCondition(cond condition.Condition) {
+	if err := RegisterCondition(cond); err != nil {
+		panic(err)
 	}
 }
@@ -0,0 +1,116 @@
+package deploy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/condition"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterCustomCondition(t *testing.T) {
+	var received condition.Task
+	cond := condition.New("TestCustom", "A custom condition for testing", func(_ context.Context, task condition.Task, value string) (bool, error) {
+		received = task
+		return value == "yes", nil
+	})
+
+	require.NoError(t, RegisterCondition(cond))
+	assert.Error(t, RegisterCondition(cond), "duplicate conditions must be rejected")
+	assert.Equal(t, condition.ErrNoCheckFunc, RegisterCondition(condition.Condition{Name: "TestNoCheck"}))
+
+	registered, ok := GetCondition("TestCustom")
+	require.True(t, ok)
+	assert.Equal(t, "TestCustom", registered.Name)
+
+	tsk, err := Decode("/tmp/test.task", strings.NewReader("[Task]\nConditionTestCustom= yes\n\n[Test]\nKey= Value\n"))
+	require.NoError(t, err)
+
+	failed, err := EvaluateConditions(context.Background(), tsk)
+	assert.NoError(t, err)
+	assert.Nil(t, failed)
+	assert.Equal(t, "test.task", received.FileName)
+	assert.Equal(t, "/tmp", received.Directory)
+
+	tsk, err = Decode("/tmp/test.task", strings.NewReader("[Task]\nAssertTestCustom= no\n\n[Test]\nKey= Value\n"))
+	require.NoError(t, err)
+
+	failed, err = EvaluateConditions(context.Background(), tsk)
+	assert.Error(t, err)
+	if assert.NotNil(t, failed) {
+		assert.True(t, failed.Assertion)
+	}
+}
+
+func TestSplitConditions(t *testing.T) {
+	cond := condition.New("TestSplit", "A condition for testing", condition.Simple(func(value string) (bool, error) {
+		return value == "yes", nil
+	}))
+	require.NoError(t, RegisterCondition(cond))
+
+	sec := conf.Section{
+		Name: "Copy",
+		Options: conf.Options{
+			{Name: "Source", Value: "a"},
+			{Name: "ConditionTestSplit", Value: "yes"},
+			{Name: "assertTestSplit", Value: "yes"},
+			{Name: "ConditionTestSplit", Value: "!no"},
+			{Name: "ConditionUnknown", Value: "yes"},
+		},
+	}
+
+	instances, rest := SplitConditions(sec)
+	assert.Equal(t, "Copy", rest.Name)
+	assert.Equal(t, conf.Options{
+		{Name: "Source", Value: "a"},
+		{Name: "ConditionUnknown", Value: "yes"},
+	}, rest.Options)
+
+	require.Len(t, instances, 2)
+	assert.Equal(t, "TestSplit", instances[0].Name)
+	assert.False(t, instances[0].Assertion)
+	assert.Equal(t, []string{"yes", "!no"}, instances[0].Values)
+	assert.True(t, instances[1].Assertion)
+	assert.Equal(t, []string{"yes"}, instances[1].Values)
+
+	failed, err := EvaluateInstances(context.Background(), &Task{}, instances)
+	assert.NoError(t, err)
+	assert.Nil(t, failed)
+
+	instances[0].Values = []string{"no"}
+	failed, err = EvaluateInstances(context.Background(), &Task{}, instances)
+	assert.Error(t, err)
+	assert.Same(t, instances[0].Condition, failed.Condition)
+
+	specs := ConditionOptions()
+	var found bool
+	for _, spec := range specs {
+		if spec.Name == "AssertTestSplit" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestCheckConditionPhase(t *testing.T) {
+	cond := condition.New("TestDeferred", "A condition that requires the execute phase", condition.Simple(func(value string) (bool, error) {
+		return true, nil
+	}))
+	cond.RequiresExecutePhase = true
+	require.NoError(t, RegisterCondition(cond))
+
+	tsk, err := Decode("/tmp/test.task", strings.NewReader("[Task]\nConditionTestDeferred= other\n\n[Test]\nKey= Value\n"))
+	require.NoError(t, err)
+	assert.EqualError(t, CheckConditionPhase(tsk, tsk.Conditions), "ConditionTestDeferred= requires EvaluateConditions=execute")
+
+	tsk, err = Decode("/tmp/test.task", strings.NewReader("[Task]\nAssertTestDeferred= other\nEvaluateConditions= prepare\n\n[Test]\nKey= Value\n"))
+	require.NoError(t, err)
+	assert.EqualError(t, CheckConditionPhase(tsk, tsk.Conditions), "AssertTestDeferred= requires EvaluateConditions=execute")
+
+	tsk, err = Decode("/tmp/test.task", strings.NewReader("[Task]\nConditionTestDeferred= other\nEvaluateConditions= execute\n\n[Test]\nKey= Value\n"))
+	require.NoError(t, err)
+	assert.NoError(t, CheckConditionPhase(tsk, tsk.Conditions))
+}
@@ -0,0 +1,89 @@
+package deploy
+
+import (
+	"path"
+	"strings"
+
+	"github.com/ppacher/system-deploy/pkg/secrets"
+)
+
+// SensitivePatterns holds glob patterns of environment variable
+// names whose values are always treated as secret. Patterns are
+// matched case-insensitively.
+var SensitivePatterns = []string{
+	"*PASSWORD*",
+	"*TOKEN*",
+}
+
+// IsSecretVariable returns true if the value of the environment
+// variable name is secret. That is, name matches one of
+// SensitivePatterns or the SecretEnvironment= patterns of the task.
+func (tsk *Task) IsSecretVariable(name string) bool {
+	name = strings.ToUpper(name)
+
+	for _, patterns := range [][]string{SensitivePatterns, tsk.SecretEnvironment} {
+		for _, p := range patterns {
+			if ok, _ := path.Match(strings.ToUpper(p), name); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// MarkSecretEnvironment marks the values of all secret variables
+// in the task environment as sensitive so they are redacted from
+// log output.
+func MarkSecretEnvironment(t *Task) {
+	for _, e := range t.Environment {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 && t.IsSecretVariable(parts[0]) {
+			secrets.MarkSensitive(parts[1])
+		}
+	}
+}
+
+// RedactedEnvironment returns a copy of the task environment
+// with the values of all secret variables redacted.
+func (tsk *Task) RedactedEnvironment() []string {
+	if tsk.Environment == nil {
+		return nil
+	}
+
+	env := make([]string, len(tsk.Environment))
+	for idx, e := range tsk.Environment {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 && tsk.IsSecretVariable(parts[0]) {
+			e = parts[0] + "=" + secrets.Redacted
+		}
+		env[idx] = secrets.Redact(e)
+	}
+
+	return env
+}
+
+// Redacted returns a copy of the task with the values of all
+// secret variables and all sensitive values in options and
+// conditions redacted. It's meant for debug output of the task.
+func (tsk *Task) Redacted() *Task {
+	n := tsk.Clone()
+	n.Environment = tsk.RedactedEnvironment()
+	n.Description = secrets.Redact(n.Description)
+
+	for idx := range n.Sections {
+		for optIdx := range n.Sections[idx].Options {
+			n.Sections[idx].Options[optIdx].Value = secrets.Redact(n.Sections[idx].Options[optIdx].Value)
+		}
+	}
+
+	for idx, instance := range n.Conditions {
+		values := make([]string, len(instance.Values))
+		for valIdx, value := range instance.Values {
+			values[valIdx] = secrets.Redact(value)
+		}
+		n.Conditions[idx].Values = values
+	}
+
+	return n
+}
@@ -0,0 +1,75 @@
+package deploy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ppacher/system-deploy/pkg/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretEnvironment(t *testing.T) {
+	tsk, err := Decode("/tmp/test.task", strings.NewReader("[Task]\nSecretEnvironment= API_*\nSecretEnvironment= db_key\n\n[Test]\nKey= Value\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"API_*", "db_key"}, tsk.SecretEnvironment)
+
+	tsk.Environment = []string{
+		"DB_PASSWORD=redact-password",
+		"github_token=redact-token",
+		"API_URL=https://example.com",
+		"DB_KEY=redact-key",
+		"USER=admin-user",
+		"EMPTY",
+	}
+
+	assert.True(t, tsk.IsSecretVariable("DB_PASSWORD"))
+	assert.True(t, tsk.IsSecretVariable("github_token"))
+	assert.True(t, tsk.IsSecretVariable("API_URL"))
+	assert.True(t, tsk.IsSecretVariable("DB_KEY"))
+	assert.False(t, tsk.IsSecretVariable("USER"))
+
+	assert.Equal(t, []string{
+		"DB_PASSWORD=" + secrets.Redacted,
+		"github_token=" + secrets.Redacted,
+		"API_URL=" + secrets.Redacted,
+		"DB_KEY=" + secrets.Redacted,
+		"USER=admin-user",
+		"EMPTY",
+	}, tsk.RedactedEnvironment())
+
+	MarkSecretEnvironment(tsk)
+	assert.True(t, secrets.IsSensitive("redact-password"))
+	assert.True(t, secrets.IsSensitive("redact-key"))
+	assert.False(t, secrets.IsSensitive("admin-user"))
+
+	_, err = Decode("/tmp/test.task", strings.NewReader("[Task]\nSecretEnvironment= [\n\n[Test]\nKey= Value\n"))
+	assert.Error(t, err)
+}
+
+func TestRedactedTaskJSON(t *testing.T) {
+	secret := `p&ss"w\rd<>`
+	secrets.MarkSensitive(secret)
+
+	tsk, err := Decode("/tmp/test.task", strings.NewReader("[Task]\nDescription= Deploy\n\n[Test]\nKey= Value\n"))
+	require.NoError(t, err)
+
+	tsk.Environment = []string{"DB_PASSWORD=" + secret}
+	tsk.Sections[0].Options[0].Value = "user:" + secret
+
+	blob, err := json.Marshal(tsk.Redacted())
+	require.NoError(t, err)
+
+	escaped, err := json.Marshal(secret)
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(blob), secret)
+	assert.NotContains(t, string(blob), strings.Trim(string(escaped), `"`))
+	assert.NotContains(t, string(blob), `p&ss`)
+	assert.Contains(t, string(blob), "user:"+secrets.Redacted)
+
+	// the original task must not be modified.
+	assert.Equal(t, "user:"+secret, tsk.Sections[0].Options[0].Value)
+	assert.Equal(t, "DB_PASSWORD="+secret, tsk.Environment[0])
+}
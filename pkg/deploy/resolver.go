@@ -0,0 +1,102 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Resolver resolves a reference in the form of ${name:argument}
+// during substitution. arg is the argument of the reference after
+// variable substitution has been applied to it.
+type Resolver func(ctx context.Context, t *Task, arg string) (string, error)
+
+var (
+	resolversLock sync.RWMutex
+	resolvers     = map[string]Resolver{
+		"file":   resolveFile,
+		"cmd":    resolveCommand,
+		"secret": resolveSecret,
+		"fact":   resolveFact,
+	}
+)
+
+// RegisterResolver registers a new resolver for references in
+// the form of ${name:argument}.
+func RegisterResolver(name string, r Resolver) error {
+	resolversLock.Lock()
+	defer resolversLock.Unlock()
+
+	if _, ok := resolvers[name]; ok {
+		return fmt.Errorf("resolver %s already registered", name)
+	}
+
+	resolvers[name] = r
+	return nil
+}
+
+// GetResolver returns the resolver registered under name.
+func GetResolver(name string) (Resolver, bool) {
+	resolversLock.RLock()
+	defer resolversLock.RUnlock()
+
+	r, ok := resolvers[name]
+	return r, ok
+}
+
+// Resolvers returns the names of all registered resolvers.
+func Resolvers() []string {
+	resolversLock.RLock()
+	defer resolversLock.RUnlock()
+
+	names := make([]string, 0, len(resolvers))
+	for name := range resolvers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// resolverAt checks if value contains a reference to a registered
+// resolver at idx. It returns the resolver, its name, the start of
+// the reference argument and the index of the closing brace or -1
+// if the reference is not terminated.
+func resolverAt(value string, idx int) (Resolver, string, int, int, bool) {
+	if idx+2 >= len(value) || value[idx] != '$' || value[idx+1] != '{' {
+		return nil, "", 0, 0, false
+	}
+
+	nameEnd := idx + 2
+	for nameEnd < len(value) && value[nameEnd] >= 'a' && value[nameEnd] <= 'z' {
+		nameEnd++
+	}
+
+	if nameEnd == idx+2 || nameEnd >= len(value) || value[nameEnd] != ':' {
+		return nil, "", 0, 0, false
+	}
+
+	name := value[idx+2 : nameEnd]
+	r, ok := GetResolver(name)
+	if !ok {
+		return nil, "", 0, 0, false
+	}
+
+	// find the matching closing brace. References may
+	// be nested in the argument.
+	depth := 1
+	for end := nameEnd + 1; end < len(value); end++ {
+		switch value[end] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return r, name, nameEnd + 1, end, true
+			}
+		}
+	}
+
+	return r, name, nameEnd + 1, -1, true
+}
@@ -0,0 +1,95 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ppacher/system-deploy/pkg/facts"
+	"github.com/ppacher/system-deploy/pkg/secrets"
+	"github.com/ppacher/system-deploy/pkg/utils"
+)
+
+// resolveTimeout is the maximum time resolving all references
+// of a single value may take.
+var resolveTimeout = 30 * time.Second
+
+// resolveFile resolves ${file:path} to the content of path.
+// Relative paths are resolved against the directory of the
+// task. A single trailing newline is removed.
+func resolveFile(_ context.Context, t *Task, path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(t.Directory, path)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return trimNewline(string(content)), nil
+}
+
+// resolveCommand resolves ${cmd:command} to the output of
+// command. The command is executed in the directory of the
+// task with the task environment. A single trailing newline
+// is removed.
+func resolveCommand(ctx context.Context, t *Task, command string) (string, error) {
+	env := make(map[string]string, len(t.Environment))
+	for _, e := range t.Environment {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+
+	// only stdout is used as the value so warnings printed
+	// to stderr don't end up in it.
+	var output strings.Builder
+	err := utils.ExecCommand(ctx, t.Directory, command, &utils.ExecOptions{
+		Env:    env,
+		Stdout: &output,
+	})
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("command timed out after %s", resolveTimeout)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return trimNewline(output.String()), nil
+}
+
+// resolveSecret resolves ${secret:[provider:]key} using the
+// secret providers of the secrets package. Resolved values are
+// marked as sensitive.
+func resolveSecret(ctx context.Context, _ *Task, ref string) (string, error) {
+	return secrets.Get(ctx, ref)
+}
+
+// resolveFact resolves ${fact:name} to the value of the host
+// fact name. See package facts for available facts.
+func resolveFact(ctx context.Context, _ *Task, name string) (string, error) {
+	// custom facts may fail while builtin ones are still
+	// available.
+	f, err := facts.Load(ctx)
+
+	value, ok := f[strings.ToLower(name)]
+	if !ok {
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("unknown fact %q", name)
+	}
+
+	return value, nil
+}
+
+// trimNewline removes a single trailing newline from s.
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
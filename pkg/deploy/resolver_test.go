@@ -0,0 +1,76 @@
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ppacher/system-deploy/pkg/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvsubstResolvers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deploy-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "version"), []byte("1.2.3\n"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "secrets"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "secrets", "db"), []byte("db-password"), 0600))
+
+	defer func(old string) { secrets.SecretDir = old }(secrets.SecretDir)
+	secrets.SecretDir = filepath.Join(dir, "secrets")
+
+	require.NoError(t, RegisterResolver("upper", func(_ context.Context, _ *Task, arg string) (string, error) {
+		return "UPPER-" + arg, nil
+	}))
+	assert.Error(t, RegisterResolver("upper", nil))
+
+	tsk := &Task{
+		FileName:    "test.task",
+		Directory:   dir,
+		Environment: []string{"NAME=world", "FILE=version"},
+	}
+
+	cases := []struct {
+		Input  string
+		Output string
+		Err    bool
+	}{
+		{"hello ${NAME}", "hello world", false},
+		{"v${file:version}", "v1.2.3", false},
+		{"v${file:${FILE}}", "v1.2.3", false},
+		{"${file:" + filepath.Join(dir, "version") + "}", "1.2.3", false},
+		{"${cmd:echo ${NAME}}!", "world!", false},
+		{`${cmd:sh -c "echo out; echo err >&2"}`, "out", false},
+		{"pass=${secret:db}", "pass=db-password", false},
+		{"pass=${secret:file:db}", "pass=db-password", false},
+		{"${fact:arch}", runtime.GOARCH, false},
+		{"${upper:${upper:a}}", "UPPER-UPPER-a", false},
+		{"$${file:version}", "${file:version}", false},
+		{"${NAME}-${file:version}-${NAME}", "world-1.2.3-world", false},
+		{"${file:missing}", "", true},
+		{"${file:version", "", true},
+		{"${fact:unknown}", "", true},
+		{"${cmd:false}", "", true},
+		{"${UNSET}", "", true},
+	}
+
+	for _, c := range cases {
+		out, err := tsk.Envsubst(tsk.FileName, c.Input)
+		if c.Err {
+			assert.Error(t, err, c.Input)
+			continue
+		}
+
+		if assert.NoError(t, err, c.Input) {
+			assert.Equal(t, c.Output, out, c.Input)
+		}
+	}
+
+	assert.True(t, secrets.IsSensitive("db-password"))
+}
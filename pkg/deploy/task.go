@@ -14,6 +14,17 @@ import (
 // ErrInvalidTaskSection is returned if a section is invalid.
 var ErrInvalidTaskSection = errors.New("invalid task section")
 
+// Supported values for EvaluateConditions=.
+const (
+	// PhasePrepare evaluates conditions while preparing
+	// the task, before any task has been executed.
+	PhasePrepare = "prepare"
+
+	// PhaseExecute evaluates conditions right before the
+	// task is executed.
+	PhaseExecute = "execute"
+)
+
 // Task defines a deploy task.
 type Task struct {
 	file *conf.File
@@ -43,8 +54,18 @@ type Task struct {
 	// Environment holds the parsed environment.
 	Environment []string
 
+	// SecretEnvironment holds glob patterns of environment
+	// variables whose values are sensitive and must be redacted
+	// from logs.
+	SecretEnvironment []string
+
 	// Conditions is a list of conditions that must match.
 	Conditions []condition.Instance
+
+	// ConditionPhase defines when the conditions of the task
+	// and its actions are evaluated. It's either PhasePrepare
+	// or PhaseExecute. An empty value equals PhasePrepare.
+	ConditionPhase string
 }
 
 // DecodeFile is like Decode but reads the task from
@@ -141,6 +162,8 @@ func (tsk *Task) Clone() *Task {
 		Description: tsk.Description,
 		StartMasked: tsk.StartMasked,
 		Disabled:    tsk.Disabled,
+
+		ConditionPhase: tsk.ConditionPhase,
 	}
 
 	if tsk.EnvironmentFiles != nil {
@@ -153,6 +176,11 @@ func (tsk *Task) Clone() *Task {
 		copy(n.Environment, tsk.Environment)
 	}
 
+	if tsk.SecretEnvironment != nil {
+		n.SecretEnvironment = make([]string, len(tsk.SecretEnvironment))
+		copy(n.SecretEnvironment, tsk.SecretEnvironment)
+	}
+
 	if tsk.Conditions != nil {
 		n.Conditions = make([]condition.Instance, len(tsk.Conditions))
 		copy(n.Conditions, tsk.Conditions)
@@ -1,15 +1,85 @@
 package deploy
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/a8m/envsubst/parse"
+	"github.com/ppacher/system-deploy/pkg/secrets"
 	"github.com/ppacher/system-deploy/pkg/utils/envfile"
 )
 
-// Envsubst applies environment substition on value.
+// Envsubst applies environment substition on value. References
+// in the form of ${name:argument} are resolved using the resolver
+// registered under name (see RegisterResolver). Resolver references
+// may not be used inside the default value of a variable.
 func (tsk *Task) Envsubst(file, value string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	var result strings.Builder
+	start := 0
+
+	for idx := 0; idx < len(value); idx++ {
+		if value[idx] != '$' {
+			continue
+		}
+
+		// $$ escapes a dollar sign and is handled by envsubst.
+		if idx+1 < len(value) && value[idx+1] == '$' {
+			idx++
+			continue
+		}
+
+		resolve, name, argStart, end, ok := resolverAt(value, idx)
+		if !ok {
+			continue
+		}
+
+		if end < 0 {
+			return "", fmt.Errorf("%s: reference to %s: closing brace expected", file, name)
+		}
+
+		text, err := tsk.envsubst(file, value[start:idx])
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(text)
+
+		arg, err := tsk.Envsubst(file, value[argStart:end])
+		if err != nil {
+			return "", err
+		}
+
+		resolved, err := resolve(ctx, tsk, arg)
+		if err != nil {
+			return "", fmt.Errorf("%s: failed to resolve ${%s:%s}: %w", file, name, arg, err)
+		}
+		result.WriteString(resolved)
+
+		start = end + 1
+		idx = end
+	}
+
+	text, err := tsk.envsubst(file, value[start:])
+	if err != nil {
+		return "", err
+	}
+	result.WriteString(text)
+
+	return result.String(), nil
+}
+
+// envsubst applies variable substitution on value.
+func (tsk *Task) envsubst(file, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
 	r := &parse.Restrictions{
 		NoEmpty: false,
 		NoUnset: true,
@@ -20,9 +90,17 @@ func (tsk *Task) Envsubst(file, value string) (string, error) {
 }
 
 // LoadEnv loads all environment files specified in t.EnvironmentFiles
-// and populates t.Environment.
+// and populates t.Environment. Variables that are already part of
+// t.Environment are kept and may be used and overwritten by the
+// environment files.
 func LoadEnv(t *Task) error {
-	env := make(map[string]string)
+	env := make(map[string]string, len(t.Environment))
+	for _, e := range t.Environment {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
 
 	for _, file := range t.EnvironmentFiles {
 		var err error
@@ -40,10 +118,32 @@ func LoadEnv(t *Task) error {
 	return nil
 }
 
+// MergeEnv merges env into t.Environment. Variables in env
+// overwrite existing variables with the same name.
+func MergeEnv(t *Task, env ...string) {
+	index := make(map[string]int, len(t.Environment))
+	for idx, e := range t.Environment {
+		index[strings.SplitN(e, "=", 2)[0]] = idx
+	}
+
+	for _, e := range env {
+		key := strings.SplitN(e, "=", 2)[0]
+		if idx, ok := index[key]; ok {
+			t.Environment[idx] = e
+			continue
+		}
+
+		index[key] = len(t.Environment)
+		t.Environment = append(t.Environment, e)
+	}
+}
+
 // ApplyEnvironment applies environment variable substitution
 // to all unit options. If tsk.Environment is nil, ApplyEnvironment
 // tries to call LoadEnv(tsk) first. Note that ApplyEnvironment does
-// not substitude variables in the tasks meta section.
+// not substitude variables in the tasks meta section. The values
+// of secret variables are marked as sensitive (see
+// MarkSecretEnvironment).
 func ApplyEnvironment(tsk *Task) error {
 	if tsk.Environment == nil {
 		if err := LoadEnv(tsk); err != nil {
@@ -51,6 +151,8 @@ func ApplyEnvironment(tsk *Task) error {
 		}
 	}
 
+	MarkSecretEnvironment(tsk)
+
 	for idx, sec := range tsk.Sections {
 		for optIdx, opt := range sec.Options {
 			var err error
@@ -69,20 +171,52 @@ func ApplyEnvironment(tsk *Task) error {
 }
 
 func loadEnv(file string, env map[string]string) (map[string]string, error) {
-	f, err := os.Open(file)
-	if err != nil {
-		return nil, err
+	var r io.Reader
+
+	// encrypted files are only decrypted in memory.
+	encrypted := secrets.IsEncrypted(file)
+	if encrypted {
+		content, err := secrets.DecryptFile(file)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(content)
+	} else {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
 	}
-	defer f.Close()
 
-	p := envfile.NewWithConfig(file, f, envfile.Config{
+	p := envfile.NewWithConfig(file, r, envfile.Config{
 		Env:                env,
 		EnableSubstitution: true,
 	})
 
+	// the parser may update env in place so keep a copy to
+	// find the values set by an encrypted file.
+	previous := make(map[string]string, len(env))
+	for key, value := range env {
+		previous[key] = value
+	}
+
 	if err := p.Parse(); err != nil {
 		return nil, err
 	}
 
-	return p.Env(), nil
+	result := p.Env()
+
+	// all values loaded from encrypted files are considered
+	// secret and must never be logged.
+	if encrypted {
+		for key, value := range result {
+			if old, ok := previous[key]; !ok || old != value {
+				secrets.MarkSensitive(value)
+			}
+		}
+	}
+
+	return result, nil
 }
@@ -0,0 +1,85 @@
+package deploy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/ppacher/system-deploy/pkg/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadEncryptedEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deploy-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	keyDir := filepath.Join(dir, "keys")
+	require.NoError(t, os.Mkdir(keyDir, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(keyDir, "host.key"), []byte(id.String()), 0600))
+
+	defer func(old string) { secrets.KeyDir = old }(secrets.KeyDir)
+	secrets.KeyDir = keyDir
+
+	var buf bytes.Buffer
+	require.NoError(t, secrets.Encrypt(&buf, []byte("DB_PASSWORD=s3cretpassword\nDB_USER=\"${USER_NAME}\"\n"), []age.Recipient{id.Recipient()}, true))
+
+	plainFile := filepath.Join(dir, "plain.env")
+	encryptedFile := filepath.Join(dir, "db.env.age")
+	require.NoError(t, ioutil.WriteFile(plainFile, []byte("USER_NAME=app\nAPP_URL=\"https://plain.example.org\"\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(encryptedFile, buf.Bytes(), 0600))
+
+	tsk := &Task{
+		EnvironmentFiles: []string{plainFile, encryptedFile},
+	}
+	require.NoError(t, LoadEnv(tsk))
+
+	sort.Strings(tsk.Environment)
+	assert.Equal(t, []string{"APP_URL=https://plain.example.org", "DB_PASSWORD=s3cretpassword", "DB_USER=app", "USER_NAME=app"}, tsk.Environment)
+
+	// values loaded from encrypted files are sensitive.
+	assert.True(t, secrets.IsSensitive("s3cretpassword"))
+	assert.False(t, secrets.IsSensitive("https://plain.example.org"))
+
+	secrets.KeyDir = filepath.Join(dir, "empty")
+	assert.Error(t, LoadEnv(&Task{EnvironmentFiles: []string{encryptedFile}}))
+}
+
+func TestLoadEnvKeepsExisting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deploy-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "test.env")
+	require.NoError(t, ioutil.WriteFile(file, []byte("GREETING=\"hello ${FACT_HOSTNAME}\"\nFACT_OS_ID=custom\n"), 0644))
+
+	tsk := &Task{
+		EnvironmentFiles: []string{file},
+		Environment:      []string{"FACT_HOSTNAME=web1", "FACT_OS_ID=debian"},
+	}
+	require.NoError(t, LoadEnv(tsk))
+
+	sort.Strings(tsk.Environment)
+	assert.Equal(t, []string{"FACT_HOSTNAME=web1", "FACT_OS_ID=custom", "GREETING=hello web1"}, tsk.Environment)
+}
+
+func TestMergeEnv(t *testing.T) {
+	tsk := &Task{
+		Environment: []string{"A=1", "B=2"},
+	}
+
+	MergeEnv(tsk, "B=3", "C=4", "C=5")
+	assert.Equal(t, []string{"A=1", "B=3", "C=5"}, tsk.Environment)
+
+	out, err := tsk.Envsubst(tsk.FileName, "${B}${C}")
+	require.NoError(t, err)
+	assert.Equal(t, "35", out)
+}
@@ -0,0 +1,29 @@
+package deploy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ppacher/system-deploy/pkg/condition"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalTaskWithConditions(t *testing.T) {
+	cond := condition.New("TestJSON", "A condition for testing", condition.Simple(func(value string) (bool, error) {
+		return value == "yes", nil
+	}))
+	require.NoError(t, RegisterCondition(cond))
+
+	tsk, err := Decode("/tmp/test.task", strings.NewReader("[Task]\nConditionTestJSON= yes\n\n[Test]\nKey= Value\n"))
+	require.NoError(t, err)
+	require.Len(t, tsk.Conditions, 1)
+
+	// tasks are dumped as JSON for debugging so they must
+	// be encodable even if they contain conditions.
+	blob, err := json.MarshalIndent(tsk, "", "  ")
+	require.NoError(t, err)
+	assert.Contains(t, string(blob), `"Name": "TestJSON"`)
+	assert.NotContains(t, string(blob), "Check")
+}
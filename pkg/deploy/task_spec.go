@@ -1,6 +1,10 @@
 package deploy
 
 import (
+	"fmt"
+	"path"
+	"strings"
+
 	"github.com/ppacher/system-conf/conf"
 )
 
@@ -101,7 +105,8 @@ var taskOptions = []taskMetaOption{
 		OptionSpec: conf.OptionSpec{
 			Name: "Environment",
 			Description: "Configure one or more environment files that are loaded into the task and may be used during substitution. " +
-				"Environment files are loaded in the order they are specified and later ones overwrite already existing values.",
+				"Environment files are loaded in the order they are specified and later ones overwrite already existing values. " +
+				"Files with an .age extension are decrypted in memory using the keys in /etc/system-deploy/keys.",
 			Type: conf.StringSliceType,
 		},
 		set: func(val conf.Options, t *Task) error {
@@ -122,4 +127,71 @@ var taskOptions = []taskMetaOption{
 			return t.EnvironmentFiles
 		},
 	},
+	{
+		OptionSpec: conf.OptionSpec{
+			Name: "SecretEnvironment",
+			Description: "Declares environment variables whose values are sensitive and must be redacted from log output. " +
+				"Glob patterns are supported. Variables matching *PASSWORD* or *TOKEN* are always treated as secret.",
+			Type: conf.StringSliceType,
+		},
+		set: func(val conf.Options, t *Task) error {
+			if val == nil {
+				t.SecretEnvironment = nil
+				return nil
+			}
+
+			patterns, err := val.GetRequiredStringSlice("SecretEnvironment")
+			if err != nil {
+				return err
+			}
+
+			for _, p := range patterns {
+				if _, err := path.Match(p, ""); err != nil {
+					return fmt.Errorf("invalid pattern for SecretEnvironment: %q", p)
+				}
+			}
+
+			t.SecretEnvironment = patterns
+			return nil
+		},
+		get: func(t *Task) []string {
+			return t.SecretEnvironment
+		},
+	},
+	{
+		OptionSpec: conf.OptionSpec{
+			Name: "EvaluateConditions",
+			Description: "Configures when conditions and assertions of the task and its actions are evaluated. If set to \"prepare\" " +
+				"they are evaluated before any task is executed. If set to \"execute\" they are evaluated right before the task is executed " +
+				"so they may depend on changes and results of earlier tasks. Note that actions are always set up and prepared before any task is executed " +
+				"so their options cannot depend on files or other output created by earlier tasks.",
+			Type:    conf.StringType,
+			Default: PhasePrepare,
+		},
+		set: func(val conf.Options, t *Task) error {
+			if val == nil {
+				t.ConditionPhase = ""
+				return nil
+			}
+
+			phase, err := val.GetString("EvaluateConditions")
+			if err != nil {
+				return err
+			}
+
+			phase = strings.ToLower(phase)
+			if phase != PhasePrepare && phase != PhaseExecute {
+				return fmt.Errorf("invalid value for EvaluateConditions: %q", phase)
+			}
+
+			t.ConditionPhase = phase
+			return nil
+		},
+		get: func(t *Task) []string {
+			if t.ConditionPhase == "" {
+				return nil
+			}
+			return []string{t.ConditionPhase}
+		},
+	},
 }
@@ -84,3 +84,17 @@ Key2= Value2
 		}
 	}
 }
+
+func TestDecodeEvaluateConditions(t *testing.T) {
+	tsk, err := Decode("test.task", strings.NewReader("[Task]\nEvaluateConditions=Execute\n\n[Test]\nKey=Value\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, PhaseExecute, tsk.ConditionPhase)
+	assert.Equal(t, PhaseExecute, tsk.Clone().ConditionPhase)
+
+	tsk, err = Decode("test.task", strings.NewReader("[Task]\nDescription=test\n\n[Test]\nKey=Value\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "", tsk.ConditionPhase)
+
+	_, err = Decode("test.task", strings.NewReader("[Task]\nEvaluateConditions=later\n\n[Test]\nKey=Value\n"))
+	assert.True(t, errors.Is(err, ErrInvalidTaskSection))
+}
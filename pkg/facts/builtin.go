@@ -0,0 +1,228 @@
+package facts
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ppacher/system-deploy/pkg/pkgmgr"
+	"github.com/ppacher/system-deploy/pkg/utils/osrelease"
+	"github.com/ppacher/system-deploy/pkg/utils/virt"
+)
+
+// Paths of files inspected by the builtin collectors. They are
+// only meant to be changed in tests.
+var (
+	machineIDPath     = "/etc/machine-id"
+	kernelReleasePath = "/proc/sys/kernel/osrelease"
+	kernelVersionPath = "/proc/sys/kernel/version"
+	procCPUInfo       = "/proc/cpuinfo"
+	procMeminfo       = "/proc/meminfo"
+)
+
+// fqdnTimeout is the maximum time the lookup of the fully
+// qualified domain name may take.
+var fqdnTimeout = 2 * time.Second
+
+// lookupCNAME resolves the canonical name of host. It's a
+// variable so it can be replaced in tests.
+var lookupCNAME = net.DefaultResolver.LookupCNAME
+
+// collectHost gathers the hostname, FQDN, machine-id and
+// architecture.
+func collectHost(ctx context.Context, f Facts) error {
+	f["arch"] = runtime.GOARCH
+
+	if id, err := ioutil.ReadFile(machineIDPath); err == nil {
+		f["machine_id"] = strings.TrimSpace(string(id))
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	f["hostname"] = hostname
+	f["fqdn"] = hostname
+
+	ctx, cancel := context.WithTimeout(ctx, fqdnTimeout)
+	defer cancel()
+
+	if cname, err := lookupCNAME(ctx, hostname); err == nil && cname != "" {
+		f["fqdn"] = strings.TrimSuffix(cname, ".")
+	}
+
+	return nil
+}
+
+// collectOS adds all fields of os-release(5) as os.<field>.
+func collectOS(_ context.Context, f Facts) error {
+	info, err := osrelease.Read()
+	if err != nil {
+		return err
+	}
+
+	f["os.id"] = info.ID
+	for key, value := range info.Fields {
+		f["os."+strings.ToLower(key)] = value
+	}
+
+	return nil
+}
+
+// collectKernel gathers the kernel release and version.
+func collectKernel(_ context.Context, f Facts) error {
+	release, err := ioutil.ReadFile(kernelReleasePath)
+	if err != nil {
+		return err
+	}
+	f["kernel.release"] = strings.TrimSpace(string(release))
+
+	if version, err := ioutil.ReadFile(kernelVersionPath); err == nil {
+		f["kernel.version"] = strings.TrimSpace(string(version))
+	}
+
+	return nil
+}
+
+// collectCPU gathers the number of CPUs and the CPU model.
+func collectCPU(_ context.Context, f Facts) error {
+	f["cpu.count"] = strconv.Itoa(runtime.NumCPU())
+
+	file, err := os.Open(procCPUInfo)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "model name" {
+			f["cpu.model"] = strings.TrimSpace(parts[1])
+			break
+		}
+	}
+
+	return scanner.Err()
+}
+
+// collectMemory gathers the total and available memory in
+// bytes.
+func collectMemory(_ context.Context, f Facts) error {
+	file, err := os.Open(procMeminfo)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	names := map[string]string{
+		"MemTotal:":     "memory.total",
+		"MemAvailable:": "memory.available",
+		"SwapTotal:":    "memory.swap_total",
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		name, ok := names[fields[0]]
+		if !ok {
+			continue
+		}
+
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %w", fields[0], err)
+		}
+		f[name] = strconv.FormatUint(kb*1024, 10)
+	}
+
+	return scanner.Err()
+}
+
+// collectNetwork gathers all non-loopback network interfaces
+// together with their MAC, IPv4 and IPv6 addresses.
+func collectNetwork(_ context.Context, f Facts) error {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		names = append(names, iface.Name)
+		prefix := "net." + iface.Name + "."
+
+		if len(iface.HardwareAddr) > 0 {
+			f[prefix+"mac"] = iface.HardwareAddr.String()
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		var ipv4, ipv6 []string
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+
+			if ipnet.IP.To4() != nil {
+				ipv4 = append(ipv4, ipnet.IP.String())
+			} else {
+				ipv6 = append(ipv6, ipnet.IP.String())
+			}
+		}
+
+		if len(ipv4) > 0 {
+			f[prefix+"ipv4"] = strings.Join(ipv4, ",")
+		}
+		if len(ipv6) > 0 {
+			f[prefix+"ipv6"] = strings.Join(ipv6, ",")
+		}
+	}
+
+	f["net.interfaces"] = strings.Join(names, ",")
+
+	return nil
+}
+
+// collectVirtualization gathers the kind and technology of
+// the virtualization environment.
+func collectVirtualization(_ context.Context, f Facts) error {
+	info := virt.Detect()
+
+	f["virtualization.kind"] = info.Kind
+	f["virtualization.technology"] = info.Technology
+
+	return nil
+}
+
+// collectPackageManagers gathers the names of all available
+// package managers.
+func collectPackageManagers(_ context.Context, f Facts) error {
+	var names []string
+	for _, m := range pkgmgr.Detect() {
+		names = append(names, m.Name())
+	}
+
+	f["package_managers"] = strings.Join(names, ",")
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package facts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Dir is the directory that holds executables providing
+// custom facts.
+var Dir = "/etc/system-deploy/facts.d"
+
+// customTimeout is the maximum time a custom fact executable
+// may run.
+var customTimeout = 30 * time.Second
+
+// CustomErrors is returned if one or more custom fact
+// executables failed. The facts of all other executables are
+// collected nevertheless.
+type CustomErrors []error
+
+func (errs CustomErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for idx, err := range errs {
+		msgs[idx] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// collectCustom executes all executables in Dir and adds the
+// facts they print to f. See parseCustom for the supported
+// output formats. A failing executable does not prevent the
+// remaining ones from being executed.
+func collectCustom(ctx context.Context, f Facts) error {
+	files, err := ioutil.ReadDir(Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var errs CustomErrors
+	for _, fi := range files {
+		if fi.IsDir() || fi.Mode()&0111 == 0 {
+			continue
+		}
+
+		path := filepath.Join(Dir, fi.Name())
+		custom, err := runCustom(ctx, path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("custom facts %s: %w", path, err))
+			continue
+		}
+
+		for name, value := range custom {
+			f[name] = value
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// runCustom executes path and parses its output.
+func runCustom(ctx context.Context, path string) (Facts, error) {
+	ctx, cancel := context.WithTimeout(ctx, customTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	return parseCustom(stdout.Bytes())
+}
+
+// parseCustom parses the output of a custom fact executable.
+// The output is either a JSON object or consists of lines in
+// the form of name=value. Empty lines and lines starting with
+// # are ignored. Fact names are converted to lower-case.
+func parseCustom(output []byte) (Facts, error) {
+	f := make(Facts)
+
+	trimmed := bytes.TrimSpace(output)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var obj map[string]interface{}
+		if err := json.Unmarshal(trimmed, &obj); err != nil {
+			return nil, err
+		}
+
+		for name, value := range obj {
+			if s, ok := value.(string); ok {
+				f[strings.ToLower(name)] = s
+				continue
+			}
+
+			blob, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			f[strings.ToLower(name)] = string(blob)
+		}
+
+		return f, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+
+		f[strings.ToLower(strings.TrimSpace(parts[0]))] = strings.TrimSpace(parts[1])
+	}
+
+	return f, scanner.Err()
+}
@@ -0,0 +1,114 @@
+// Package facts gathers facts about the local host like the
+// hostname, operating system, hardware or network interfaces.
+// Facts are exposed to tasks as FACT_* environment variables
+// and may be referenced using ${fact:name} during substitution.
+package facts
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EnvPrefix is the prefix of all fact environment variables.
+const EnvPrefix = "FACT_"
+
+// Facts holds host facts by name. Names are lower-case and use
+// dots to separate components (like "os.id" or "net.eth0.ipv4").
+// Multiple values of a fact are separated by commas.
+type Facts map[string]string
+
+// Names returns the names of all facts in alphabetical order.
+func (f Facts) Names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Environ returns all facts as FACT_* environment variables
+// in the form of KEY=value. See EnvName.
+func (f Facts) Environ() []string {
+	env := make([]string, 0, len(f))
+	for _, name := range f.Names() {
+		env = append(env, EnvName(name)+"="+f[name])
+	}
+	return env
+}
+
+// EnvName returns the name of the environment variable for
+// the fact name. All characters other than letters and digits
+// are replaced by underscores (like "os.id" -> "FACT_OS_ID").
+func EnvName(name string) string {
+	return EnvPrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// collector gathers a group of facts and adds them to f.
+type collector func(ctx context.Context, f Facts) error
+
+// collectors holds all builtin fact collectors.
+var collectors = []struct {
+	name string
+	fn   collector
+}{
+	{"host", collectHost},
+	{"os", collectOS},
+	{"kernel", collectKernel},
+	{"cpu", collectCPU},
+	{"memory", collectMemory},
+	{"network", collectNetwork},
+	{"virtualization", collectVirtualization},
+	{"package managers", collectPackageManagers},
+}
+
+// Gather gathers all builtin facts and custom facts from the
+// executables in Dir. Builtin facts that cannot be determined
+// are omitted. Custom facts overwrite builtin ones with the same
+// name. If a custom fact executable fails, the facts gathered so
+// far are returned together with the error.
+func Gather(ctx context.Context) (Facts, error) {
+	f := make(Facts)
+
+	for _, c := range collectors {
+		if err := c.fn(ctx, f); err != nil {
+			logrus.Debugf("facts: failed to gather %s facts: %s", c.name, err)
+		}
+	}
+
+	if err := collectCustom(ctx, f); err != nil {
+		return f, err
+	}
+
+	return f, nil
+}
+
+var (
+	loadOnce sync.Once
+	loaded   Facts
+	loadErr  error
+)
+
+// Load is like Gather but only gathers facts once and returns
+// the same result on subsequent calls. The returned facts must
+// not be modified.
+func Load(ctx context.Context) (Facts, error) {
+	loadOnce.Do(func() {
+		loaded, loadErr = Gather(ctx)
+	})
+
+	return loaded, loadErr
+}
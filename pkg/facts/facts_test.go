@@ -0,0 +1,150 @@
+package facts
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnviron(t *testing.T) {
+	assert.Equal(t, "FACT_OS_ID", EnvName("os.id"))
+	assert.Equal(t, "FACT_NET_ETH0_IPV4", EnvName("net.eth0.ipv4"))
+	assert.Equal(t, "FACT_MY_CUSTOM_FACT", EnvName("my-custom.fact"))
+
+	f := Facts{
+		"os.id":    "debian",
+		"hostname": "web1",
+	}
+	assert.Equal(t, []string{"FACT_HOSTNAME=web1", "FACT_OS_ID=debian"}, f.Environ())
+}
+
+func TestBuiltinCollectors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "facts-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	defer func(id, release, version, cpu, mem string) {
+		machineIDPath, kernelReleasePath, kernelVersionPath, procCPUInfo, procMeminfo = id, release, version, cpu, mem
+	}(machineIDPath, kernelReleasePath, kernelVersionPath, procCPUInfo, procMeminfo)
+
+	machineIDPath = write("machine-id", "0123456789abcdef\n")
+	kernelReleasePath = write("osrelease", "5.10.0-test\n")
+	kernelVersionPath = write("version", "#1 SMP Debian\n")
+	procCPUInfo = write("cpuinfo", "processor\t: 0\nmodel name\t: Test CPU @ 2.00GHz\n")
+	procMeminfo = write("meminfo", "MemTotal:       2048 kB\nMemFree:        1024 kB\nMemAvailable:   1536 kB\nSwapTotal:      0 kB\n")
+
+	defer func(fn func(context.Context, string) (string, error)) { lookupCNAME = fn }(lookupCNAME)
+	lookupCNAME = func(_ context.Context, host string) (string, error) {
+		return host + ".example.com.", nil
+	}
+
+	f := make(Facts)
+	for _, fn := range []collector{collectHost, collectKernel, collectCPU, collectMemory} {
+		require.NoError(t, fn(context.Background(), f))
+	}
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	assert.Equal(t, hostname, f["hostname"])
+	assert.Equal(t, hostname+".example.com", f["fqdn"])
+	assert.Equal(t, "0123456789abcdef", f["machine_id"])
+	assert.Equal(t, runtime.GOARCH, f["arch"])
+	assert.Equal(t, "5.10.0-test", f["kernel.release"])
+	assert.Equal(t, "#1 SMP Debian", f["kernel.version"])
+	assert.Equal(t, strconv.Itoa(runtime.NumCPU()), f["cpu.count"])
+	assert.Equal(t, "Test CPU @ 2.00GHz", f["cpu.model"])
+	assert.Equal(t, "2097152", f["memory.total"])
+	assert.Equal(t, "1572864", f["memory.available"])
+	assert.Equal(t, "0", f["memory.swap_total"])
+
+	// the hostname is used if the FQDN cannot be resolved.
+	lookupCNAME = func(context.Context, string) (string, error) {
+		return "", errors.New("no such host")
+	}
+	require.NoError(t, collectHost(context.Background(), f))
+	assert.Equal(t, hostname, f["fqdn"])
+}
+
+func TestParseCustom(t *testing.T) {
+	cases := []struct {
+		Output string
+		Facts  Facts
+		Err    bool
+	}{
+		{
+			"# comment\nRole=web\n\n datacenter = fra1 \n",
+			Facts{"role": "web", "datacenter": "fra1"},
+			false,
+		},
+		{
+			`{"Role": "db", "replicas": 3, "tags": ["a", "b"]}`,
+			Facts{"role": "db", "replicas": "3", "tags": `["a","b"]`},
+			false,
+		},
+		{"invalid line", nil, true},
+		{"{invalid json", nil, true},
+	}
+
+	for _, c := range cases {
+		f, err := parseCustom([]byte(c.Output))
+		if c.Err {
+			assert.Error(t, err, c.Output)
+			continue
+		}
+
+		if assert.NoError(t, err, c.Output) {
+			assert.Equal(t, c.Facts, f, c.Output)
+		}
+	}
+}
+
+func TestCollectCustom(t *testing.T) {
+	dir, err := ioutil.TempDir("", "facts-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(old string) { Dir = old }(Dir)
+	Dir = dir
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "10-role"), []byte("#!/bin/sh\necho role=web\necho hostname=overwritten\n"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not executable"), 0644))
+
+	f := Facts{"hostname": "web1"}
+	require.NoError(t, collectCustom(context.Background(), f))
+	assert.Equal(t, Facts{"hostname": "overwritten", "role": "web"}, f)
+
+	// failing executables must not prevent the remaining
+	// ones from being executed.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "05-broken"), []byte("#!/bin/sh\necho failed >&2\nexit 1\n"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "20-invalid"), []byte("#!/bin/sh\necho not-a-fact\n"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "30-dc"), []byte("#!/bin/sh\necho datacenter=fra1\n"), 0755))
+
+	f = Facts{}
+	err = collectCustom(context.Background(), f)
+	assert.Equal(t, Facts{"hostname": "overwritten", "role": "web", "datacenter": "fra1"}, f)
+
+	var errs CustomErrors
+	if assert.True(t, errors.As(err, &errs)) && assert.Len(t, errs, 2) {
+		assert.Contains(t, errs[0].Error(), "05-broken")
+		assert.Contains(t, errs[0].Error(), "failed")
+		assert.Contains(t, errs[1].Error(), "20-invalid")
+	}
+
+	Dir = filepath.Join(dir, "missing")
+	assert.NoError(t, collectCustom(context.Background(), f))
+}
@@ -0,0 +1,302 @@
+// Package inventory implements host inventories. An inventory
+// assigns hosts to groups and configures environment files that
+// are loaded for all hosts, for all members of a group or for
+// specific hosts only. This allows to deploy the same tasks on
+// multiple machines with different values.
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ppacher/system-conf/conf"
+)
+
+// DefaultPaths holds the locations that are searched for an
+// inventory file if none is specified explicitly.
+var DefaultPaths = []string{
+	"inventory.conf", // inside the working directory
+	"/etc/system-deploy/inventory.conf",
+}
+
+// ErrNotFound is returned by Find if no inventory file exists.
+var ErrNotFound = errors.New("no inventory found")
+
+// Inventory describes a host inventory.
+type Inventory struct {
+	// Path is the path of the inventory file.
+	Path string
+
+	// Defaults holds environment files that are loaded for
+	// all hosts.
+	Defaults []string
+
+	// Groups holds all groups in the order they are defined.
+	Groups []Group
+
+	// Hosts holds all hosts in the order they are defined.
+	Hosts []Host
+}
+
+// Group describes a group of hosts.
+type Group struct {
+	// Name is the name of the group.
+	Name string
+
+	// Hosts holds glob patterns of hostnames that are
+	// members of the group.
+	Hosts []string
+
+	// Environment holds environment files that are loaded
+	// for all members of the group.
+	Environment []string
+}
+
+// Host describes a host or a set of hosts.
+type Host struct {
+	// Name is the hostname or a glob pattern matching
+	// hostnames.
+	Name string
+
+	// Groups holds the names of additional groups the host
+	// is a member of.
+	Groups []string
+
+	// Environment holds environment files that are loaded
+	// for the host.
+	Environment []string
+}
+
+// Match describes the parts of an inventory that apply to
+// a host.
+type Match struct {
+	// Hosts holds the names of all matching [Host] sections.
+	Hosts []string
+
+	// Groups holds the names of all groups the host is a
+	// member of in the order they are defined.
+	Groups []string
+
+	// EnvironmentFiles holds all environment files that
+	// apply to the host. Files are ordered by precedence
+	// starting with the lowest: defaults, groups in the
+	// order they are defined and finally hosts.
+	EnvironmentFiles []string
+}
+
+var environmentSpec = conf.OptionSpec{
+	Name:        "Environment",
+	Description: "Environment files to load. Relative paths are resolved against the directory of the inventory.",
+	Type:        conf.StringSliceType,
+}
+
+var specs = map[string][]conf.OptionSpec{
+	"defaults": {
+		environmentSpec,
+	},
+	"group": {
+		{
+			Name:        "Name",
+			Description: "The name of the group.",
+			Type:        conf.StringType,
+			Required:    true,
+		},
+		{
+			Name:        "Hosts",
+			Description: "Glob patterns of hostnames that are members of the group.",
+			Type:        conf.StringSliceType,
+		},
+		environmentSpec,
+	},
+	"host": {
+		{
+			Name:        "Name",
+			Description: "The hostname or a glob pattern matching hostnames.",
+			Type:        conf.StringType,
+			Required:    true,
+		},
+		{
+			Name:        "Groups",
+			Description: "Additional groups the host is a member of.",
+			Type:        conf.StringSliceType,
+		},
+		environmentSpec,
+	},
+}
+
+// Find returns the first existing file in paths. It returns
+// ErrNotFound if none of them exists.
+func Find(paths []string) (string, error) {
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+// LoadFile is like Decode but reads the inventory from path.
+func LoadFile(filePath string) (*Inventory, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Decode(filePath, f)
+}
+
+// Decode decodes an inventory from r. Relative paths of
+// environment files are resolved against the directory of
+// filePath.
+func Decode(filePath string, r io.Reader) (*Inventory, error) {
+	file, err := conf.Deserialize(filePath, r)
+	if err != nil && !errors.Is(err, conf.ErrNoSections) {
+		return nil, err
+	}
+
+	inv := &Inventory{
+		Path: filePath,
+	}
+
+	if file == nil {
+		return inv, nil
+	}
+
+	if err := conf.ValidateFile(file, specs); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	dir := filepath.Dir(filePath)
+	resolve := func(files []string) []string {
+		for idx, f := range files {
+			if !filepath.IsAbs(f) {
+				files[idx] = filepath.Join(dir, f)
+			}
+		}
+		return files
+	}
+
+	groups := make(map[string]bool)
+	for _, sec := range file.Sections {
+		switch strings.ToLower(sec.Name) {
+		case "defaults":
+			inv.Defaults = append(inv.Defaults, resolve(sec.GetStringSlice("Environment"))...)
+
+		case "group":
+			name, err := sec.GetString("Name")
+			if err != nil {
+				return nil, fmt.Errorf("%s: group: %w", filePath, err)
+			}
+
+			if groups[name] {
+				return nil, fmt.Errorf("%s: duplicate group %q", filePath, name)
+			}
+			groups[name] = true
+
+			inv.Groups = append(inv.Groups, Group{
+				Name:        name,
+				Hosts:       sec.GetStringSlice("Hosts"),
+				Environment: resolve(sec.GetStringSlice("Environment")),
+			})
+
+		case "host":
+			name, err := sec.GetString("Name")
+			if err != nil {
+				return nil, fmt.Errorf("%s: host: %w", filePath, err)
+			}
+
+			inv.Hosts = append(inv.Hosts, Host{
+				Name:        name,
+				Groups:      sec.GetStringSlice("Groups"),
+				Environment: resolve(sec.GetStringSlice("Environment")),
+			})
+		}
+	}
+
+	for _, host := range inv.Hosts {
+		for _, group := range host.Groups {
+			if !groups[group] {
+				return nil, fmt.Errorf("%s: host %s: unknown group %q", filePath, host.Name, group)
+			}
+		}
+	}
+
+	var patterns []string
+	for _, group := range inv.Groups {
+		patterns = append(patterns, group.Hosts...)
+	}
+	for _, host := range inv.Hosts {
+		patterns = append(patterns, host.Name)
+	}
+
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern %q", filePath, p)
+		}
+	}
+
+	return inv, nil
+}
+
+// Match returns the parts of the inventory that apply to a
+// host known by any of names (like its hostname and its fully
+// qualified domain name). Names are matched case-insensitively.
+func (inv *Inventory) Match(names ...string) *Match {
+	m := &Match{}
+
+	memberOf := make(map[string]bool)
+	var hostFiles []string
+	for _, host := range inv.Hosts {
+		if !matchAny(host.Name, names) {
+			continue
+		}
+
+		m.Hosts = append(m.Hosts, host.Name)
+		hostFiles = append(hostFiles, host.Environment...)
+		for _, group := range host.Groups {
+			memberOf[group] = true
+		}
+	}
+
+	m.EnvironmentFiles = append(m.EnvironmentFiles, inv.Defaults...)
+
+	for _, group := range inv.Groups {
+		if !memberOf[group.Name] {
+			for _, pattern := range group.Hosts {
+				if matchAny(pattern, names) {
+					memberOf[group.Name] = true
+					break
+				}
+			}
+		}
+
+		if memberOf[group.Name] {
+			m.Groups = append(m.Groups, group.Name)
+			m.EnvironmentFiles = append(m.EnvironmentFiles, group.Environment...)
+		}
+	}
+
+	m.EnvironmentFiles = append(m.EnvironmentFiles, hostFiles...)
+
+	return m
+}
+
+// matchAny returns true if the glob pattern matches any of names.
+func matchAny(pattern string, names []string) bool {
+	pattern = strings.ToLower(pattern)
+	for _, name := range names {
+		if ok, _ := path.Match(pattern, strings.ToLower(name)); ok {
+			return true
+		}
+	}
+	return false
+}
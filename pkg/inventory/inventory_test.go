@@ -0,0 +1,155 @@
+package inventory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testInventory = `
+[Defaults]
+Environment=vars/defaults.env
+
+[Group]
+Name=webservers
+Hosts=web*
+Hosts=*.web.example.com
+Environment=vars/web.env
+
+[Group]
+Name=databases
+Hosts=db?
+Environment=/etc/vars/db.env
+
+[Group]
+Name=production
+Environment=vars/prod.env
+
+[Host]
+Name=web1
+Groups=production
+Environment=vars/web1.env
+
+[Host]
+Name=WEB*
+Environment=vars/all-web.env
+`
+
+func TestDecode(t *testing.T) {
+	inv, err := Decode("/srv/repo/inventory.conf", strings.NewReader(testInventory))
+	require.NoError(t, err)
+
+	assert.Equal(t, "/srv/repo/inventory.conf", inv.Path)
+	assert.Equal(t, []string{"/srv/repo/vars/defaults.env"}, inv.Defaults)
+	require.Len(t, inv.Groups, 3)
+	assert.Equal(t, Group{
+		Name:        "webservers",
+		Hosts:       []string{"web*", "*.web.example.com"},
+		Environment: []string{"/srv/repo/vars/web.env"},
+	}, inv.Groups[0])
+	assert.Equal(t, []string{"/etc/vars/db.env"}, inv.Groups[1].Environment)
+	require.Len(t, inv.Hosts, 2)
+	assert.Equal(t, Host{
+		Name:        "web1",
+		Groups:      []string{"production"},
+		Environment: []string{"/srv/repo/vars/web1.env"},
+	}, inv.Hosts[0])
+
+	inv, err = Decode("inventory.conf", strings.NewReader("# empty\n"))
+	require.NoError(t, err)
+	assert.Empty(t, inv.Groups)
+
+	invalid := []string{
+		"[Group]\nHosts=web*\n",
+		"[Group]\nName=a\n\n[Group]\nName=a\n",
+		"[Host]\nName=web1\nGroups=unknown\n",
+		"[Host]\nName=[\n",
+		"[Unknown]\nName=a\n",
+		"[Defaults]\nFoo=bar\n",
+	}
+	for _, content := range invalid {
+		_, err := Decode("inventory.conf", strings.NewReader(content))
+		assert.Error(t, err, content)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	inv, err := Decode("/srv/repo/inventory.conf", strings.NewReader(testInventory))
+	require.NoError(t, err)
+
+	cases := []struct {
+		Names  []string
+		Hosts  []string
+		Groups []string
+		Files  []string
+	}{
+		{
+			[]string{"web1", "web1.example.com"},
+			[]string{"web1", "WEB*"},
+			[]string{"webservers", "production"},
+			[]string{"defaults", "web", "prod", "web1", "all-web"},
+		},
+		{
+			[]string{"web2"},
+			[]string{"WEB*"},
+			[]string{"webservers"},
+			[]string{"defaults", "web", "all-web"},
+		},
+		{
+			[]string{"app", "app.web.example.com"},
+			nil,
+			[]string{"webservers"},
+			[]string{"defaults", "web"},
+		},
+		{
+			[]string{"db1"},
+			nil,
+			[]string{"databases"},
+			[]string{"defaults", "db"},
+		},
+		{
+			[]string{"mail"},
+			nil,
+			nil,
+			[]string{"defaults"},
+		},
+	}
+
+	for _, c := range cases {
+		m := inv.Match(c.Names...)
+		assert.Equal(t, c.Hosts, m.Hosts, c.Names)
+		assert.Equal(t, c.Groups, m.Groups, c.Names)
+
+		var files []string
+		for _, f := range m.EnvironmentFiles {
+			files = append(files, strings.TrimSuffix(filepath.Base(f), ".env"))
+		}
+		assert.Equal(t, c.Files, files, c.Names)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inventory-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	missing := filepath.Join(dir, "missing.conf")
+	existing := filepath.Join(dir, "inventory.conf")
+	require.NoError(t, ioutil.WriteFile(existing, []byte("[Defaults]\nEnvironment=test.env\n"), 0644))
+
+	path, err := Find([]string{missing, existing})
+	require.NoError(t, err)
+	assert.Equal(t, existing, path)
+
+	_, err = Find([]string{missing})
+	assert.Equal(t, ErrNotFound, err)
+
+	inv, err := LoadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "test.env")}, inv.Defaults)
+}
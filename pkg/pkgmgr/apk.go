@@ -0,0 +1,78 @@
+package pkgmgr
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+type apk struct{}
+
+func (*apk) Name() string { return Apk }
+
+func (*apk) Detect() bool {
+	return hasBinary("apk")
+}
+
+func (*apk) Installed(ctx context.Context) (Packages, error) {
+	output, err := run(ctx, nil, []string{"apk", "info", "-v"})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseApkInfo(output), nil
+}
+
+func (m *apk) installer() installer {
+	return installer{
+		m:       m,
+		install: []string{"apk", "add", "--no-progress"},
+		upgrade: []string{"apk", "add", "--no-progress", "--upgrade"},
+		remove:  []string{"apk", "del", "--no-progress"},
+		format: func(s Spec) string {
+			// apk does not support globs but a fuzzy
+			// version match using "~".
+			if strings.HasSuffix(s.Version, ".*") {
+				return s.Name + "~" + strings.TrimSuffix(s.Version, ".*")
+			}
+			return s.Name + "=" + s.Version
+		},
+	}
+}
+
+func (m *apk) Install(ctx context.Context, specs ...Spec) ([]Change, error) {
+	return m.installer().Install(ctx, specs...)
+}
+
+func (m *apk) Upgrade(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Upgrade(ctx, pkgs...)
+}
+
+func (m *apk) Remove(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Remove(ctx, pkgs...)
+}
+
+func (*apk) Update(ctx context.Context) error {
+	_, err := run(ctx, nil, []string{"apk", "update"})
+	return err
+}
+
+// LastUpdate implements UpdateTracker.
+func (*apk) LastUpdate() (time.Time, bool) {
+	return lastModified("/var/cache/apk")
+}
+
+// parseApkInfo parses the output of "apk info -v". Each line
+// has the format "name-version-rN" where name may contain
+// dashes as well.
+func parseApkInfo(output []byte) Packages {
+	return parseFields(output, func(fields []string) (string, string) {
+		parts := strings.Split(fields[0], "-")
+		if len(parts) < 3 {
+			return "", ""
+		}
+
+		idx := len(parts) - 2
+		return strings.Join(parts[:idx], "-"), strings.Join(parts[idx:], "-")
+	})
+}
@@ -0,0 +1,112 @@
+package pkgmgr
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// aptEnv is passed to all apt commands.
+var aptEnv = []string{"DEBIAN_FRONTEND=noninteractive"}
+
+type apt struct{}
+
+func (*apt) Name() string { return APT }
+
+func (*apt) Detect() bool {
+	return hasBinary("apt-get") && hasBinary("dpkg-query")
+}
+
+func (*apt) Installed(ctx context.Context) (Packages, error) {
+	output, err := run(ctx, nil, []string{"dpkg-query", "-W", "-f=${db:Status-Abbrev} ${binary:Package} ${Version}\n"})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDpkgQuery(output), nil
+}
+
+func (m *apt) installer() installer {
+	return installer{
+		m:   m,
+		env: aptEnv,
+		// --allow-downgrades is required for version pins
+		// that match an older version than installed.
+		install: []string{"apt-get", "install", "-y", "--allow-downgrades"},
+		remove:  []string{"apt-get", "remove", "-y"},
+		format: func(s Spec) string {
+			// apt supports glob patterns in version
+			// specifications.
+			return s.Name + "=" + s.Version
+		},
+	}
+}
+
+func (m *apt) Install(ctx context.Context, specs ...Spec) ([]Change, error) {
+	return m.installer().Install(ctx, specs...)
+}
+
+func (m *apt) Upgrade(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Upgrade(ctx, pkgs...)
+}
+
+func (m *apt) Remove(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Remove(ctx, pkgs...)
+}
+
+func (*apt) Update(ctx context.Context) error {
+	_, err := run(ctx, aptEnv, []string{"apt-get", "update"})
+	return err
+}
+
+// LastUpdate implements UpdateTracker.
+func (*apt) LastUpdate() (time.Time, bool) {
+	return lastModified(
+		"/var/lib/apt/periodic/update-success-stamp",
+		"/var/lib/apt/lists",
+	)
+}
+
+// Hold implements Holder using apt-mark.
+func (*apt) Hold(ctx context.Context, pkgs ...string) (bool, error) {
+	output, err := run(ctx, nil, []string{"apt-mark", "showhold"})
+	if err != nil {
+		return false, err
+	}
+
+	held := make(map[string]bool)
+	for _, name := range strings.Fields(string(output)) {
+		held[name] = true
+	}
+
+	var missing []string
+	for _, pkg := range pkgs {
+		if !held[pkg] {
+			missing = append(missing, pkg)
+		}
+	}
+
+	if len(missing) == 0 {
+		return false, nil
+	}
+
+	if _, err := run(ctx, nil, []string{"apt-mark", "hold"}, missing...); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// parseDpkgQuery parses the output of dpkg-query in the
+// format "${db:Status-Abbrev} ${binary:Package} ${Version}". Only
+// packages that are actually installed are returned.
+func parseDpkgQuery(output []byte) Packages {
+	return parseFields(output, func(fields []string) (string, string) {
+		// the second character of the status holds the
+		// current state of the package.
+		if len(fields) < 3 || len(fields[0]) < 2 || fields[0][1] != 'i' {
+			return "", ""
+		}
+		return fields[1], fields[2]
+	})
+}
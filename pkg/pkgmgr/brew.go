@@ -0,0 +1,62 @@
+package pkgmgr
+
+import "context"
+
+// brewEnv is passed to all brew commands.
+var brewEnv = []string{"HOMEBREW_NO_AUTO_UPDATE=1"}
+
+type brew struct{}
+
+func (*brew) Name() string { return Brew }
+
+func (*brew) Detect() bool {
+	return hasBinary("brew")
+}
+
+func (*brew) Installed(ctx context.Context) (Packages, error) {
+	output, err := run(ctx, brewEnv, []string{"brew", "list", "--versions"})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBrewList(output), nil
+}
+
+func (m *brew) installer() installer {
+	return installer{
+		m:       m,
+		env:     brewEnv,
+		install: []string{"brew", "install"},
+		upgrade: []string{"brew", "upgrade"},
+		remove:  []string{"brew", "uninstall"},
+	}
+}
+
+func (m *brew) Install(ctx context.Context, specs ...Spec) ([]Change, error) {
+	return m.installer().Install(ctx, specs...)
+}
+
+func (m *brew) Upgrade(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Upgrade(ctx, pkgs...)
+}
+
+func (m *brew) Remove(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Remove(ctx, pkgs...)
+}
+
+func (*brew) Update(ctx context.Context) error {
+	_, err := run(ctx, nil, []string{"brew", "update"})
+	return err
+}
+
+// parseBrewList parses the output of "brew list --versions".
+// If multiple versions of a package are installed, the last
+// one is used.
+func parseBrewList(output []byte) Packages {
+	return parseFields(output, func(fields []string) (string, string) {
+		if len(fields) < 2 {
+			return "", ""
+		}
+		return fields[0], fields[len(fields)-1]
+	})
+}
@@ -0,0 +1,46 @@
+package pkgmgr
+
+import "context"
+
+type flatpak struct{}
+
+func (*flatpak) Name() string { return Flatpak }
+
+func (*flatpak) Detect() bool {
+	return hasBinary("flatpak")
+}
+
+func (*flatpak) Installed(ctx context.Context) (Packages, error) {
+	output, err := run(ctx, nil, []string{"flatpak", "list", "--columns=application,active"})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNameVersion(output), nil
+}
+
+func (m *flatpak) installer() installer {
+	return installer{
+		m:       m,
+		install: []string{"flatpak", "install", "--noninteractive", "-y"},
+		upgrade: []string{"flatpak", "update", "--noninteractive", "-y"},
+		remove:  []string{"flatpak", "uninstall", "--noninteractive", "-y"},
+	}
+}
+
+func (m *flatpak) Install(ctx context.Context, specs ...Spec) ([]Change, error) {
+	return m.installer().Install(ctx, specs...)
+}
+
+func (m *flatpak) Upgrade(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Upgrade(ctx, pkgs...)
+}
+
+func (m *flatpak) Remove(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Remove(ctx, pkgs...)
+}
+
+func (*flatpak) Update(ctx context.Context) error {
+	_, err := run(ctx, nil, []string{"flatpak", "update", "--appstream", "--noninteractive", "-y"})
+	return err
+}
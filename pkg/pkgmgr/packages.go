@@ -0,0 +1,145 @@
+package pkgmgr
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// Packages maps the names of installed packages to their
+// version. Package managers that support installing a
+// package for multiple architectures or in multiple versions
+// qualify the name using a colon ("libc6:i386"). Use Lookup
+// to find packages by their plain name.
+type Packages map[string]string
+
+// Lookup returns the versions of all packages installed as
+// name, including those qualified by architecture or version,
+// sorted by their key.
+func (p Packages) Lookup(name string) []string {
+	var keys []string
+	for key := range p {
+		if key == name || strings.HasPrefix(key, name+":") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	versions := make([]string, len(keys))
+	for idx, key := range keys {
+		versions[idx] = p[key]
+	}
+	return versions
+}
+
+// Change describes a package that has been installed,
+// upgraded or removed.
+type Change struct {
+	// Name is the name of the package.
+	Name string
+
+	// From is the version installed before the change. It's
+	// empty if the package has been newly installed.
+	From string
+
+	// To is the version installed after the change. It's
+	// empty if the package has been removed.
+	To string
+}
+
+// Action returns a short description of the change. It
+// returns either "installed", "removed" or "upgraded".
+func (c Change) Action() string {
+	switch {
+	case c.From == "":
+		return "installed"
+	case c.To == "":
+		return "removed"
+	default:
+		return "upgraded"
+	}
+}
+
+func (c Change) String() string {
+	switch {
+	case c.From == "":
+		return c.Name + " (" + c.To + ")"
+	case c.To == "":
+		return c.Name + " (" + c.From + ")"
+	default:
+		return c.Name + " (" + c.From + " -> " + c.To + ")"
+	}
+}
+
+// Diff returns all changes required to get from before to
+// after sorted by package name.
+func Diff(before, after Packages) []Change {
+	var changes []Change
+
+	for name, version := range after {
+		if old, ok := before[name]; !ok || old != version {
+			changes = append(changes, Change{
+				Name: name,
+				From: old,
+				To:   version,
+			})
+		}
+	}
+
+	for name, version := range before {
+		if _, ok := after[name]; !ok {
+			changes = append(changes, Change{
+				Name: name,
+				From: version,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes
+}
+
+// Names returns the names of all packages in changes.
+func Names(changes []Change) []string {
+	names := make([]string, len(changes))
+	for idx, c := range changes {
+		names[idx] = c.Name
+	}
+	return names
+}
+
+// parseFields parses output line by line and calls fn with
+// the whitespace separated fields of each non-empty line.
+// If fn returns an empty name the line is ignored.
+func parseFields(output []byte, fn func(fields []string) (string, string)) Packages {
+	pkgs := make(Packages)
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if name, version := fn(fields); name != "" {
+			pkgs[name] = version
+		}
+	}
+
+	return pkgs
+}
+
+// parseNameVersion parses output consisting of lines in
+// the format "name version".
+func parseNameVersion(output []byte) Packages {
+	return parseFields(output, func(fields []string) (string, string) {
+		if len(fields) < 2 {
+			return "", ""
+		}
+		return fields[0], fields[1]
+	})
+}
@@ -0,0 +1,151 @@
+package pkgmgr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ppacher/system-deploy/pkg/utils"
+)
+
+// pacmanConf is the path to the pacman configuration file.
+var pacmanConf = "/etc/pacman.conf"
+
+type pacman struct{}
+
+func (*pacman) Name() string { return Pacman }
+
+func (*pacman) Detect() bool {
+	return hasBinary("pacman")
+}
+
+func (*pacman) Installed(ctx context.Context) (Packages, error) {
+	output, err := run(ctx, nil, []string{"pacman", "-Q"})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNameVersion(output), nil
+}
+
+func (m *pacman) installer() installer {
+	return installer{
+		m:       m,
+		install: []string{"pacman", "-S", "--needed", "--quiet", "--noconfirm"},
+		remove:  []string{"pacman", "-R", "--noconfirm"},
+	}
+}
+
+func (m *pacman) Install(ctx context.Context, specs ...Spec) ([]Change, error) {
+	return m.installer().Install(ctx, specs...)
+}
+
+func (m *pacman) Upgrade(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Upgrade(ctx, pkgs...)
+}
+
+func (m *pacman) Remove(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Remove(ctx, pkgs...)
+}
+
+// Update refreshes the package databases and upgrades all
+// installed packages. Arch Linux does not support partial
+// upgrades so installing packages from a refreshed database
+// without upgrading the system might break it.
+func (*pacman) Update(ctx context.Context) error {
+	_, err := run(ctx, nil, []string{"pacman", "-Syu", "--noconfirm"})
+	return err
+}
+
+// LastUpdate implements UpdateTracker.
+func (*pacman) LastUpdate() (time.Time, bool) {
+	return lastModified("/var/lib/pacman/sync")
+}
+
+// Hold implements Holder by adding pkgs to the IgnorePkg
+// option in pacman.conf.
+func (*pacman) Hold(ctx context.Context, pkgs ...string) (bool, error) {
+	content, err := ioutil.ReadFile(pacmanConf)
+	if err != nil {
+		return false, err
+	}
+
+	updated, changed := addIgnorePkg(content, pkgs)
+	if !changed {
+		return false, nil
+	}
+
+	mode, err := utils.FileMode(pacmanConf)
+	if err != nil {
+		return false, err
+	}
+
+	if err := utils.CreateAtomic(pacmanConf, mode, bytes.NewReader(updated)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// addIgnorePkg adds all pkgs that are not yet ignored to the
+// IgnorePkg option of the [options] section in content. It
+// returns the updated content and true if something changed.
+func addIgnorePkg(content []byte, pkgs []string) ([]byte, bool) {
+	var (
+		lines     []string
+		inOptions bool
+		optionIdx = -1
+		ignoreIdx = -1
+		ignored   = make(map[string]bool)
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "[") {
+			inOptions = trimmed == "[options]"
+			if inOptions {
+				optionIdx = len(lines)
+			}
+		} else if inOptions && strings.HasPrefix(trimmed, "IgnorePkg") {
+			parts := strings.SplitN(trimmed, "=", 2)
+			if len(parts) == 2 && strings.TrimSpace(parts[0]) == "IgnorePkg" {
+				for _, name := range strings.Fields(parts[1]) {
+					ignored[name] = true
+				}
+				ignoreIdx = len(lines)
+			}
+		}
+
+		lines = append(lines, line)
+	}
+
+	var missing []string
+	for _, pkg := range pkgs {
+		if !ignored[pkg] {
+			missing = append(missing, pkg)
+			ignored[pkg] = true
+		}
+	}
+
+	if len(missing) == 0 {
+		return content, false
+	}
+
+	switch {
+	case ignoreIdx >= 0:
+		lines[ignoreIdx] += " " + strings.Join(missing, " ")
+	case optionIdx >= 0:
+		line := "IgnorePkg = " + strings.Join(missing, " ")
+		lines = append(lines[:optionIdx+1], append([]string{line}, lines[optionIdx+1:]...)...)
+	default:
+		lines = append(lines, "[options]", "IgnorePkg = "+strings.Join(missing, " "))
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n"), true
+}
@@ -0,0 +1,340 @@
+package pkgmgr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Known package manager names.
+const (
+	APT     = "apt"
+	Pacman  = "pacman"
+	Dnf     = "dnf"
+	Zypper  = "zypper"
+	Apk     = "apk"
+	Snap    = "snap"
+	Flatpak = "flatpak"
+	Brew    = "brew"
+)
+
+// ErrNotSupported is returned if a package manager does
+// not support an operation.
+var ErrNotSupported = errors.New("not supported")
+
+// Manager describes a package manager.
+type Manager interface {
+	// Name returns the name of the package manager.
+	Name() string
+
+	// Detect returns true if the package manager is
+	// available on the system.
+	Detect() bool
+
+	// Installed returns all packages that are currently
+	// installed.
+	Installed(ctx context.Context) (Packages, error)
+
+	// Install installs all packages in specs that are
+	// either missing or don't match the requested version.
+	// It returns the list of packages that have been
+	// installed or upgraded. This may include dependencies
+	// of specs.
+	Install(ctx context.Context, specs ...Spec) ([]Change, error)
+
+	// Upgrade installs or upgrades all pkgs to the latest
+	// available version and returns the list of packages that
+	// have been installed or upgraded.
+	Upgrade(ctx context.Context, pkgs ...string) ([]Change, error)
+
+	// Remove removes all pkgs and returns the list of
+	// packages that have been removed.
+	Remove(ctx context.Context, pkgs ...string) ([]Change, error)
+
+	// Update updates the package cache or database of
+	// the package manager.
+	Update(ctx context.Context) error
+}
+
+// Holder is implemented by package managers that support
+// holding packages back from upgrades.
+type Holder interface {
+	// Hold marks all pkgs as held and returns true if at
+	// least one package was not held before.
+	Hold(ctx context.Context, pkgs ...string) (bool, error)
+}
+
+// UpdateTracker is implemented by package managers that
+// know when their package cache has been updated the last
+// time.
+type UpdateTracker interface {
+	// LastUpdate returns the time of the last cache
+	// update. It returns false if it's unknown.
+	LastUpdate() (time.Time, bool)
+}
+
+// managers holds all supported package managers in the
+// order they are detected.
+var managers = []Manager{
+	&apt{},
+	&pacman{},
+	&dnf{},
+	&zypper{},
+	&apk{},
+	&snap{},
+	&flatpak{},
+	&brew{},
+}
+
+// All returns all supported package managers.
+func All() []Manager {
+	result := make([]Manager, len(managers))
+	copy(result, managers)
+	return result
+}
+
+// Get returns the package manager called name.
+func Get(name string) (Manager, bool) {
+	for _, m := range managers {
+		if strings.EqualFold(m.Name(), name) {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// Detect returns all package managers that are
+// available on the system.
+func Detect() []Manager {
+	var result []Manager
+	for _, m := range managers {
+		if m.Detect() {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// DetectNames is like Detect but returns the names of the
+// package managers.
+func DetectNames() []string {
+	var names []string
+	for _, m := range Detect() {
+		names = append(names, m.Name())
+	}
+	return names
+}
+
+// IsAvailable returns true if the package manager name
+// is available on the system.
+func IsAvailable(name string) bool {
+	m, ok := Get(name)
+	if !ok {
+		return false
+	}
+	return m.Detect()
+}
+
+// CacheAge returns the age of the package cache of m. It
+// returns false if m does not know when the cache has been
+// updated.
+func CacheAge(m Manager) (time.Duration, bool) {
+	tracker, ok := m.(UpdateTracker)
+	if !ok {
+		return 0, false
+	}
+
+	t, ok := tracker.LastUpdate()
+	if !ok {
+		return 0, false
+	}
+
+	return time.Since(t), true
+}
+
+// hasBinary returns true if name can be found in $PATH.
+func hasBinary(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// lastModified returns the modification time of the first
+// path that exists.
+func lastModified(paths ...string) (time.Time, bool) {
+	for _, p := range paths {
+		stat, err := os.Stat(p)
+		if err == nil {
+			return stat.ModTime(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// run executes the command cmd with args and returns the
+// standard output. The command is executed with LC_ALL=C to
+// get predictable output. Any error returned contains the
+// complete output of the command.
+func run(ctx context.Context, env []string, cmd []string, args ...string) ([]byte, error) {
+	args = append(append([]string{}, cmd[1:]...), args...)
+
+	c := exec.CommandContext(ctx, cmd[0], args...)
+	c.Env = os.Environ()
+	c.Env = append(c.Env, "LC_ALL=C")
+	c.Env = append(c.Env, env...)
+
+	var stdout, combined bytes.Buffer
+	c.Stdout = io.MultiWriter(&stdout, &combined)
+	c.Stderr = &combined
+
+	if err := c.Run(); err != nil {
+		return stdout.Bytes(), fmt.Errorf("%s %s: %w\n%s", cmd[0], strings.Join(args, " "), err, combined.String())
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// filter returns all pkgs whose installation state in
+// installed matches want.
+func filter(pkgs []string, installed Packages, want bool) []string {
+	var result []string
+	for _, pkg := range pkgs {
+		if ok := len(installed.Lookup(pkg)) > 0; ok == want {
+			result = append(result, pkg)
+		}
+	}
+	return result
+}
+
+// matchesAny returns true if one of versions matches spec.
+func matchesAny(spec Spec, versions []string) bool {
+	for _, version := range versions {
+		if spec.Matches(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// track executes fn and returns the changes performed by fn
+// to the set of installed packages in before.
+func track(ctx context.Context, m Manager, before Packages, fn func() error) ([]Change, error) {
+	if err := fn(); err != nil {
+		return nil, err
+	}
+
+	after, err := m.Installed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return Diff(before, after), nil
+}
+
+// formatFunc formats a pinned package specification as
+// expected by the package manager.
+type formatFunc func(Spec) string
+
+// installer implements Install, Upgrade and Remove for a
+// package manager using the configured commands.
+type installer struct {
+	m   Manager
+	env []string
+
+	// install is used to install packages.
+	install []string
+
+	// upgrade is used to upgrade installed packages. If
+	// nil, install is used for all packages.
+	upgrade []string
+
+	// remove is used to remove packages.
+	remove []string
+
+	// format formats pinned packages. If nil, version pins
+	// are not supported.
+	format formatFunc
+}
+
+func (i installer) Install(ctx context.Context, specs ...Spec) ([]Change, error) {
+	before, err := i.m.Installed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	for _, spec := range specs {
+		if spec.IsPinned() && i.format == nil {
+			return nil, fmt.Errorf("%s: version pins are %w", i.m.Name(), ErrNotSupported)
+		}
+
+		if matchesAny(spec, before.Lookup(spec.Name)) {
+			continue
+		}
+
+		if spec.IsPinned() {
+			args = append(args, i.format(spec))
+		} else {
+			args = append(args, spec.Name)
+		}
+	}
+
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	return track(ctx, i.m, before, func() error {
+		_, err := run(ctx, i.env, i.install, args...)
+		return err
+	})
+}
+
+func (i installer) Upgrade(ctx context.Context, pkgs ...string) ([]Change, error) {
+	before, err := i.m.Installed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return track(ctx, i.m, before, func() error {
+		if i.upgrade == nil {
+			_, err := run(ctx, i.env, i.install, pkgs...)
+			return err
+		}
+
+		if installed := filter(pkgs, before, true); len(installed) > 0 {
+			if _, err := run(ctx, i.env, i.upgrade, installed...); err != nil {
+				return err
+			}
+		}
+
+		if missing := filter(pkgs, before, false); len(missing) > 0 {
+			if _, err := run(ctx, i.env, i.install, missing...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (i installer) Remove(ctx context.Context, pkgs ...string) ([]Change, error) {
+	before, err := i.m.Installed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs = filter(pkgs, before, true)
+	if len(pkgs) == 0 {
+		return nil, nil
+	}
+
+	return track(ctx, i.m, before, func() error {
+		_, err := run(ctx, i.env, i.remove, pkgs...)
+		return err
+	})
+}
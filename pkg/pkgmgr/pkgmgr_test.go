@@ -0,0 +1,144 @@
+package pkgmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	for _, name := range []string{APT, Pacman, Dnf, Zypper, Apk, Snap, Flatpak, Brew} {
+		m, ok := Get(name)
+		if assert.True(t, ok, name) {
+			assert.Equal(t, name, m.Name())
+		}
+	}
+
+	m, ok := Get("APT")
+	assert.True(t, ok)
+	assert.Equal(t, APT, m.Name())
+
+	_, ok = Get("nuget")
+	assert.False(t, ok)
+	assert.False(t, IsAvailable("nuget"))
+}
+
+func TestDiff(t *testing.T) {
+	before := Packages{
+		"nginx":   "1.18.0",
+		"curl":    "7.68.0",
+		"removed": "1.0",
+	}
+	after := Packages{
+		"nginx":   "1.20.1",
+		"curl":    "7.68.0",
+		"libfoo1": "2.1",
+	}
+
+	changes := Diff(before, after)
+	assert.Equal(t, []Change{
+		{Name: "libfoo1", To: "2.1"},
+		{Name: "nginx", From: "1.18.0", To: "1.20.1"},
+		{Name: "removed", From: "1.0"},
+	}, changes)
+
+	assert.Equal(t, "installed", changes[0].Action())
+	assert.Equal(t, "upgraded", changes[1].Action())
+	assert.Equal(t, "removed", changes[2].Action())
+	assert.Equal(t, "nginx (1.18.0 -> 1.20.1)", changes[1].String())
+	assert.Equal(t, []string{"libfoo1", "nginx", "removed"}, Names(changes))
+
+	assert.Empty(t, Diff(before, before))
+}
+
+func TestParseInstalled(t *testing.T) {
+	dpkg := []byte("ii  nginx 1.18.0-6ubuntu14\nrc  oldpkg 0.1\nhi  held 2.0\nii  libc6:amd64 2.31-0ubuntu9\nii  libc6:i386 2.31-0ubuntu9\n")
+	assert.Equal(t, Packages{
+		"nginx":       "1.18.0-6ubuntu14",
+		"held":        "2.0",
+		"libc6:amd64": "2.31-0ubuntu9",
+		"libc6:i386":  "2.31-0ubuntu9",
+	}, parseDpkgQuery(dpkg))
+
+	rpm := []byte("kernel x86_64 5.14.0-70.el9\nkernel x86_64 5.14.0-162.el9\nglibc x86_64 2.34-40.el9\nglibc i686 2.34-40.el9\nperl-IO x86_64 1:1.43-479.el9\ngpg-pubkey (none) fd431d51-4ae0493b\n")
+	assert.Equal(t, Packages{
+		"kernel:x86_64:5.14.0-70.el9":  "5.14.0-70.el9",
+		"kernel:x86_64:5.14.0-162.el9": "5.14.0-162.el9",
+		"glibc:x86_64":                 "2.34-40.el9",
+		"glibc:i686":                   "2.34-40.el9",
+		"perl-IO:x86_64":               "1:1.43-479.el9",
+		"gpg-pubkey":                   "fd431d51-4ae0493b",
+	}, parseRpmQuery(rpm))
+
+	pacman := []byte("linux 5.15.7.arch1-1\nnginx 1.20.2-1\n")
+	assert.Equal(t, Packages{
+		"linux": "5.15.7.arch1-1",
+		"nginx": "1.20.2-1",
+	}, parseNameVersion(pacman))
+
+	apk := []byte("musl-1.2.2-r7\nca-certificates-bundle-20211220-r0\n")
+	assert.Equal(t, Packages{
+		"musl":                   "1.2.2-r7",
+		"ca-certificates-bundle": "20211220-r0",
+	}, parseApkInfo(apk))
+
+	snap := []byte("Name  Version  Rev  Tracking  Publisher  Notes\ncore  16-2.52  11993  latest/stable  canonical  core\n")
+	assert.Equal(t, Packages{
+		"core": "16-2.52",
+	}, parseSnapList(snap))
+
+	brew := []byte("go 1.16.5 1.17.3\nwget 1.21.2\n")
+	assert.Equal(t, Packages{
+		"go":   "1.17.3",
+		"wget": "1.21.2",
+	}, parseBrewList(brew))
+}
+
+func TestLookup(t *testing.T) {
+	installed := Packages{
+		"libc6:amd64": "2.31-1",
+		"libc6:i386":  "2.31-2",
+		"libc6-dev":   "2.31-3",
+		"nginx":       "1.20.1",
+	}
+
+	assert.Equal(t, []string{"2.31-1", "2.31-2"}, installed.Lookup("libc6"))
+	assert.Equal(t, []string{"2.31-2"}, installed.Lookup("libc6:i386"))
+	assert.Equal(t, []string{"1.20.1"}, installed.Lookup("nginx"))
+	assert.Empty(t, installed.Lookup("libc"))
+	assert.Empty(t, installed.Lookup("missing"))
+}
+
+func TestSpec(t *testing.T) {
+	spec := ParseSpec("nginx=1.24.*")
+	assert.Equal(t, Spec{Name: "nginx", Version: "1.24.*"}, spec)
+	assert.True(t, spec.IsPinned())
+	assert.Equal(t, "nginx=1.24.*", spec.String())
+
+	assert.True(t, spec.Matches("1.24.0-1ubuntu1"))
+	assert.True(t, spec.Matches("1:1.24.0"))
+	assert.False(t, spec.Matches("1.22.1"))
+
+	spec = ParseSpec("curl")
+	assert.False(t, spec.IsPinned())
+	assert.True(t, spec.Matches("7.68.0"))
+
+	spec = ParseSpec("openssl=1:3.*")
+	assert.True(t, spec.Matches("1:3.0.2"))
+	assert.False(t, spec.Matches("3.0.2"))
+}
+
+func TestAddIgnorePkg(t *testing.T) {
+	conf := "[options]\n#IgnorePkg   =\nIgnorePkg = linux\nArchitecture = auto\n\n[core]\nInclude = /etc/pacman.d/mirrorlist\n"
+
+	updated, changed := addIgnorePkg([]byte(conf), []string{"linux", "nginx"})
+	assert.True(t, changed)
+	assert.Equal(t, "[options]\n#IgnorePkg   =\nIgnorePkg = linux nginx\nArchitecture = auto\n\n[core]\nInclude = /etc/pacman.d/mirrorlist\n", string(updated))
+
+	_, changed = addIgnorePkg(updated, []string{"nginx"})
+	assert.False(t, changed)
+
+	updated, changed = addIgnorePkg([]byte("[options]\nArchitecture = auto\n"), []string{"nginx"})
+	assert.True(t, changed)
+	assert.Equal(t, "[options]\nIgnorePkg = nginx\nArchitecture = auto\n", string(updated))
+}
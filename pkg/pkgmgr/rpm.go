@@ -0,0 +1,161 @@
+package pkgmgr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"time"
+)
+
+// rpmQueryFormat is used to query the RPM database. The
+// epoch is only printed if set.
+const rpmQueryFormat = "%{NAME} %{ARCH} %|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\n"
+
+// rpmInstalled returns all packages installed in the
+// RPM database.
+func rpmInstalled(ctx context.Context) (Packages, error) {
+	output, err := run(ctx, nil, []string{"rpm", "-qa", "--queryformat", rpmQueryFormat})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRpmQuery(output), nil
+}
+
+// parseRpmQuery parses the output of rpm -qa using
+// rpmQueryFormat. Packages are keyed by name and
+// architecture ("glibc:i686"). Packages that are installed
+// in multiple versions, like kernels, are additionally
+// qualified by their version ("kernel:x86_64:5.14.0-70.el9").
+func parseRpmQuery(output []byte) Packages {
+	type entry struct {
+		key     string
+		version string
+	}
+
+	var entries []entry
+	count := make(map[string]int)
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+
+		key := fields[0]
+		// packages like gpg-pubkey do not have an
+		// architecture.
+		if arch := fields[1]; arch != "(none)" {
+			key += ":" + arch
+		}
+
+		entries = append(entries, entry{key, fields[2]})
+		count[key]++
+	}
+
+	pkgs := make(Packages, len(entries))
+	for _, e := range entries {
+		if count[e.key] > 1 {
+			pkgs[e.key+":"+e.version] = e.version
+		} else {
+			pkgs[e.key] = e.version
+		}
+	}
+
+	return pkgs
+}
+
+type dnf struct{}
+
+func (*dnf) Name() string { return Dnf }
+
+func (*dnf) Detect() bool {
+	return hasBinary("dnf") && hasBinary("rpm")
+}
+
+func (*dnf) Installed(ctx context.Context) (Packages, error) {
+	return rpmInstalled(ctx)
+}
+
+func (m *dnf) installer() installer {
+	return installer{
+		m:       m,
+		install: []string{"dnf", "install", "-y"},
+		upgrade: []string{"dnf", "upgrade", "-y"},
+		remove:  []string{"dnf", "remove", "-y"},
+		format: func(s Spec) string {
+			// dnf accepts globs in package specifications.
+			return s.Name + "-" + s.Version
+		},
+	}
+}
+
+func (m *dnf) Install(ctx context.Context, specs ...Spec) ([]Change, error) {
+	return m.installer().Install(ctx, specs...)
+}
+
+func (m *dnf) Upgrade(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Upgrade(ctx, pkgs...)
+}
+
+func (m *dnf) Remove(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Remove(ctx, pkgs...)
+}
+
+func (*dnf) Update(ctx context.Context) error {
+	_, err := run(ctx, nil, []string{"dnf", "makecache", "-y"})
+	return err
+}
+
+// LastUpdate implements UpdateTracker.
+func (*dnf) LastUpdate() (time.Time, bool) {
+	return lastModified("/var/cache/dnf/last_makecache")
+}
+
+type zypper struct{}
+
+func (*zypper) Name() string { return Zypper }
+
+func (*zypper) Detect() bool {
+	return hasBinary("zypper") && hasBinary("rpm")
+}
+
+func (*zypper) Installed(ctx context.Context) (Packages, error) {
+	return rpmInstalled(ctx)
+}
+
+func (m *zypper) installer() installer {
+	return installer{
+		m:       m,
+		install: []string{"zypper", "--non-interactive", "install"},
+		upgrade: []string{"zypper", "--non-interactive", "update"},
+		remove:  []string{"zypper", "--non-interactive", "remove"},
+		format: func(s Spec) string {
+			return s.Name + "=" + s.Version
+		},
+	}
+}
+
+func (m *zypper) Install(ctx context.Context, specs ...Spec) ([]Change, error) {
+	return m.installer().Install(ctx, specs...)
+}
+
+func (m *zypper) Upgrade(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Upgrade(ctx, pkgs...)
+}
+
+func (m *zypper) Remove(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Remove(ctx, pkgs...)
+}
+
+func (*zypper) Update(ctx context.Context) error {
+	_, err := run(ctx, nil, []string{"zypper", "--non-interactive", "refresh"})
+	return err
+}
+
+// LastUpdate implements UpdateTracker.
+func (*zypper) LastUpdate() (time.Time, bool) {
+	return lastModified("/var/cache/zypp/raw")
+}
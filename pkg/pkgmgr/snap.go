@@ -0,0 +1,55 @@
+package pkgmgr
+
+import "context"
+
+type snap struct{}
+
+func (*snap) Name() string { return Snap }
+
+func (*snap) Detect() bool {
+	return hasBinary("snap")
+}
+
+func (*snap) Installed(ctx context.Context) (Packages, error) {
+	output, err := run(ctx, nil, []string{"snap", "list"})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSnapList(output), nil
+}
+
+func (m *snap) installer() installer {
+	return installer{
+		m:       m,
+		install: []string{"snap", "install"},
+		upgrade: []string{"snap", "refresh"},
+		remove:  []string{"snap", "remove"},
+	}
+}
+
+func (m *snap) Install(ctx context.Context, specs ...Spec) ([]Change, error) {
+	return m.installer().Install(ctx, specs...)
+}
+
+func (m *snap) Upgrade(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Upgrade(ctx, pkgs...)
+}
+
+func (m *snap) Remove(ctx context.Context, pkgs ...string) ([]Change, error) {
+	return m.installer().Remove(ctx, pkgs...)
+}
+
+// Update is a no-op for snap as snapd keeps its
+// metadata up-to-date on its own.
+func (*snap) Update(ctx context.Context) error {
+	return nil
+}
+
+// parseSnapList parses the output of "snap list". The
+// first line holds the column headers.
+func parseSnapList(output []byte) Packages {
+	pkgs := parseNameVersion(output)
+	delete(pkgs, "Name")
+	return pkgs
+}
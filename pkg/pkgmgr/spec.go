@@ -0,0 +1,70 @@
+package pkgmgr
+
+import (
+	"path"
+	"strings"
+)
+
+// Spec describes a package that should be installed. If
+// Version is set, it's a glob pattern (like "1.24.*") the
+// installed version must match.
+type Spec struct {
+	// Name is the name of the package.
+	Name string
+
+	// Version is an optional version pattern.
+	Version string
+}
+
+// ParseSpec parses a package specification in the format
+// "name" or "name=version".
+func ParseSpec(s string) Spec {
+	parts := strings.SplitN(s, "=", 2)
+
+	spec := Spec{Name: parts[0]}
+	if len(parts) == 2 {
+		spec.Version = parts[1]
+	}
+
+	return spec
+}
+
+// Specs is like ParseSpec but for multiple package
+// specifications.
+func Specs(values ...string) []Spec {
+	specs := make([]Spec, len(values))
+	for idx, v := range values {
+		specs[idx] = ParseSpec(v)
+	}
+	return specs
+}
+
+// IsPinned returns true if s requires a specific version.
+func (s Spec) IsPinned() bool {
+	return s.Version != ""
+}
+
+// Matches returns true if version satisfies s. If the
+// version pattern of s does not contain an epoch, the epoch
+// of version is ignored.
+func (s Spec) Matches(version string) bool {
+	if !s.IsPinned() {
+		return true
+	}
+
+	if !strings.Contains(s.Version, ":") {
+		if idx := strings.Index(version, ":"); idx >= 0 {
+			version = version[idx+1:]
+		}
+	}
+
+	matched, err := path.Match(s.Version, version)
+	return err == nil && matched
+}
+
+func (s Spec) String() string {
+	if !s.IsPinned() {
+		return s.Name
+	}
+	return s.Name + "=" + s.Version
+}
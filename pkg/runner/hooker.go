@@ -13,6 +13,7 @@ type Hooker struct {
 	l         sync.RWMutex
 	preHooks  map[string][]actions.BeforeTaskFunc
 	postHooks map[string][]actions.AfterTaskFunc
+	postRun   []actions.PostRunFunc
 }
 
 // NewHooker creates and returns a new hooker.
@@ -73,3 +74,22 @@ func (h *Hooker) ExecuteAfter(ctx context.Context, task string, actionPerformed
 		fn(ctx, task, actionPerformed, errResult)
 	}
 }
+
+// AddPostRun registeres fn to be executed after all tasks.
+func (h *Hooker) AddPostRun(fn actions.PostRunFunc) {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	h.postRun = append(h.postRun, fn)
+}
+
+// ExecutePostRun executes all PostRunFunc in the order they
+// have been registered.
+func (h *Hooker) ExecutePostRun(ctx context.Context, success bool) {
+	h.l.RLock()
+	defer h.l.RUnlock()
+
+	for _, fn := range h.postRun {
+		fn(ctx, success)
+	}
+}
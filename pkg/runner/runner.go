@@ -3,21 +3,59 @@ package runner
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/condition"
 	"github.com/ppacher/system-deploy/pkg/deploy"
 )
 
 // Runner executes a set of targets in order and aborts
-// on the first error.
+// on the first error unless KeepGoing is set.
 type Runner struct {
 	*TaskManager
 	*Hooker
 
+	// KeepGoing configures the runner to continue with the
+	// remaining tasks if a task fails. Failed tasks are
+	// reported by Deploy as TaskErrors once all tasks have
+	// been executed.
+	KeepGoing bool
+
 	l actions.Logger
 }
 
+// TaskError describes the failure of a single task.
+type TaskError struct {
+	// Task is the name of the failed task.
+	Task string
+
+	// Err is the error returned by the task.
+	Err error
+}
+
+func (err TaskError) Error() string {
+	return fmt.Sprintf("target %s: %s", err.Task, err.Err)
+}
+
+func (err TaskError) Unwrap() error {
+	return err.Err
+}
+
+// TaskErrors is returned by Deploy if KeepGoing is set
+// and one or more tasks failed.
+type TaskErrors []TaskError
+
+func (errs TaskErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for idx, err := range errs {
+		msgs[idx] = err.Error()
+	}
+
+	return fmt.Sprintf("%d task(s) failed: %s", len(errs), strings.Join(msgs, "; "))
+}
+
 // NewRunner creates a new runner for the given targets.
 func NewRunner(l actions.Logger, targets []deploy.Task) (*Runner, error) {
 	r := &Runner{
@@ -36,8 +74,23 @@ func NewRunner(l actions.Logger, targets []deploy.Task) (*Runner, error) {
 }
 
 // Deploy runs all deploy targets and aborts and returns
-// the first error encountered.
+// the first error encountered. If KeepGoing is set, the
+// remaining tasks are executed after a failure and all
+// failures are returned as TaskErrors. Post-run functions
+// are executed once all tasks have been executed or the
+// first task failed.
 func (r *Runner) Deploy(ctx context.Context) error {
+	err := r.deploy(ctx)
+
+	r.ExecutePostRun(ctx, err == nil)
+
+	return err
+}
+
+func (r *Runner) deploy(ctx context.Context) error {
+	// conditions may depend on the results of other tasks.
+	ctx = condition.WithTaskResults(ctx, r.TaskManager)
+
 	iter := &taskIter{
 		tm: r.TaskManager,
 	}
@@ -45,7 +98,7 @@ func (r *Runner) Deploy(ctx context.Context) error {
 	r.inPrepare.Set()
 	for iter.Next() {
 		r.l.Debugf("Preparing task %q", iter.Name())
-		if err := iter.Task().Prepare(r); err != nil {
+		if err := iter.Task().Prepare(ctx, r); err != nil {
 			return fmt.Errorf("failed to perpare target %s: %w", iter.Name(), err)
 		}
 	}
@@ -58,10 +111,31 @@ func (r *Runner) Deploy(ctx context.Context) error {
 	r.inExec.Set()
 	defer r.inExec.UnSet()
 
+	var failed TaskErrors
+	fail := func(name string, err error) error {
+		r.l.Warnf("%s: %s", color.New(color.BgRed, color.FgWhite).Sprint("FAIL"), err.Error())
+		if !r.KeepGoing {
+			return err
+		}
+
+		failed = append(failed, TaskError{Task: name, Err: err})
+		return nil
+	}
+
 	for iter.Next() {
 		task := iter.Task()
 		name := iter.Name()
 
+		if task.deferConditions() && !iter.IsDisabled() && !iter.IsMasked() {
+			if err := task.EvaluateConditions(ctx); err != nil {
+				r.setTaskResult(task, false, err)
+				if err := fail(name, fmt.Errorf("target %s: %w", name, err)); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
 		if iter.IsDisabled() {
 			r.l.Infof("%s: %s", bold.Sprintf("%-30v", name), color.New(color.FgYellow).Sprint("disabled"))
 			continue
@@ -72,19 +146,26 @@ func (r *Runner) Deploy(ctx context.Context) error {
 			continue
 		}
 
-		taskContext, err := r.ExecuteBefore(ctx, name)
+		taskContext, err := r.ExecuteBefore(actions.WithChanges(ctx), name)
 		if err != nil {
-			return err
+			r.setTaskResult(task, false, err)
+			if err := fail(name, err); err != nil {
+				return err
+			}
+			continue
 		}
 
 		r.l.Debugf("Starting task %s", bold.Sprint(name))
 		res, err := task.Execute(taskContext, r.l)
 
+		r.setTaskResult(task, res, err)
 		r.ExecuteAfter(taskContext, name, res, err)
 
 		if err != nil {
-			r.l.Warnf("%s: %s", color.New(color.BgRed, color.FgWhite).Sprint("FAIL"), err.Error())
-			return err
+			if err := fail(name, err); err != nil {
+				return err
+			}
+			continue
 		}
 		resStr := "pristine"
 
@@ -94,5 +175,9 @@ func (r *Runner) Deploy(ctx context.Context) error {
 		r.l.Infof("%s: %s", bold.Sprintf("%-30v", name), resStr)
 	}
 
+	if len(failed) > 0 {
+		return failed
+	}
+
 	return nil
 }
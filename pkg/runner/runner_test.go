@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ppacher/system-conf/conf"
+	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/deploy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// events records the prepare and execute calls of all
+// recordAction instances.
+var events []string
+
+func init() {
+	deploy.RegisterAllConditions()
+
+	actions.MustRegister(actions.Plugin{
+		Name: "Record",
+		Options: []conf.OptionSpec{
+			{Name: "Fail", Type: conf.BoolType},
+		},
+		Setup: func(task deploy.Task, sec conf.Section) (actions.Action, error) {
+			fail, _ := sec.GetBool("Fail")
+			return &recordAction{task: task.FileName, fail: fail}, nil
+		},
+	})
+}
+
+type recordAction struct {
+	actions.Base
+
+	task string
+	fail bool
+}
+
+func (a *recordAction) Name() string { return "Record" }
+
+func (a *recordAction) Prepare(actions.ExecGraph) error {
+	events = append(events, "prepare "+a.task)
+	return nil
+}
+
+func (a *recordAction) Execute(context.Context) (bool, error) {
+	events = append(events, "execute "+a.task)
+	if a.fail {
+		return false, errors.New("failed")
+	}
+	return true, nil
+}
+
+func decode(t *testing.T, name, content string) deploy.Task {
+	tsk, err := deploy.Decode("/tmp/"+name, strings.NewReader(content))
+	require.NoError(t, err)
+	return *tsk
+}
+
+func newTestRunner(t *testing.T) *Runner {
+	events = nil
+
+	r, err := NewRunner(actions.NewLogger(), []deploy.Task{
+		decode(t, "10-fail.task", "[Task]\nDescription= fails\n\n[Record]\nFail= yes\n"),
+		decode(t, "20-on-failure.task", "[Task]\nEvaluateConditions= execute\nConditionTaskFailed= 10-fail\n\n[Record]\n"),
+		decode(t, "30-on-success.task", "[Task]\nEvaluateConditions= execute\nConditionTaskSucceeded= 10-fail\n\n[Record]\n"),
+	})
+	require.NoError(t, err)
+
+	return r
+}
+
+func TestDeployAbortsOnFailure(t *testing.T) {
+	r := newTestRunner(t)
+
+	err := r.Deploy(context.Background())
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, []string{
+		"prepare 10-fail.task",
+		"prepare 20-on-failure.task",
+		"prepare 30-on-success.task",
+		"execute 10-fail.task",
+	}, events)
+}
+
+func TestDeployKeepGoing(t *testing.T) {
+	r := newTestRunner(t)
+	r.KeepGoing = true
+
+	err := r.Deploy(context.Background())
+	require.Error(t, err)
+
+	var failed TaskErrors
+	require.True(t, errors.As(err, &failed))
+	require.Len(t, failed, 1)
+	assert.Equal(t, "10-fail.task", failed[0].Task)
+
+	// 20-on-failure runs because 10-fail failed while
+	// 30-on-success is disabled.
+	assert.Equal(t, []string{
+		"prepare 10-fail.task",
+		"prepare 20-on-failure.task",
+		"prepare 30-on-success.task",
+		"execute 10-fail.task",
+		"execute 20-on-failure.task",
+	}, events)
+
+	res, ok := r.TaskResult("20-on-failure.task")
+	require.True(t, ok)
+	assert.True(t, res.Executed)
+	assert.True(t, res.Changed)
+	assert.NoError(t, res.Err)
+}
+
+func TestDeferredTaskPreparedBeforeExecution(t *testing.T) {
+	events = nil
+
+	r, err := NewRunner(actions.NewLogger(), []deploy.Task{
+		decode(t, "10-first.task", "[Task]\nDescription= first\n\n[Record]\n"),
+		decode(t, "20-deferred.task", "[Task]\nEvaluateConditions= execute\nConditionTaskChanged= 10-first\n\n[Record]\n"),
+	})
+	require.NoError(t, err)
+
+	// EvaluateConditions=execute only defers conditions. The
+	// actions of all tasks are still prepared before the first
+	// task is executed.
+	require.NoError(t, r.Deploy(context.Background()))
+	assert.Equal(t, []string{
+		"prepare 10-first.task",
+		"prepare 20-deferred.task",
+		"execute 10-first.task",
+		"execute 20-deferred.task",
+	}, events)
+}
@@ -2,8 +2,11 @@ package runner
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/fatih/color"
 	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/condition"
 	"github.com/ppacher/system-deploy/pkg/deploy"
 	"github.com/sirupsen/logrus"
 	"github.com/tevino/abool"
@@ -13,6 +16,14 @@ type Task struct {
 	task    *deploy.Task
 	actions []actions.Action
 
+	// result holds the result of the task once it has
+	// been executed.
+	result condition.TaskResult
+
+	// skipped holds the indexes of all actions whose
+	// conditions failed during preparation.
+	skipped map[int]bool
+
 	name   string
 	masked *abool.AtomicBool
 
@@ -46,27 +57,31 @@ func (t *Task) isMasked() bool {
 }
 
 // Prepare calls the perpare method of each action defined
-// in the task.
-func (t *Task) Prepare(graph actions.ExecGraph) error {
-
-	cond, err := deploy.EvaluateConditions(t.task)
-	if err != nil {
-		logrus.Debugf("%s: conditon %s: %s", t.name, cond.Name, err)
-		if cond.Assertion {
-			// TODO(ppacher): should we mark the task
-			// as "pre-failed" and execute all others
-			// until we hit it?
+// in the task. Conditions are evaluated first unless they
+// are deferred until execution.
+func (t *Task) Prepare(ctx context.Context, graph actions.ExecGraph) error {
+	if !t.deferConditions() {
+		if err := t.EvaluateConditions(ctx); err != nil {
 			return err
 		}
-
-		// Condition failed, mark task as disabled.
-		t.disabled.Set()
 	}
 
 	// don't even try to prepare the task if it's already
 	// disabled.
 	if !t.disabled.IsSet() {
-		for _, a := range t.actions {
+		for idx, a := range t.actions {
+			if !t.deferConditions() {
+				skip, err := t.evaluateActionConditions(ctx, a)
+				if err != nil {
+					return err
+				}
+
+				if skip {
+					t.skipped[idx] = true
+					continue
+				}
+			}
+
 			if p, ok := a.(actions.Preparer); ok {
 				if err := p.Prepare(graph); err != nil {
 					return err
@@ -78,12 +93,52 @@ func (t *Task) Prepare(graph actions.ExecGraph) error {
 	return nil
 }
 
+// EvaluateConditions evaluates the conditions of the task
+// meta section and disables the task if one of them fails.
+// It returns an error if an assertion fails.
+func (t *Task) EvaluateConditions(ctx context.Context) error {
+	cond, err := deploy.EvaluateConditions(ctx, t.task)
+	if err != nil {
+		logrus.Debugf("%s: conditon %s: %s", t.name, cond.Name, err)
+		if cond.Assertion {
+			// TODO(ppacher): should we mark the task
+			// as "pre-failed" and execute all others
+			// until we hit it?
+			return err
+		}
+
+		// Condition failed, mark task as disabled.
+		t.disabled.Set()
+	}
+
+	return nil
+}
+
+// deferConditions returns true if conditions should be
+// evaluated right before the task is executed.
+func (t *Task) deferConditions() bool {
+	return t.task.ConditionPhase == deploy.PhaseExecute
+}
+
 // Execute executes all actions of the task in the order they are defined.
 // It returns true if any of the actions returned true and aborts on the
 // first error encountered.
 func (t *Task) Execute(ctx context.Context, log actions.Logger) (bool, error) {
 	var changed bool
-	for _, a := range t.actions {
+	for idx, a := range t.actions {
+		if t.deferConditions() {
+			skip, err := t.evaluateActionConditions(ctx, a)
+			if err != nil {
+				return false, err
+			}
+			t.skipped[idx] = skip
+		}
+
+		if t.skipped[idx] {
+			log.Infof("%s: %s %s", color.New(color.Bold).Sprintf("%-30v", t.name), color.New(color.FgYellow).Sprint("skipped"), a.Name())
+			continue
+		}
+
 		log.Debugf("%s: actions %s", t.name, a.Name())
 		if r, ok := a.(actions.Executor); ok {
 			c, err := r.Execute(ctx)
@@ -99,3 +154,25 @@ func (t *Task) Execute(ctx context.Context, log actions.Logger) (bool, error) {
 
 	return changed, nil
 }
+
+// evaluateActionConditions evaluates the conditions defined
+// in the section of a. It returns true if a should be skipped
+// and an error if an assertion failed.
+func (t *Task) evaluateActionConditions(ctx context.Context, a actions.Action) (bool, error) {
+	c, ok := a.(actions.Conditional)
+	if !ok || len(c.ActionConditions()) == 0 {
+		return false, nil
+	}
+
+	cond, err := deploy.EvaluateInstances(ctx, t.task, c.ActionConditions())
+	if err == nil {
+		return false, nil
+	}
+
+	logrus.Debugf("%s: %s: conditon %s: %s", t.name, a.Name(), cond.Name, err)
+	if cond.Assertion {
+		return false, fmt.Errorf("%s: %w", a.Name(), err)
+	}
+
+	return true, nil
+}
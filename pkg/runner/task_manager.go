@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/ppacher/system-deploy/pkg/actions"
+	"github.com/ppacher/system-deploy/pkg/condition"
 	"github.com/ppacher/system-deploy/pkg/deploy"
 	"github.com/tevino/abool"
 )
@@ -51,10 +52,14 @@ func NewTaskManager(l actions.Logger) *TaskManager {
 func (tm *TaskManager) AddTask(name string, target deploy.Task) error {
 	var targetActions []actions.Action
 
+	if err := deploy.CheckConditionPhase(&target, target.Conditions); err != nil {
+		return fmt.Errorf("invalid task %s: %w", name, err)
+	}
+
 	for idx := range target.Sections {
 		section := target.Sections[idx]
 		tm.log.Debugf("%s: setup action %s", name, section.Name)
-		action, err := actions.Setup(section.Name, tm.log, target, section)
+		action, err := actions.SetupSection(tm.log, target, idx)
 		if err != nil {
 			return fmt.Errorf("setup failed for %s: %w", name, err)
 		}
@@ -65,6 +70,7 @@ func (tm *TaskManager) AddTask(name string, target deploy.Task) error {
 	t := &Task{
 		task:     &target,
 		actions:  targetActions,
+		skipped:  make(map[int]bool),
 		name:     name,
 		masked:   abool.NewBool(target.StartMasked),
 		disabled: abool.NewBool(target.Disabled),
@@ -179,6 +185,32 @@ func (tm *TaskManager) DisableTask(task string) error {
 	return nil
 }
 
+// TaskResult returns the result of task and implements
+// condition.TaskResults.
+func (tm *TaskManager) TaskResult(task string) (condition.TaskResult, bool) {
+	t, err := tm.getTask(task)
+	if err != nil {
+		return condition.TaskResult{}, false
+	}
+
+	tm.l.RLock()
+	defer tm.l.RUnlock()
+
+	return t.result, true
+}
+
+// setTaskResult records the result of an executed task.
+func (tm *TaskManager) setTaskResult(t *Task, changed bool, err error) {
+	tm.l.Lock()
+	defer tm.l.Unlock()
+
+	t.result = condition.TaskResult{
+		Executed: true,
+		Changed:  changed,
+		Err:      err,
+	}
+}
+
 // getTask returns the task with the given name.
 func (tm *TaskManager) getTask(name string) (*Task, error) {
 	tm.l.RLock()
@@ -0,0 +1,101 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Provider fetches secrets by key from a secret store.
+type Provider interface {
+	// GetSecret returns the secret value stored under key.
+	GetSecret(ctx context.Context, key string) (string, error)
+}
+
+// ProviderFunc is a convenience type for implementing
+// a Provider using a function.
+type ProviderFunc func(ctx context.Context, key string) (string, error)
+
+// GetSecret implements Provider.
+func (fn ProviderFunc) GetSecret(ctx context.Context, key string) (string, error) {
+	return fn(ctx, key)
+}
+
+// DefaultProvider is the name of the provider that is used
+// for secret references without a provider prefix.
+var DefaultProvider = "file"
+
+var (
+	providersLock sync.RWMutex
+	providers     = map[string]Provider{
+		"file":  &FileProvider{},
+		"cmd":   &CommandProvider{},
+		"vault": &VaultProvider{},
+	}
+)
+
+// RegisterProvider registers a new secret provider under name.
+func RegisterProvider(name string, p Provider) error {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	if _, ok := providers[name]; ok {
+		return fmt.Errorf("secret provider %s already registered", name)
+	}
+
+	providers[name] = p
+	return nil
+}
+
+// GetProvider returns the provider registered under name.
+func GetProvider(name string) (Provider, bool) {
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Providers returns the names of all registered providers.
+func Providers() []string {
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Get fetches the secret referenced by ref. A reference has the
+// format [provider:]key. If provider is omitted, DefaultProvider
+// is used. Keys that contain a colon therefore always require an
+// explicit provider. The returned value is marked as sensitive.
+func Get(ctx context.Context, ref string) (string, error) {
+	name := DefaultProvider
+	key := ref
+
+	if parts := strings.SplitN(ref, ":", 2); len(parts) == 2 {
+		name = parts[0]
+		key = parts[1]
+	}
+
+	p, ok := GetProvider(name)
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider %q", name)
+	}
+
+	value, err := p.GetSecret(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("secret %s:%s: %w", name, key, err)
+	}
+
+	MarkSensitive(value)
+
+	return value, nil
+}
@@ -0,0 +1,61 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/flynn/go-shlex"
+)
+
+// SecretCommand is the command used by the command provider
+// if CommandProvider.Command is not set.
+var SecretCommand string
+
+// CommandProvider fetches secrets by executing a command
+// with the key appended as the last argument. The standard
+// output of the command is used as the secret value. A single
+// trailing newline is removed from the value.
+type CommandProvider struct {
+	// Command is the command to execute. Defaults to
+	// SecretCommand.
+	Command string
+}
+
+// GetSecret implements Provider.
+func (cp *CommandProvider) GetSecret(ctx context.Context, key string) (string, error) {
+	command := cp.Command
+	if command == "" {
+		command = SecretCommand
+	}
+
+	if command == "" {
+		return "", errors.New("no secret command configured")
+	}
+
+	parts, err := shlex.Split(command)
+	if err != nil {
+		return "", err
+	}
+
+	if len(parts) == 0 {
+		return "", errors.New("invalid secret command")
+	}
+
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, parts[0], append(parts[1:], key)...)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+
+	return trimNewline(stdout.String()), nil
+}
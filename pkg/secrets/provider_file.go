@@ -0,0 +1,58 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SecretDir is the directory used by the file provider if
+// FileProvider.Dir is not set.
+var SecretDir = "/etc/system-deploy/secrets"
+
+// FileProvider reads secrets from files in a directory. The
+// key is the path of the file relative to the directory. If
+// the file does not exist but an encrypted one with the same
+// name and an .age extension does, it is decrypted instead.
+// A single trailing newline is removed from the value.
+type FileProvider struct {
+	// Dir is the directory that holds the secret files.
+	// Defaults to SecretDir.
+	Dir string
+}
+
+// GetSecret implements Provider.
+func (fp *FileProvider) GetSecret(_ context.Context, key string) (string, error) {
+	dir := fp.Dir
+	if dir == "" {
+		dir = SecretDir
+	}
+
+	clean := filepath.Clean(key)
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+
+	path := filepath.Join(dir, clean)
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if _, statErr := os.Stat(path + Extension); statErr == nil {
+			content, err = DecryptFile(path + Extension)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return trimNewline(string(content)), nil
+}
+
+// trimNewline removes a single trailing newline from s.
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
@@ -0,0 +1,174 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileProvider(t *testing.T) {
+	id, cleanup := withKeyDir(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "secrets-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "db"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "db", "password"), []byte("s3cr3t\n"), 0600))
+
+	var buf bytes.Buffer
+	require.NoError(t, Encrypt(&buf, []byte("encrypted-value"), []age.Recipient{id.Recipient()}, false))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "token.age"), buf.Bytes(), 0600))
+
+	fp := &FileProvider{Dir: dir}
+
+	value, err := fp.GetSecret(context.Background(), "db/password")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", value)
+
+	value, err = fp.GetSecret(context.Background(), "token")
+	require.NoError(t, err)
+	assert.Equal(t, "encrypted-value", value)
+
+	_, err = fp.GetSecret(context.Background(), "missing")
+	assert.Error(t, err)
+
+	_, err = fp.GetSecret(context.Background(), "../etc/passwd")
+	assert.Error(t, err)
+
+	_, err = fp.GetSecret(context.Background(), "/etc/passwd")
+	assert.Error(t, err)
+}
+
+func TestCommandProvider(t *testing.T) {
+	cp := &CommandProvider{Command: "echo secret-for"}
+
+	value, err := cp.GetSecret(context.Background(), "db")
+	require.NoError(t, err)
+	assert.Equal(t, "secret-for db", value)
+
+	cp = &CommandProvider{Command: "sh -c 'echo denied >&2; exit 1' --"}
+	_, err = cp.GetSecret(context.Background(), "db")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "denied")
+	}
+
+	old := SecretCommand
+	SecretCommand = ""
+	defer func() { SecretCommand = old }()
+
+	_, err = (&CommandProvider{}).GetSecret(context.Background(), "db")
+	assert.Error(t, err)
+}
+
+func TestVaultProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/db":
+			w.Write([]byte(`{"data":{"data":{"password":"kv2-pass","port":5432},"metadata":{"version":1}}}`))
+		case "/v1/kv/app":
+			w.Write([]byte(`{"data":{"value":"kv1-value"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer srv.Close()
+
+	vp := &VaultProvider{Address: srv.URL, Token: "test-token"}
+
+	cases := []struct {
+		Key   string
+		Value string
+		Err   bool
+	}{
+		{"secret/data/db#password", "kv2-pass", false},
+		{"secret/data/db#port", "5432", false},
+		{"kv/app", "kv1-value", false},
+		{"secret/data/db#missing", "", true},
+		{"secret/data/other#value", "", true},
+	}
+
+	for _, c := range cases {
+		value, err := vp.GetSecret(context.Background(), c.Key)
+		if c.Err {
+			assert.Error(t, err, c.Key)
+			continue
+		}
+
+		if assert.NoError(t, err, c.Key) {
+			assert.Equal(t, c.Value, value, c.Key)
+		}
+	}
+
+	vp.Token = "wrong"
+	_, err := vp.GetSecret(context.Background(), "kv/app")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "permission denied")
+	}
+}
+
+func TestGet(t *testing.T) {
+	require.NoError(t, RegisterProvider("test", ProviderFunc(func(_ context.Context, key string) (string, error) {
+		return "value-of-" + key, nil
+	})))
+	assert.Error(t, RegisterProvider("test", ProviderFunc(nil)))
+
+	value, err := Get(context.Background(), "test:some:key")
+	require.NoError(t, err)
+	assert.Equal(t, "value-of-some:key", value)
+	assert.True(t, IsSensitive(value))
+
+	old := DefaultProvider
+	DefaultProvider = "test"
+	defer func() { DefaultProvider = old }()
+
+	value, err = Get(context.Background(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value-of-key", value)
+
+	// a typo in the provider name must not fall back to
+	// the default provider.
+	_, err = Get(context.Background(), "tset:key")
+	assert.EqualError(t, err, `unknown secret provider "tset"`)
+
+	value, err = Get(context.Background(), "test:tset:key")
+	require.NoError(t, err)
+	assert.Equal(t, "value-of-tset:key", value)
+}
+
+func TestRedact(t *testing.T) {
+	MarkSensitive("hunter2-secret")
+	MarkSensitive("abc")
+	MarkSensitive("line-one\nline-two")
+
+	assert.Equal(t, "password is "+Redacted, Redact("password is hunter2-secret"))
+	assert.Equal(t, "abc", Redact("abc"), "short values must not be redacted")
+	assert.Equal(t, Redacted+" and "+Redacted, Redact("line-one and line-two"))
+}
+
+func TestRedactWriter(t *testing.T) {
+	MarkSensitive("writer-secret")
+
+	var buf bytes.Buffer
+	log.New(RedactWriter{W: &buf}, "", 0).Printf("command returned 1\nlogin with writer-secret failed")
+
+	assert.Equal(t, "command returned 1\nlogin with "+Redacted+" failed\n", buf.String())
+}
@@ -0,0 +1,133 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// defaultVaultField is the field of a Vault secret that is
+// returned if the key does not specify one.
+const defaultVaultField = "value"
+
+// VaultProvider fetches secrets from a HashiCorp Vault server
+// using its HTTP API. Keys have the format path#field where
+// path is the API path of the secret without the /v1/ prefix
+// (like secret/data/db) and field the name of the value within
+// the secret. field defaults to "value". Both, KV version 1
+// and version 2 secret engines are supported.
+type VaultProvider struct {
+	// Address is the address of the Vault server.
+	// Defaults to $VAULT_ADDR.
+	Address string
+
+	// Token is the token used for authentication.
+	// Defaults to $VAULT_TOKEN.
+	Token string
+
+	// Namespace is the Vault namespace to use, if any.
+	// Defaults to $VAULT_NAMESPACE.
+	Namespace string
+
+	// Client is the HTTP client used for requests.
+	// Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// vaultResponse is the response of the Vault API for
+// reading a secret.
+type vaultResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []string               `json:"errors"`
+}
+
+// GetSecret implements Provider.
+func (vp *VaultProvider) GetSecret(ctx context.Context, key string) (string, error) {
+	addr := valueOrEnv(vp.Address, "VAULT_ADDR")
+	if addr == "" {
+		return "", errors.New("no vault address configured")
+	}
+
+	path := key
+	field := defaultVaultField
+	if idx := strings.LastIndex(key, "#"); idx >= 0 {
+		path = key[:idx]
+		field = key[idx+1:]
+	}
+
+	url := strings.TrimSuffix(addr, "/") + "/v1/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+
+	if token := valueOrEnv(vp.Token, "VAULT_TOKEN"); token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if ns := valueOrEnv(vp.Namespace, "VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+
+	client := vp.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var payload vaultResponse
+	if err := json.NewDecoder(res.Body).Decode(&payload); err != nil && res.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		if len(payload.Errors) > 0 {
+			return "", fmt.Errorf("vault returned %s: %s", res.Status, strings.Join(payload.Errors, ", "))
+		}
+		return "", fmt.Errorf("vault returned %s", res.Status)
+	}
+
+	data := payload.Data
+
+	// KV version 2 engines nest the actual secret data and
+	// add version metadata.
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("field %q not found", field)
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	blob, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(blob), nil
+}
+
+// valueOrEnv returns value or, if empty, the value of the
+// environment variable env.
+func valueOrEnv(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
@@ -0,0 +1,207 @@
+// Package secrets implements encryption and decryption of
+// secret files using age (https://age-encryption.org).
+// Identities (private keys) are read from *.key files and
+// additional recipients (public keys) from *.pub files in
+// KeyDir. In addition, the package provides access to
+// secret stores using Providers and keeps track of sensitive
+// values that must be redacted.
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// Extension is the file extension of encrypted files.
+const Extension = ".age"
+
+// KeyDir is the directory that holds age identities and
+// recipients.
+var KeyDir = "/etc/system-deploy/keys"
+
+// ErrNoKeys is returned if KeyDir does not contain any
+// usable keys.
+var ErrNoKeys = errors.New("no keys found")
+
+// IsEncrypted returns true if path is an encrypted file.
+func IsEncrypted(path string) bool {
+	return strings.HasSuffix(path, Extension)
+}
+
+// Identities returns all identities from the *.key files in
+// KeyDir.
+func Identities() ([]age.Identity, error) {
+	var identities []age.Identity
+
+	err := forEachKeyFile(".key", func(path string, r io.Reader) error {
+		ids, err := age.ParseIdentities(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		identities = append(identities, ids...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoKeys, KeyDir)
+	}
+
+	return identities, nil
+}
+
+// Recipients returns all recipients from the *.pub files in
+// KeyDir as well as the recipients of all X25519 identities
+// in *.key files. Additional recipients in the age format
+// may be passed in extra.
+func Recipients(extra ...string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+
+	for _, r := range extra {
+		recipient, err := age.ParseX25519Recipient(r)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	err := forEachKeyFile(".pub", func(path string, r io.Reader) error {
+		rs, err := age.ParseRecipients(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		recipients = append(recipients, rs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := Identities()
+	if err != nil && !errors.Is(err, ErrNoKeys) {
+		return nil, err
+	}
+
+	for _, id := range identities {
+		if x, ok := id.(*age.X25519Identity); ok {
+			recipients = append(recipients, x.Recipient())
+		}
+	}
+
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoKeys, KeyDir)
+	}
+
+	return recipients, nil
+}
+
+// forEachKeyFile calls fn for each file in KeyDir with the
+// extension ext. A missing KeyDir is not an error.
+func forEachKeyFile(ext string, fn func(path string, r io.Reader) error) error {
+	files, err := ioutil.ReadDir(KeyDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, fi := range files {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ext {
+			continue
+		}
+
+		path := filepath.Join(KeyDir, fi.Name())
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(path, bytes.NewReader(content)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Decrypt decrypts data read from r using all identities
+// in KeyDir. Both binary and ASCII armored files are
+// supported.
+func Decrypt(r io.Reader) ([]byte, error) {
+	identities, err := Identities()
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	if start, _ := br.Peek(len(armor.Header)); string(start) == armor.Header {
+		src = armor.NewReader(br)
+	}
+
+	plain, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(plain)
+}
+
+// DecryptFile is like Decrypt but reads from path.
+func DecryptFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	content, err := Decrypt(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+
+	return content, nil
+}
+
+// Encrypt encrypts plain for all recipients and writes the
+// result to w. If armored is true the output is ASCII armored.
+func Encrypt(w io.Writer, plain []byte, recipients []age.Recipient, armored bool) error {
+	dst := w
+	var armorWriter io.WriteCloser
+	if armored {
+		armorWriter = armor.NewWriter(w)
+		dst = armorWriter
+	}
+
+	enc, err := age.Encrypt(dst, recipients...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := enc.Write(plain); err != nil {
+		return err
+	}
+
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	if armorWriter != nil {
+		return armorWriter.Close()
+	}
+
+	return nil
+}
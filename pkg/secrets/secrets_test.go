@@ -0,0 +1,107 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withKeyDir points KeyDir to a temporary directory that
+// contains a single identity and returns it.
+func withKeyDir(t *testing.T) (*age.X25519Identity, func()) {
+	dir, err := ioutil.TempDir("", "secrets-")
+	require.NoError(t, err)
+
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "host.key"), []byte("# test key\n"+id.String()+"\n"), 0600))
+
+	old := KeyDir
+	KeyDir = dir
+
+	return id, func() {
+		KeyDir = old
+		os.RemoveAll(dir)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	_, cleanup := withKeyDir(t)
+	defer cleanup()
+
+	recipients, err := Recipients()
+	require.NoError(t, err)
+	require.Len(t, recipients, 1)
+
+	for _, armored := range []bool{false, true} {
+		var buf bytes.Buffer
+		require.NoError(t, Encrypt(&buf, []byte("PASSWORD=secret\n"), recipients, armored))
+
+		assert.Equal(t, armored, strings.HasPrefix(buf.String(), armor.Header))
+
+		plain, err := Decrypt(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, "PASSWORD=secret\n", string(plain))
+	}
+}
+
+func TestRecipients(t *testing.T) {
+	_, cleanup := withKeyDir(t)
+	defer cleanup()
+
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(KeyDir, "admin.pub"), []byte(other.Recipient().String()+"\n"), 0644))
+
+	extra, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	recipients, err := Recipients(extra.Recipient().String())
+	require.NoError(t, err)
+	assert.Len(t, recipients, 3)
+
+	var buf bytes.Buffer
+	require.NoError(t, Encrypt(&buf, []byte("data"), recipients, false))
+
+	// all recipients must be able to decrypt the file.
+	for _, id := range []age.Identity{other, extra} {
+		r, err := age.Decrypt(bytes.NewReader(buf.Bytes()), id)
+		require.NoError(t, err)
+		plain, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(plain))
+	}
+
+	_, err = Recipients("not-a-recipient")
+	assert.Error(t, err)
+}
+
+func TestNoKeys(t *testing.T) {
+	old := KeyDir
+	KeyDir = "/does/not/exist"
+	defer func() { KeyDir = old }()
+
+	_, err := Identities()
+	assert.True(t, errors.Is(err, ErrNoKeys))
+
+	_, err = Decrypt(strings.NewReader("data"))
+	assert.True(t, errors.Is(err, ErrNoKeys))
+
+	_, err = Recipients()
+	assert.True(t, errors.Is(err, ErrNoKeys))
+}
+
+func TestIsEncrypted(t *testing.T) {
+	assert.True(t, IsEncrypted("db.env.age"))
+	assert.False(t, IsEncrypted("db.env"))
+}
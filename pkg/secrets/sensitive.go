@@ -0,0 +1,90 @@
+package secrets
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces sensitive values in redacted text.
+const Redacted = "********"
+
+// minSensitiveLength is the minimum length of values that
+// are tracked as sensitive. Shorter values would garble
+// unrelated output when being redacted.
+const minSensitiveLength = 4
+
+var (
+	sensitiveLock sync.RWMutex
+	sensitive     = make(map[string]struct{})
+)
+
+// MarkSensitive marks value as sensitive so it is redacted
+// by Redact. Each line of a multi-line value is marked as
+// well because output is often logged line by line.
+func MarkSensitive(value string) {
+	sensitiveLock.Lock()
+	defer sensitiveLock.Unlock()
+
+	add := func(v string) {
+		if len(v) >= minSensitiveLength {
+			sensitive[v] = struct{}{}
+		}
+	}
+
+	add(value)
+	if strings.Contains(value, "\n") {
+		for _, line := range strings.Split(value, "\n") {
+			add(strings.TrimSpace(line))
+		}
+	}
+}
+
+// IsSensitive returns true if value has been marked as
+// sensitive.
+func IsSensitive(value string) bool {
+	sensitiveLock.RLock()
+	defer sensitiveLock.RUnlock()
+
+	_, ok := sensitive[value]
+	return ok
+}
+
+// Redact replaces all sensitive values in s with Redacted.
+func Redact(s string) string {
+	sensitiveLock.RLock()
+	values := make([]string, 0, len(sensitive))
+	for v := range sensitive {
+		values = append(values, v)
+	}
+	sensitiveLock.RUnlock()
+
+	// replace longer values first so a value that contains
+	// another one is still fully redacted.
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	for _, v := range values {
+		s = strings.ReplaceAll(s, v, Redacted)
+	}
+
+	return s
+}
+
+// RedactWriter redacts sensitive values from everything
+// written to W. Each call to Write is redacted on its own so
+// it should only be used for writers that receive complete
+// messages, like the output of a log.Logger.
+type RedactWriter struct {
+	W io.Writer
+}
+
+// Write implements io.Writer.
+func (rw RedactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.W, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
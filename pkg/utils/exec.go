@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"syscall"
@@ -17,6 +18,14 @@ type ExecOptions struct {
 	PipeInput  bool
 	Env        map[string]string
 	ExitCode   *int64
+
+	// Output receives a copy of the command output. It's
+	// ignored if PipeOutput is set.
+	Output io.Writer
+
+	// Stdout receives a copy of the standard output of the
+	// command only. It's ignored if PipeOutput is set.
+	Stdout io.Writer
 }
 
 type ExitCodeError struct {
@@ -57,6 +66,16 @@ func ExecCommand(ctx context.Context, workDir string, cmd string, opts *ExecOpti
 		if opts.PipeOutput {
 			c.Stderr = os.Stderr
 			c.Stdout = os.Stdout
+		} else {
+			if opts.Output != nil {
+				w := io.MultiWriter(&output, opts.Output)
+				c.Stderr = w
+				c.Stdout = w
+			}
+
+			if opts.Stdout != nil {
+				c.Stdout = io.MultiWriter(c.Stdout, opts.Stdout)
+			}
 		}
 
 		if opts.PipeInput {
@@ -0,0 +1,302 @@
+package hostsfile
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"strings"
+)
+
+// Line is a single line of a hosts file. Lines that do not
+// contain an address (empty lines, comments or malformed
+// lines) only have Raw set and are always written back
+// unmodified.
+type Line struct {
+	// Raw holds the original content of the line.
+	Raw string
+
+	// IP is the address of the entry. Empty for non-entry
+	// lines.
+	IP string
+
+	// Names holds the canonical hostname followed by all
+	// aliases.
+	Names []string
+
+	// Comment holds a trailing comment, including the
+	// leading '#'.
+	Comment string
+
+	modified bool
+}
+
+// IsEntry returns true if l contains an address.
+func (l *Line) IsEntry() bool {
+	return l.IP != ""
+}
+
+// String returns the line as it should be written to the
+// hosts file.
+func (l *Line) String() string {
+	if !l.modified {
+		return l.Raw
+	}
+
+	s := l.IP + "\t" + strings.Join(l.Names, " ")
+	if l.Comment != "" {
+		s += " " + l.Comment
+	}
+
+	return s
+}
+
+func (l *Line) hasName(name string) bool {
+	for _, n := range l.Names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// File is a parsed hosts file. Use Parse to create a
+// new File.
+type File struct {
+	Lines []*Line
+}
+
+// Parse parses a hosts file from r. Parse never fails on
+// malformed lines but keeps them as they are.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f.Lines = append(f.Lines, parseLine(scanner.Text()))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func parseLine(raw string) *Line {
+	l := &Line{Raw: raw}
+
+	content := raw
+	if idx := strings.Index(raw, "#"); idx >= 0 {
+		content = raw[:idx]
+		l.Comment = strings.TrimSpace(raw[idx:])
+	}
+
+	fields := strings.Fields(content)
+	if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+		// not an entry, just keep the raw line
+		l.Comment = ""
+		return l
+	}
+
+	l.IP = fields[0]
+	l.Names = fields[1:]
+
+	return l
+}
+
+// Lookup returns the first entry for ip or nil.
+func (f *File) Lookup(ip string) *Line {
+	for _, l := range f.Lines {
+		if l.IsEntry() && sameIP(l.IP, ip) {
+			return l
+		}
+	}
+	return nil
+}
+
+// Add ensures that all names resolve to ip. Names are removed
+// from entries of other addresses of the same family first so
+// a host name that moved to a new address does not resolve to
+// the old one anymore. Entries that don't have any names left
+// are dropped. If there's already an entry for ip, missing
+// names are appended to it. Otherwise a new line is added at
+// the end of the file. Add returns true if f has been modified.
+func (f *File) Add(ip string, names ...string) bool {
+	changed := f.removeFromOthers(ip, names)
+
+	line := f.Lookup(ip)
+	if line == nil {
+		f.Lines = append(f.Lines, &Line{
+			IP:       ip,
+			Names:    dedup(names),
+			modified: true,
+		})
+		return true
+	}
+
+	for _, name := range names {
+		if f.hasName(ip, name) {
+			continue
+		}
+
+		line.Names = append(line.Names, name)
+		line.modified = true
+		changed = true
+	}
+
+	return changed
+}
+
+// removeFromOthers removes names from all entries that have
+// a different address of the same family as ip. It returns
+// true if f has been modified.
+func (f *File) removeFromOthers(ip string, names []string) bool {
+	var (
+		changed bool
+		lines   = make([]*Line, 0, len(f.Lines))
+	)
+
+	for _, l := range f.Lines {
+		if !l.IsEntry() || sameIP(l.IP, ip) || !sameFamily(l.IP, ip) {
+			lines = append(lines, l)
+			continue
+		}
+
+		kept := make([]string, 0, len(l.Names))
+		for _, n := range l.Names {
+			if !containsFold(names, n) {
+				kept = append(kept, n)
+			}
+		}
+
+		if len(kept) == len(l.Names) {
+			lines = append(lines, l)
+			continue
+		}
+
+		changed = true
+		if len(kept) > 0 {
+			l.Names = kept
+			l.modified = true
+			lines = append(lines, l)
+		}
+	}
+
+	f.Lines = lines
+
+	return changed
+}
+
+// Remove removes names from all entries of ip. If names
+// is empty, all entries for ip are removed. Entries that
+// don't have any names left are dropped as well. Remove
+// returns true if f has been modified.
+func (f *File) Remove(ip string, names ...string) bool {
+	var (
+		changed bool
+		lines   = make([]*Line, 0, len(f.Lines))
+	)
+
+	for _, l := range f.Lines {
+		if !l.IsEntry() || !sameIP(l.IP, ip) {
+			lines = append(lines, l)
+			continue
+		}
+
+		if len(names) > 0 {
+			kept := make([]string, 0, len(l.Names))
+			for _, n := range l.Names {
+				if !containsFold(names, n) {
+					kept = append(kept, n)
+				}
+			}
+
+			if len(kept) == len(l.Names) {
+				lines = append(lines, l)
+				continue
+			}
+
+			changed = true
+			if len(kept) > 0 {
+				l.Names = kept
+				l.modified = true
+				lines = append(lines, l)
+			}
+
+			continue
+		}
+
+		changed = true
+	}
+
+	f.Lines = lines
+
+	return changed
+}
+
+// Bytes returns the content of the hosts file.
+func (f *File) Bytes() []byte {
+	var buf bytes.Buffer
+
+	for _, l := range f.Lines {
+		buf.WriteString(l.String())
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
+
+// hasName returns true if any entry of ip already contains
+// name.
+func (f *File) hasName(ip, name string) bool {
+	for _, l := range f.Lines {
+		if l.IsEntry() && sameIP(l.IP, ip) && l.hasName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameIP(a, b string) bool {
+	ipA := net.ParseIP(a)
+	ipB := net.ParseIP(b)
+
+	if ipA == nil || ipB == nil {
+		return a == b
+	}
+
+	return ipA.Equal(ipB)
+}
+
+// sameFamily returns true if a and b are both IPv4 or both
+// IPv6 addresses.
+func sameFamily(a, b string) bool {
+	ipA := net.ParseIP(a)
+	ipB := net.ParseIP(b)
+
+	if ipA == nil || ipB == nil {
+		return false
+	}
+
+	return (ipA.To4() == nil) == (ipB.To4() == nil)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func dedup(names []string) []string {
+	result := make([]string, 0, len(names))
+	for _, n := range names {
+		if !containsFold(result, n) {
+			result = append(result, n)
+		}
+	}
+	return result
+}
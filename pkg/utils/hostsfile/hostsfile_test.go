@@ -0,0 +1,92 @@
+package hostsfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const input = `# managed by hand
+127.0.0.1	localhost
+::1		localhost ip6-localhost # ipv6
+
+10.0.0.5 db
+not an entry
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, f.Lines, 6)
+
+	assert.False(t, f.Lines[0].IsEntry())
+	assert.Equal(t, "127.0.0.1", f.Lines[1].IP)
+	assert.Equal(t, []string{"localhost", "ip6-localhost"}, f.Lines[2].Names)
+	assert.Equal(t, "# ipv6", f.Lines[2].Comment)
+	assert.False(t, f.Lines[5].IsEntry())
+
+	// unmodified files must be written back as they are.
+	assert.Equal(t, input, string(f.Bytes()))
+}
+
+func TestAdd(t *testing.T) {
+	f, err := Parse(strings.NewReader(input))
+	require.NoError(t, err)
+
+	assert.False(t, f.Add("10.0.0.5", "db"))
+	assert.False(t, f.Add("::1", "IP6-LOCALHOST"))
+	assert.True(t, f.Add("10.0.0.5", "db", "db.internal"))
+	assert.True(t, f.Add("10.0.0.6", "cache", "cache"))
+	assert.False(t, f.Add("10.0.0.6", "cache"))
+
+	assert.Equal(t, `# managed by hand
+127.0.0.1	localhost
+::1		localhost ip6-localhost # ipv6
+
+10.0.0.5	db db.internal
+not an entry
+10.0.0.6	cache
+`, string(f.Bytes()))
+}
+
+func TestAddMovesNames(t *testing.T) {
+	f, err := Parse(strings.NewReader(input))
+	require.NoError(t, err)
+
+	assert.True(t, f.Add("10.0.0.6", "DB"))
+	assert.True(t, f.Add("fe80::1", "localhost"))
+	assert.False(t, f.Add("fe80::1", "localhost"))
+
+	// names are only removed from addresses of the same
+	// family.
+	assert.Equal(t, `# managed by hand
+127.0.0.1	localhost
+::1	ip6-localhost # ipv6
+
+not an entry
+10.0.0.6	DB
+fe80::1	localhost
+`, string(f.Bytes()))
+}
+
+func TestRemove(t *testing.T) {
+	f, err := Parse(strings.NewReader(input))
+	require.NoError(t, err)
+
+	assert.False(t, f.Remove("10.0.0.7"))
+	assert.False(t, f.Remove("10.0.0.5", "cache"))
+	assert.True(t, f.Remove("::1", "ip6-localhost"))
+	assert.True(t, f.Remove("10.0.0.5", "db"))
+
+	assert.Equal(t, `# managed by hand
+127.0.0.1	localhost
+::1	localhost # ipv6
+
+not an entry
+`, string(f.Bytes()))
+
+	assert.True(t, f.Remove("127.0.0.1"))
+	assert.Nil(t, f.Lookup("127.0.0.1"))
+}
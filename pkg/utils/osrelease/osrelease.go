@@ -0,0 +1,151 @@
+package osrelease
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Paths holds the locations of the os-release file in the
+// order they are tried by Read.
+var Paths = []string{
+	"/etc/os-release",
+	"/usr/lib/os-release",
+}
+
+// Info holds the operating system identification data
+// from os-release(5).
+type Info struct {
+	// ID is a lower-case string identifying the operating
+	// system (like "ubuntu").
+	ID string
+
+	// IDLike holds the identifiers of operating systems the
+	// local one is derived from (like "debian").
+	IDLike []string
+
+	// Name is the name of the operating system.
+	Name string
+
+	// PrettyName is a pretty name of the operating system
+	// including the version.
+	PrettyName string
+
+	// VersionID is the version of the operating system
+	// (like "22.04").
+	VersionID string
+
+	// VersionCodename is the codename of the release (like
+	// "jammy").
+	VersionCodename string
+
+	// Fields holds all fields of the file.
+	Fields map[string]string
+}
+
+// Is returns true if id equals the ID of info or is one of
+// the distributions listed in ID_LIKE.
+func (info *Info) Is(id string) bool {
+	if strings.EqualFold(info.ID, id) {
+		return true
+	}
+
+	for _, like := range info.IDLike {
+		if strings.EqualFold(like, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parse parses an os-release file from r.
+func Parse(r io.Reader) (*Info, error) {
+	info := &Info{
+		Fields: make(map[string]string),
+	}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(text, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: invalid assignment", line)
+		}
+
+		value, err := unquote(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		info.Fields[parts[0]] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	info.ID = info.Fields["ID"]
+	info.IDLike = strings.Fields(info.Fields["ID_LIKE"])
+	info.Name = info.Fields["NAME"]
+	info.PrettyName = info.Fields["PRETTY_NAME"]
+	info.VersionID = info.Fields["VERSION_ID"]
+	info.VersionCodename = info.Fields["VERSION_CODENAME"]
+
+	// defaults as specified in os-release(5)
+	if info.ID == "" {
+		info.ID = "linux"
+	}
+	if info.Name == "" {
+		info.Name = "Linux"
+	}
+
+	return info, nil
+}
+
+// Read reads and parses the first os-release file found in
+// Paths.
+func Read() (*Info, error) {
+	for _, path := range Paths {
+		f, err := os.Open(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		defer f.Close()
+
+		return Parse(f)
+	}
+
+	return nil, fmt.Errorf("no os-release file found: %w", os.ErrNotExist)
+}
+
+// unquote removes shell-style quotes from value.
+func unquote(value string) (string, error) {
+	if len(value) < 2 {
+		return value, nil
+	}
+
+	switch value[0] {
+	case '"':
+		return strconv.Unquote(value)
+	case '\'':
+		if value[len(value)-1] != '\'' {
+			return "", fmt.Errorf("missing closing quote")
+		}
+		return value[1 : len(value)-1], nil
+	}
+
+	return value, nil
+}
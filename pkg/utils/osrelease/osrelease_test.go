@@ -0,0 +1,50 @@
+package osrelease
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	content := `# Ubuntu
+PRETTY_NAME="Ubuntu 22.04.1 LTS"
+NAME="Ubuntu"
+VERSION_ID="22.04"
+VERSION="22.04.1 LTS (Jammy Jellyfish)"
+VERSION_CODENAME=jammy
+ID=ubuntu
+ID_LIKE=debian
+HOME_URL='https://www.ubuntu.com/'
+`
+
+	info, err := Parse(strings.NewReader(content))
+	require.NoError(t, err)
+
+	assert.Equal(t, "ubuntu", info.ID)
+	assert.Equal(t, []string{"debian"}, info.IDLike)
+	assert.Equal(t, "Ubuntu", info.Name)
+	assert.Equal(t, "Ubuntu 22.04.1 LTS", info.PrettyName)
+	assert.Equal(t, "22.04", info.VersionID)
+	assert.Equal(t, "jammy", info.VersionCodename)
+	assert.Equal(t, "https://www.ubuntu.com/", info.Fields["HOME_URL"])
+
+	assert.True(t, info.Is("ubuntu"))
+	assert.True(t, info.Is("Debian"))
+	assert.False(t, info.Is("fedora"))
+}
+
+func TestParseDefaults(t *testing.T) {
+	info, err := Parse(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Equal(t, "linux", info.ID)
+	assert.Equal(t, "Linux", info.Name)
+
+	_, err = Parse(strings.NewReader("ID"))
+	assert.Error(t, err)
+
+	_, err = Parse(strings.NewReader("ID='ubuntu"))
+	assert.Error(t, err)
+}
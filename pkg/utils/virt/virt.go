@@ -0,0 +1,205 @@
+package virt
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kinds of virtualization.
+const (
+	None      = "none"
+	VM        = "vm"
+	Container = "container"
+)
+
+// Root is prepended to all paths inspected by Detect. It's
+// only meant to be changed in tests.
+var Root = "/"
+
+// Info describes the virtualization environment.
+type Info struct {
+	// Kind is either None, VM or Container.
+	Kind string
+
+	// Technology is the detected virtualization technology
+	// using the same identifiers as systemd-detect-virt(1)
+	// (like "kvm", "docker" or "wsl"). It's "none" if no
+	// virtualization has been detected and may be
+	// "vm-other" or "container-other" if the technology
+	// is unknown.
+	Technology string
+}
+
+// Matches returns true if value matches info. Value may
+// either be a boolean ("yes", "no"), a kind ("vm",
+// "container") or a technology identifier.
+func (info Info) Matches(value string) bool {
+	switch strings.ToLower(value) {
+	case "yes", "true", "1":
+		return info.Kind != None
+	case "no", "false", "0":
+		return info.Kind == None
+	case VM:
+		return info.Kind == VM
+	case Container:
+		return info.Kind == Container
+	default:
+		return strings.EqualFold(info.Technology, value)
+	}
+}
+
+// dmiVendors maps DMI vendor and product strings to
+// virtualization technologies.
+var dmiVendors = []struct {
+	prefix string
+	tech   string
+}{
+	{"KVM", "kvm"},
+	{"OpenStack", "kvm"},
+	{"QEMU", "qemu"},
+	{"VMware", "vmware"},
+	{"VMW", "vmware"},
+	{"innotek GmbH", "oracle"},
+	{"VirtualBox", "oracle"},
+	{"Oracle Corporation", "oracle"},
+	{"Xen", "xen"},
+	{"Bochs", "bochs"},
+	{"Parallels", "parallels"},
+	{"BHYVE", "bhyve"},
+	{"Amazon EC2", "amazon"},
+	{"Google Compute Engine", "google"},
+	{"Microsoft Corporation Virtual Machine", "microsoft"},
+}
+
+// Detect detects the virtualization environment. Containers
+// take precedence over virtual machines.
+func Detect() Info {
+	if tech := detectContainer(); tech != "" {
+		return Info{Kind: Container, Technology: tech}
+	}
+
+	if tech := detectVM(); tech != "" {
+		return Info{Kind: VM, Technology: tech}
+	}
+
+	return Info{Kind: None, Technology: None}
+}
+
+func detectContainer() string {
+	// systemd and most container managers tell us.
+	if content := readFile("run/systemd/container"); content != "" {
+		return content
+	}
+
+	if exists("run/.containerenv") {
+		return "podman"
+	}
+
+	if exists(".dockerenv") {
+		return "docker"
+	}
+
+	if release := strings.ToLower(readFile("proc/sys/kernel/osrelease")); strings.Contains(release, "microsoft") {
+		return "wsl"
+	}
+
+	if env, err := ioutil.ReadFile(path("proc/1/environ")); err == nil {
+		for _, v := range bytes.Split(env, []byte{0}) {
+			if bytes.HasPrefix(v, []byte("container=")) {
+				if tech := string(v[len("container="):]); tech != "" {
+					return tech
+				}
+			}
+		}
+	}
+
+	cgroup := readFile("proc/1/cgroup")
+	switch {
+	case strings.Contains(cgroup, "/docker/"):
+		return "docker"
+	case strings.Contains(cgroup, "/lxc/"):
+		return "lxc"
+	case strings.Contains(cgroup, "/kubepods"):
+		return "container-other"
+	}
+
+	return ""
+}
+
+func detectVM() string {
+	for _, file := range []string{
+		"sys/class/dmi/id/product_name",
+		"sys/class/dmi/id/sys_vendor",
+		"sys/class/dmi/id/board_vendor",
+		"sys/class/dmi/id/bios_vendor",
+	} {
+		value := readFile(file)
+		if value == "" {
+			continue
+		}
+
+		for _, v := range dmiVendors {
+			if strings.HasPrefix(value, v.prefix) {
+				return v.tech
+			}
+		}
+	}
+
+	if readFile("sys/hypervisor/type") == "xen" {
+		return "xen"
+	}
+
+	if hasHypervisorFlag() {
+		return "vm-other"
+	}
+
+	return ""
+}
+
+// hasHypervisorFlag returns true if /proc/cpuinfo contains the
+// hypervisor CPU flag.
+func hasHypervisorFlag() bool {
+	f, err := os.Open(path("proc/cpuinfo"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "flags") {
+			continue
+		}
+
+		for _, flag := range strings.Fields(line) {
+			if flag == "hypervisor" {
+				return true
+			}
+		}
+		return false
+	}
+
+	return false
+}
+
+func path(p string) string {
+	return filepath.Join(Root, p)
+}
+
+func exists(p string) bool {
+	_, err := os.Stat(path(p))
+	return err == nil
+}
+
+func readFile(p string) string {
+	content, err := ioutil.ReadFile(path(p))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
@@ -0,0 +1,63 @@
+package virt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name     string
+		files    map[string]string
+		expected Info
+	}{
+		{"bare metal", map[string]string{"sys/class/dmi/id/sys_vendor": "Dell Inc.\n"}, Info{None, None}},
+		{"kvm", map[string]string{"sys/class/dmi/id/product_name": "KVM\n"}, Info{VM, "kvm"}},
+		{"virtualbox", map[string]string{"sys/class/dmi/id/sys_vendor": "innotek GmbH\n"}, Info{VM, "oracle"}},
+		{"hypervisor flag", map[string]string{"proc/cpuinfo": "processor: 0\nflags\t: fpu vme hypervisor\n"}, Info{VM, "vm-other"}},
+		{"docker", map[string]string{".dockerenv": "", "sys/class/dmi/id/product_name": "KVM"}, Info{Container, "docker"}},
+		{"systemd-nspawn", map[string]string{"run/systemd/container": "systemd-nspawn\n"}, Info{Container, "systemd-nspawn"}},
+		{"podman", map[string]string{"run/.containerenv": ""}, Info{Container, "podman"}},
+		{"wsl", map[string]string{"proc/sys/kernel/osrelease": "5.10.16.3-microsoft-standard-WSL2\n"}, Info{Container, "wsl"}},
+		{"lxc via environ", map[string]string{"proc/1/environ": "PATH=/bin\x00container=lxc\x00"}, Info{Container, "lxc"}},
+	}
+
+	defer func() { Root = "/" }()
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "virt-")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			for name, content := range c.files {
+				p := filepath.Join(dir, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+				require.NoError(t, ioutil.WriteFile(p, []byte(content), 0644))
+			}
+
+			Root = dir
+			assert.Equal(t, c.expected, Detect())
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	vm := Info{Kind: VM, Technology: "kvm"}
+	assert.True(t, vm.Matches("yes"))
+	assert.True(t, vm.Matches("vm"))
+	assert.True(t, vm.Matches("KVM"))
+	assert.False(t, vm.Matches("no"))
+	assert.False(t, vm.Matches("container"))
+	assert.False(t, vm.Matches("qemu"))
+
+	none := Info{Kind: None, Technology: None}
+	assert.True(t, none.Matches("no"))
+	assert.True(t, none.Matches("none"))
+	assert.False(t, none.Matches("yes"))
+}
@@ -0,0 +1,351 @@
+package version
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Compare compares the version strings a and b and returns
+// -1 if a is older than b, 1 if a is newer than b and 0 if
+// both are equal. It uses the dpkg ordering rules which work
+// well for most version schemes.
+func Compare(a, b string) int {
+	return CompareDebian(a, b)
+}
+
+// CompareDebian compares two Debian package versions in the
+// format [epoch:]upstream[-revision] as dpkg does.
+func CompareDebian(a, b string) int {
+	epochA, upstreamA, revisionA := splitVersion(a)
+	epochB, upstreamB, revisionB := splitVersion(b)
+
+	if epochA != epochB {
+		return sign(epochA - epochB)
+	}
+
+	if res := compareDebianPart(upstreamA, upstreamB); res != 0 {
+		return res
+	}
+
+	return compareDebianPart(revisionA, revisionB)
+}
+
+// splitDebian splits a Debian version into its epoch, upstream
+// version and revision.
+func splitVersion(v string) (int, string, string) {
+	v = strings.TrimSpace(v)
+
+	var epoch int
+	if idx := strings.Index(v, ":"); idx > 0 {
+		if e, err := strconv.Atoi(v[:idx]); err == nil {
+			epoch = e
+			v = v[idx+1:]
+		}
+	}
+
+	var revision string
+	if idx := strings.LastIndex(v, "-"); idx >= 0 {
+		revision = v[idx+1:]
+		v = v[:idx]
+	}
+
+	return epoch, v, revision
+}
+
+// debianOrder returns the sort weight of the character c
+// for non-digit parts. The tilde sorts before everything,
+// even the end of the string, letters sort before all
+// other characters.
+func debianOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+
+	c := s[i]
+	switch {
+	case isDigit(c):
+		return 0
+	case isAlpha(c):
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+// compareDebianPart implements the verrevcmp algorithm of dpkg.
+func compareDebianPart(a, b string) int {
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := debianOrder(a, i), debianOrder(b, j)
+			if ac != bc {
+				return sign(ac - bc)
+			}
+			i++
+			j++
+		}
+
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+
+		firstDiff := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+
+	return 0
+}
+
+// CompareRPM compares two RPM package versions in the format
+// [epoch:]version[-release] as rpm does. The release is only
+// compared if both versions contain one.
+func CompareRPM(a, b string) int {
+	epochA, versionA, releaseA := splitVersion(a)
+	epochB, versionB, releaseB := splitVersion(b)
+
+	if epochA != epochB {
+		return sign(epochA - epochB)
+	}
+
+	if res := rpmvercmp(versionA, versionB); res != 0 {
+		return res
+	}
+
+	if releaseA == "" || releaseB == "" {
+		return 0
+	}
+
+	return rpmvercmp(releaseA, releaseB)
+}
+
+// rpmvercmp implements the version comparison algorithm of rpm.
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	for len(a) > 0 || len(b) > 0 {
+		a = strings.TrimLeftFunc(a, isRPMSeparator)
+		b = strings.TrimLeftFunc(b, isRPMSeparator)
+
+		// the tilde sorts before everything else.
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		// the caret sorts after the end of the string but
+		// before everything else.
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			if a == "" {
+				return -1
+			}
+			if b == "" {
+				return 1
+			}
+			if !strings.HasPrefix(a, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "^") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if a == "" || b == "" {
+			break
+		}
+
+		isNum := isDigit(a[0])
+		segA, restA := rpmSegment(a, isNum)
+		segB, restB := rpmSegment(b, isNum)
+		a, b = restA, restB
+
+		// segments of different types: numeric ones are
+		// considered newer.
+		if segB == "" {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		if isNum {
+			segA = strings.TrimLeft(segA, "0")
+			segB = strings.TrimLeft(segB, "0")
+
+			if len(segA) != len(segB) {
+				return sign(len(segA) - len(segB))
+			}
+		}
+
+		if res := strings.Compare(segA, segB); res != 0 {
+			return res
+		}
+	}
+
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+// ComparePacman compares two pacman package versions in the
+// format [epoch:]version[-release] as vercmp does. The release
+// is only compared if both versions contain one.
+func ComparePacman(a, b string) int {
+	epochA, versionA, releaseA := splitVersion(a)
+	epochB, versionB, releaseB := splitVersion(b)
+
+	if epochA != epochB {
+		return sign(epochA - epochB)
+	}
+
+	if res := alpmvercmp(versionA, versionB); res != 0 {
+		return res
+	}
+
+	if releaseA == "" || releaseB == "" {
+		return 0
+	}
+
+	return alpmvercmp(releaseA, releaseB)
+}
+
+// alpmvercmp implements the version comparison algorithm of
+// libalpm. It's similar to rpmvercmp but neither supports the
+// tilde nor the caret and a trailing alpha segment is considered
+// older than the end of the version so 1.0rc1 < 1.0 < 1.0.1.
+func alpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	for len(a) > 0 && len(b) > 0 {
+		trimmedA := strings.TrimLeftFunc(a, isAlpmSeparator)
+		trimmedB := strings.TrimLeftFunc(b, isAlpmSeparator)
+		sepA, sepB := len(a)-len(trimmedA), len(b)-len(trimmedB)
+		a, b = trimmedA, trimmedB
+
+		if a == "" || b == "" {
+			break
+		}
+
+		// more separators mean a newer version.
+		if sepA != sepB {
+			return sign(sepA - sepB)
+		}
+
+		isNum := isDigit(a[0])
+		segA, restA := rpmSegment(a, isNum)
+		segB, restB := rpmSegment(b, isNum)
+		a, b = restA, restB
+
+		// segments of different types: numeric ones are
+		// considered newer.
+		if segB == "" {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		if isNum {
+			segA = strings.TrimLeft(segA, "0")
+			segB = strings.TrimLeft(segB, "0")
+
+			if len(segA) != len(segB) {
+				return sign(len(segA) - len(segB))
+			}
+		}
+
+		if res := strings.Compare(segA, segB); res != 0 {
+			return res
+		}
+	}
+
+	if a == "" && b == "" {
+		return 0
+	}
+
+	// a remaining alpha segment never beats the end of the
+	// version.
+	if (a == "" && !isAlpha(b[0])) || (a != "" && isAlpha(a[0])) {
+		return -1
+	}
+
+	return 1
+}
+
+func isAlpmSeparator(r rune) bool {
+	return r >= 128 || (!isDigit(byte(r)) && !isAlpha(byte(r)))
+}
+
+// rpmSegment returns the leading run of digits (if num is
+// true) or letters of s and the remaining string.
+func rpmSegment(s string, num bool) (string, string) {
+	i := 0
+	for i < len(s) && ((num && isDigit(s[i])) || (!num && isAlpha(s[i]))) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isRPMSeparator(r rune) bool {
+	if r == '~' || r == '^' {
+		return false
+	}
+	return r >= 128 || (!isDigit(byte(r)) && !isAlpha(byte(r)))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,90 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareDebian(t *testing.T) {
+	cases := []struct {
+		a, b string
+		res  int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0", "1.0+b1", -1},
+		{"1.0a", "1.0", 1},
+		{"1.0a", "1.0+", -1},
+		{"1:1.0", "2.0", 1},
+		{"1.0-1", "1.0-2", -1},
+		{"1.0-10", "1.0-9", 1},
+		{"1.18.0-6ubuntu14", "1.20", -1},
+		{"5.15.0-91-generic", "5.10", 1},
+		{"001.0", "1.0", 0},
+		{"", "1.0", -1},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.res, CompareDebian(c.a, c.b), "%s <> %s", c.a, c.b)
+		assert.Equal(t, -c.res, CompareDebian(c.b, c.a), "%s <> %s", c.b, c.a)
+	}
+}
+
+func TestCompareRPM(t *testing.T) {
+	cases := []struct {
+		a, b string
+		res  int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.0", "1.0.1", -1},
+		{"1.0a", "1.0", 1},
+		{"1.0a", "1.0.1", -1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0^git1", "1.0", 1},
+		{"1.0^git1", "1.0.1", -1},
+		{"1.0_1", "1.0.1", 0},
+		{"2:1.0", "1:2.0", 1},
+		{"1.20.1-1.fc38", "1.20.1-2.fc38", -1},
+		{"1.20.1-1.fc38", "1.20.1", 0},
+		{"1.020", "1.20", 0},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.res, CompareRPM(c.a, c.b), "%s <> %s", c.a, c.b)
+		assert.Equal(t, -c.res, CompareRPM(c.b, c.a), "%s <> %s", c.b, c.a)
+	}
+}
+
+func TestComparePacman(t *testing.T) {
+	cases := []struct {
+		a, b string
+		res  int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.0", "1.0.1", -1},
+		{"1.0a", "1.0", -1},
+		{"1.0a", "1.0b", -1},
+		{"1.0beta", "1.0pre", -1},
+		{"1.0rc1", "1.0", -1},
+		{"1.0", "1.0.a", -1},
+		{"1.0.a", "1.0.1", -1},
+		{"1.0..1", "1.0.1", 1},
+		{"2:1.0", "1:2.0", 1},
+		{"1.2.3-1", "1.2.3-2", -1},
+		{"1.2.3-1", "1.2.3", 0},
+		{"1.020", "1.20", 0},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.res, ComparePacman(c.a, c.b), "%s <> %s", c.a, c.b)
+		assert.Equal(t, -c.res, ComparePacman(c.b, c.a), "%s <> %s", c.b, c.a)
+	}
+}
@@ -0,0 +1,9 @@
+// Package version provides the version of system-deploy and
+// distribution-aware comparison of version strings.
+package version
+
+// Version is the version of system-deploy. It is set during
+// release builds using
+//
+//	-ldflags "-X github.com/ppacher/system-deploy/pkg/version.Version=<version>"
+var Version = "dev"